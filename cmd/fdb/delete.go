@@ -1,6 +1,8 @@
 package fdb
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
@@ -9,6 +11,8 @@ import (
 )
 
 var confirmed = false
+var retainPolicy internal.RetainPolicy
+var printPlanJSON = false
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -46,6 +50,13 @@ var deleteEverythingCmd = &cobra.Command{
 	Run:       runDeleteEverything,
 }
 
+var deleteRetainPolicyCmd = &cobra.Command{
+	Use:     internal.DeleteRetainPolicyUsageExample,
+	Example: internal.DeleteRetainPolicyExamples,
+	Args:    internal.DeleteRetainPolicyArgsValidator,
+	Run:     runDeleteRetainPolicy,
+}
+
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -86,11 +97,23 @@ func runDeleteRetainAfter(args []string) {
 	deleteHandler.HandleDeleteRetainAfter(args, confirmed)
 }
 
+func runDeleteRetainPolicy(cmd *cobra.Command, args []string) {
+	folder, err := internal.ConfigureFolder()
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler, err := newFdbDeleteHandler(folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler.HandleDeleteRetainPolicy(retainPolicy, confirmed)
+}
+
 func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteRetainCmd.Flags().StringP("after", "a", "", "Set the time after which retain backups")
-	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd)
+	internal.AddRetainPolicyFlags(deleteRetainPolicyCmd, &retainPolicy)
+	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd, deleteRetainPolicyCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().BoolVar(&printPlanJSON, internal.DeletePlanJSONFlag, false, internal.DeletePlanJSONDescription)
 }
 
 func newFdbDeleteHandler(folder storage.Folder) (*internal.DeleteHandler, error) {
@@ -104,7 +127,14 @@ func newFdbDeleteHandler(folder storage.Folder) (*internal.DeleteHandler, error)
 		backupObjects = append(backupObjects, internal.NewDefaultBackupObject(object))
 	}
 
-	return internal.NewDeleteHandler(folder, backupObjects, makeLessFunc()), nil
+	return internal.NewDeleteHandler(folder, backupObjects, makeLessFunc(), deleteHandlerOptions()...), nil
+}
+
+func deleteHandlerOptions() []internal.DeleteHandlerOption {
+	if !printPlanJSON {
+		return nil
+	}
+	return []internal.DeleteHandlerOption{internal.DeletePlanOutput(os.Stdout)}
 }
 
 func makeLessFunc() func(object1, object2 storage.Object) bool {
@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"io"
 	"os"
 	"syscall"
 
@@ -17,6 +18,7 @@ const backupDeleteShortDescription = "Deletes backup data from storage"
 
 var (
 	confirmedBackupDelete bool
+	printBackupDeletePlan bool
 )
 
 // backupDeleteCmd represents the backupDelete command
@@ -37,12 +39,21 @@ var backupDeleteCmd = &cobra.Command{
 		purger, err := archive.NewStoragePurger(archive.NewDefaultStorageSettings())
 		tracelog.ErrorLogger.FatalOnError(err)
 
-		err = mongo.HandleBackupDelete(args[0], downloader, purger, !confirmedBackupDelete)
+		err = mongo.HandleBackupDelete(args[0], downloader, purger, !confirmedBackupDelete, backupDeletePlanOutput())
 		tracelog.ErrorLogger.FatalOnError(err)
 	},
 }
 
+func backupDeletePlanOutput() io.Writer {
+	if !printBackupDeletePlan {
+		return nil
+	}
+	return os.Stdout
+}
+
 func init() {
 	backupDeleteCmd.Flags().BoolVar(&confirmedBackupDelete, internal.ConfirmFlag, false, "Confirms backup deletion")
+	backupDeleteCmd.Flags().BoolVar(&printBackupDeletePlan, internal.DeletePlanJSONFlag, false,
+		internal.DeletePlanJSONDescription)
 	cmd.AddCommand(backupDeleteCmd)
 }
@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -11,18 +12,23 @@ import (
 )
 
 const (
-	retainAfterFlag  = "retain-after"
-	retainCountFlag  = "retain-count"
-	purgeOplogFlag   = "purge-oplog"
-	purgeGarbageFlag = "purge-garbage"
+	retainAfterFlag   = "retain-after"
+	retainCountFlag   = "retain-count"
+	purgeOplogFlag    = "purge-oplog"
+	purgeGarbageFlag  = "purge-garbage"
+	retainDailyFlag   = "retain-daily"
+	retainWeeklyFlag  = "retain-weekly"
+	retainMonthlyFlag = "retain-monthly"
 )
 
 var (
-	confirmed    bool
-	purgeOplog   bool
-	purgeGarbage bool
-	retainAfter  string
-	retainCount  uint
+	confirmed     bool
+	purgeOplog    bool
+	purgeGarbage  bool
+	retainAfter   string
+	retainCount   uint
+	retainPolicy  internal.RetainPolicy
+	printPlanJSON bool
 )
 
 // deleteCmd represents the delete command
@@ -52,6 +58,14 @@ func runPurge(cmd *cobra.Command, args []string) {
 		opts = append(opts, mongo.PurgeRetainCount(int(retainCount)))
 	}
 
+	if !retainPolicy.IsEmpty() {
+		opts = append(opts, mongo.PurgeRetainPolicy(retainPolicy))
+	}
+
+	if printPlanJSON {
+		opts = append(opts, mongo.PurgePlanOutput(os.Stdout))
+	}
+
 	// set up storage downloader client
 	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -67,8 +81,12 @@ func runPurge(cmd *cobra.Command, args []string) {
 func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteCmd.Flags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.Flags().BoolVar(&printPlanJSON, internal.DeletePlanJSONFlag, false, internal.DeletePlanJSONDescription)
 	deleteCmd.Flags().BoolVar(&purgeOplog, purgeOplogFlag, false, "Purge oplog archives")
 	deleteCmd.Flags().BoolVar(&purgeGarbage, purgeGarbageFlag, false, "Purge garbage in backup folder")
 	deleteCmd.Flags().StringVar(&retainAfter, retainAfterFlag, "", "Keep backups newer")
 	deleteCmd.Flags().UintVar(&retainCount, retainCountFlag, 0, "Keep minimum count, except permanent backups")
+	deleteCmd.Flags().IntVar(&retainPolicy.Daily, retainDailyFlag, 0, internal.DeleteRetainDailyDescription)
+	deleteCmd.Flags().IntVar(&retainPolicy.Weekly, retainWeeklyFlag, 0, internal.DeleteRetainWeeklyDescription)
+	deleteCmd.Flags().IntVar(&retainPolicy.Monthly, retainMonthlyFlag, 0, internal.DeleteRetainMonthlyDescription)
 }
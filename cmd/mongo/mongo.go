@@ -24,9 +24,14 @@ var cmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		err := internal.AssertRequiredSettingsSet()
 		tracelog.ErrorLogger.FatalOnError(err)
+		internal.ConfigureMetricsLabels(internal.MONGO, cmd)
+		internal.WriteMetricsTextFile(false)
 		err = internal.ConfigureAndRunDefaultWebServer()
 		tracelog.ErrorLogger.FatalOnError(err)
 	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		internal.WriteMetricsTextFile(true)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -25,8 +25,14 @@ var (
 			internal.RequiredSettings[internal.MysqlDatasourceNameSetting] = true
 			err := internal.AssertRequiredSettingsSet()
 			tracelog.ErrorLogger.FatalOnError(err)
+			// the root PersistentPreRun is overridden here
+			internal.ConfigureMetricsLabels(internal.MYSQL, cmd)
+			internal.WriteMetricsTextFile(false)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			err := internal.ConfigureAndRunDefaultWebServer()
+			tracelog.ErrorLogger.FatalOnError(err)
+
 			uploader, err := internal.ConfigureUploader()
 			tracelog.ErrorLogger.FatalOnError(err)
 			backupCmd, err := internal.GetCommandSetting(internal.NameStreamCreateCmd)
@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+)
+
+var (
+	// backupVerifyCmd represents the backupVerify command
+	backupVerifyCmd = &cobra.Command{
+		Use:   internal.BackupVerifyUsage,
+		Short: internal.BackupVerifyShortDescription,
+		Args:  cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			backupName := internal.LatestString
+			if len(args) > 0 {
+				backupName = args[0]
+			}
+			targetBackupSelector, err := internal.NewTargetBackupSelector("", backupName, mysql.NewGenericMetaFetcher())
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			internal.HandleBackupVerify(folder, targetBackupSelector, internal.VerifyStreamBackup,
+				os.Stdout, backupVerifyJSONOutput)
+		},
+	}
+	backupVerifyJSONOutput bool
+)
+
+func init() {
+	cmd.AddCommand(backupVerifyCmd)
+	backupVerifyCmd.Flags().BoolVar(&backupVerifyJSONOutput, internal.BackupVerifyJSONFlag,
+		false, internal.BackupVerifyJSONDescription)
+}
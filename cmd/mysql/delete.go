@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
@@ -10,6 +12,8 @@ import (
 )
 
 var confirmed = false
+var retainPolicy internal.RetainPolicy
+var printPlanJSON = false
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -40,6 +44,13 @@ var deleteEverythingCmd = &cobra.Command{
 	Run:       runDeleteEverything,
 }
 
+var deleteRetainPolicyCmd = &cobra.Command{
+	Use:     internal.DeleteRetainPolicyUsageExample,
+	Example: internal.DeleteRetainPolicyExamples,
+	Args:    internal.DeleteRetainPolicyArgsValidator,
+	Run:     runDeleteRetainPolicy,
+}
+
 const (
 	DeleteTargetUsageExample = "target"
 	DeleteTargetExamples     = ""
@@ -88,10 +99,19 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
+func runDeleteRetainPolicy(cmd *cobra.Command, args []string) {
+	deleteHandler, err := NewMySQLDeleteHandler()
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler.HandleDeleteRetainPolicy(retainPolicy, confirmed)
+}
+
 func init() {
 	cmd.AddCommand(deleteCmd)
-	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd, deleteTargetCmd)
+	internal.AddRetainPolicyFlags(deleteRetainPolicyCmd, &retainPolicy)
+	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd, deleteTargetCmd, deleteRetainPolicyCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().BoolVar(&printPlanJSON, internal.DeletePlanJSONFlag, false, internal.DeletePlanJSONDescription)
 }
 
 func makeLessFunc(folder storage.Folder) func(object1, object2 storage.Object) bool {
@@ -159,10 +179,17 @@ func NewMySQLDeleteHandler() (*DeleteHandler, error) {
 
 	return &DeleteHandler{
 		DeleteHandler: internal.NewDeleteHandler(folder, backupObjects, makeLessFunc(folder),
-			internal.IsPermanentFunc(func(object storage.Object) bool {
+			append(deleteHandlerOptions(), internal.IsPermanentFunc(func(object storage.Object) bool {
 				return IsPermanent(object.GetName(), permanentBackups)
-			}),
+			}))...,
 		),
 		permanentObjects: permanentBackups,
 	}, nil
 }
+
+func deleteHandlerOptions() []internal.DeleteHandlerOption {
+	if !printPlanJSON {
+		return nil
+	}
+	return []internal.DeleteHandlerOption{internal.DeletePlanOutput(os.Stdout)}
+}
@@ -26,6 +26,13 @@ var cmd = &cobra.Command{
 		if err != nil {
 			tracelog.WarningLogger.PrintError(err)
 		}
+		internal.ConfigureMetricsLabels(internal.MYSQL, cmd)
+		internal.WriteMetricsTextFile(false)
+		err = internal.ConfigureAndRunDefaultWebServer()
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		internal.WriteMetricsTextFile(true)
 	},
 }
 
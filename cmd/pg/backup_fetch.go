@@ -5,6 +5,7 @@ import (
 
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/wal-g/storages/storage"
@@ -17,17 +18,35 @@ const (
 	maskFlagDescription         = `Fetches only files which path relative to destination_directory
 matches given shell file pattern.
 For information about pattern syntax view: https://golang.org/pkg/path/filepath/#Match`
+	databaseDescription           = "Fetches only the files of the database, the system databases and the cluster-wide files"
 	restoreSpecDescription        = "Path to file containing tablespace restore specification"
 	reverseDeltaUnpackDescription = "Unpack delta backups in reverse order (beta feature)"
 	skipRedundantTarsDescription  = "Skip tars with no useful data (requires reverse delta unpack)"
 	targetUserDataDescription     = "Fetch storage backup which has the specified user data"
+	resumeDescription             = "Resume the interrupted fetch, the tars extracted in full are skipped"
+
+	recoveryTargetTimeDescription     = "Write the recovery configuration to recover up to the RFC 3339 time"
+	recoveryTargetLsnDescription      = "Write the recovery configuration to recover up to the LSN"
+	recoveryTargetXidDescription      = "Write the recovery configuration to recover up to the transaction ID"
+	recoveryTargetTimelineDescription = "Write the recovery configuration to recover to the timeline"
+	standbyDescription                = "Write the recovery configuration to start the cluster as a standby"
+	restoreCommandDescription         = "Write the recovery configuration with the restore_command, " +
+		"'" + postgres.DefaultRestoreCommand + "' by default"
 )
 
 var fileMask string
+var databaseName string
 var restoreSpec string
 var reverseDeltaUnpack bool
 var skipRedundantTars bool
 var fetchTargetUserData string
+var resumeFetch bool
+var recoveryTargetTime string
+var recoveryTargetLsn string
+var recoveryTargetXid string
+var recoveryTargetTimeline string
+var standby bool
+var restoreCommand string
 
 var backupFetchCmd = &cobra.Command{
 	Use:   "backup-fetch destination_directory [backup_name | --target-user-data <data>]",
@@ -39,6 +58,8 @@ var backupFetchCmd = &cobra.Command{
 		}
 		targetBackupSelector, err := createTargetFetchBackupSelector(cmd, args, fetchTargetUserData)
 		tracelog.ErrorLogger.FatalOnError(err)
+		recoveryConfig, writeRecoveryConfig, err := createRecoveryConfig()
+		tracelog.ErrorLogger.FatalOnError(err)
 
 		folder, err := internal.ConfigureFolder()
 		tracelog.ErrorLogger.FatalOnError(err)
@@ -46,10 +67,16 @@ var backupFetchCmd = &cobra.Command{
 		var pgFetcher func(folder storage.Folder, backup internal.Backup)
 		reverseDeltaUnpack = reverseDeltaUnpack || viper.GetBool(internal.UseReverseUnpackSetting)
 		skipRedundantTars = skipRedundantTars || viper.GetBool(internal.SkipRedundantTarsSetting)
+		if reverseDeltaUnpack && resumeFetch {
+			tracelog.ErrorLogger.Fatal("--resume is not supported with the reverse delta unpack\n")
+		}
 		if reverseDeltaUnpack {
-			pgFetcher = postgres.GetPgFetcherNew(args[0], fileMask, restoreSpec, skipRedundantTars)
+			pgFetcher = postgres.GetPgFetcherNew(args[0], fileMask, databaseName, restoreSpec, skipRedundantTars)
 		} else {
-			pgFetcher = postgres.GetPgFetcherOld(args[0], fileMask, restoreSpec)
+			pgFetcher = postgres.GetPgFetcherOld(args[0], fileMask, databaseName, restoreSpec, resumeFetch)
+		}
+		if writeRecoveryConfig {
+			pgFetcher = postgres.GetPgFetcherWithRecoveryConfig(pgFetcher, args[0], recoveryConfig)
 		}
 
 		internal.HandleBackupFetch(folder, targetBackupSelector, pgFetcher)
@@ -72,8 +99,37 @@ func createTargetFetchBackupSelector(cmd *cobra.Command,
 	return backupSelector, nil
 }
 
+// create the recovery configuration to write after the fetch, if any of its flags is set
+func createRecoveryConfig() (config postgres.RecoveryConfig, isSet bool, err error) {
+	targets := map[postgres.RecoveryTargetType]string{
+		postgres.RecoveryTargetTime: recoveryTargetTime,
+		postgres.RecoveryTargetLsn:  recoveryTargetLsn,
+		postgres.RecoveryTargetXid:  recoveryTargetXid,
+	}
+	for targetType, value := range targets {
+		if value == "" {
+			continue
+		}
+		if config.Target != nil {
+			return config, false, errors.New("only one of the --recovery-target-time, " +
+				"--recovery-target-lsn and --recovery-target-xid flags can be set")
+		}
+		target, err := postgres.NewRecoveryTarget(targetType, value)
+		if err != nil {
+			return config, false, err
+		}
+		config.Target = &target
+	}
+	config.TargetTimeline = recoveryTargetTimeline
+	config.Standby = standby
+	config.RestoreCommand = restoreCommand
+	isSet = config.Target != nil || config.TargetTimeline != "" || config.Standby || config.RestoreCommand != ""
+	return config, isSet, nil
+}
+
 func init() {
 	backupFetchCmd.Flags().StringVar(&fileMask, "mask", "", maskFlagDescription)
+	backupFetchCmd.Flags().StringVar(&databaseName, "database", "", databaseDescription)
 	backupFetchCmd.Flags().StringVar(&restoreSpec, "restore-spec", "", restoreSpecDescription)
 	backupFetchCmd.Flags().BoolVar(&reverseDeltaUnpack, "reverse-unpack",
 		false, reverseDeltaUnpackDescription)
@@ -81,5 +137,16 @@ func init() {
 		false, skipRedundantTarsDescription)
 	backupFetchCmd.Flags().StringVar(&fetchTargetUserData, "target-user-data",
 		"", targetUserDataDescription)
+	backupFetchCmd.Flags().BoolVar(&resumeFetch, "resume", false, resumeDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetTime, "recovery-target-time",
+		"", recoveryTargetTimeDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetLsn, "recovery-target-lsn",
+		"", recoveryTargetLsnDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetXid, "recovery-target-xid",
+		"", recoveryTargetXidDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetTimeline, "recovery-target-timeline",
+		"", recoveryTargetTimelineDescription)
+	backupFetchCmd.Flags().BoolVar(&standby, "standby", false, standbyDescription)
+	backupFetchCmd.Flags().StringVar(&restoreCommand, "restore-command", "", restoreCommandDescription)
 	Cmd.AddCommand(backupFetchCmd)
 }
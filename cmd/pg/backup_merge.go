@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	BackupMergeShortDescription = "Merges a delta backup with its base backups into a full backup"
+	BackupMergeLongDescription  = `Builds a full backup from a delta backup and its base backups stored in the storage.
+	The full backup is named after the delta backup LSN. Neither Postgres nor PGDATA is needed.`
+)
+
+var (
+	// backupMergeCmd represents the backupMerge command
+	backupMergeCmd = &cobra.Command{
+		Use:   "backup-merge delta_backup_name",
+		Short: BackupMergeShortDescription,
+		Long:  BackupMergeLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			uploader, err := postgres.ConfigureWalUploader()
+			tracelog.ErrorLogger.FatalOnError(err)
+			postgres.HandleBackupMerge(uploader, args[0])
+		},
+	}
+)
+
+func init() {
+	Cmd.AddCommand(backupMergeCmd)
+}
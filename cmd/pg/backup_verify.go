@@ -0,0 +1,47 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const backupVerifyLongDescription = "Downloads every tar of the backup without unpacking it, " +
+	"checks the tar structure, the file lists of the backup sentinel and the page checksums. LATEST by default."
+
+var (
+	// backupVerifyCmd represents the backupVerify command
+	backupVerifyCmd = &cobra.Command{
+		Use:   internal.BackupVerifyUsage,
+		Short: internal.BackupVerifyShortDescription,
+		Long:  backupVerifyLongDescription,
+		Args:  cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			backupName := internal.LatestString
+			if len(args) > 0 {
+				backupName = args[0]
+			}
+			targetBackupSelector, err := internal.NewTargetBackupSelector("", backupName, postgres.NewGenericMetaFetcher())
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			internal.HandleBackupVerify(folder, targetBackupSelector, verifyPgBackup, os.Stdout, backupVerifyJSONOutput)
+		},
+	}
+	backupVerifyJSONOutput bool
+)
+
+func verifyPgBackup(backup internal.Backup) *internal.BackupVerifyResult {
+	return postgres.VerifyBackup(postgres.ToPgBackup(backup))
+}
+
+func init() {
+	Cmd.AddCommand(backupVerifyCmd)
+	backupVerifyCmd.Flags().BoolVar(&backupVerifyJSONOutput, internal.BackupVerifyJSONFlag,
+		false, internal.BackupVerifyJSONDescription)
+}
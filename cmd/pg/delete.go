@@ -2,6 +2,7 @@ package pg
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/wal-g/wal-g/internal/databases/postgres"
@@ -20,6 +21,8 @@ const UseSentinelTimeDescription = "Use backup creation time from sentinel for b
 var confirmed = false
 var useSentinelTime = false
 var deleteTargetUserData = ""
+var retainPolicy internal.RetainPolicy
+var printPlanJSON = false
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -56,6 +59,13 @@ var deleteTargetCmd = &cobra.Command{
 	Run:     runDeleteTarget,
 }
 
+var deleteRetainPolicyCmd = &cobra.Command{
+	Use:     internal.DeleteRetainPolicyUsageExample,
+	Example: internal.DeleteRetainPolicyExamples,
+	Args:    internal.DeleteRetainPolicyArgsValidator,
+	Run:     runDeleteRetainPolicy,
+}
+
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -88,6 +98,22 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
+func runDeleteRetainPolicy(cmd *cobra.Command, args []string) {
+	folder, err := internal.ConfigureFolder()
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
+	if len(permanentBackups) > 0 {
+		tracelog.InfoLogger.Printf("Found permanent objects: backups=%v, wals=%v\n",
+			permanentBackups, permanentWals)
+	}
+
+	deleteHandler, err := newPostgresDeleteHandler(folder, permanentBackups, permanentWals)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	deleteHandler.HandleDeleteRetainPolicy(retainPolicy, confirmed)
+}
+
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -131,8 +157,11 @@ func init() {
 	deleteTargetCmd.Flags().StringVar(
 		&deleteTargetUserData, internal.DeleteTargetUserDataFlag, "", internal.DeleteTargetUserDataDescription)
 
-	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd)
+	internal.AddRetainPolicyFlags(deleteRetainPolicyCmd, &retainPolicy)
+
+	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd, deleteRetainPolicyCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().BoolVar(&printPlanJSON, internal.DeletePlanJSONFlag, false, internal.DeletePlanJSONDescription)
 	deleteCmd.PersistentFlags().BoolVar(&useSentinelTime, UseSentinelTimeFlag, false, UseSentinelTimeDescription)
 }
 
@@ -162,17 +191,26 @@ func newPostgresDeleteHandler(folder storage.Folder, permanentBackups, permanent
 		return nil, err
 	}
 
+	options := append(deleteHandlerOptions(),
+		internal.IsPermanentFunc(makePostgresPermanentFunc(permanentBackups, permanentWals)),
+		internal.ListObjectsFunc(postgres.ListObjectsWithWalIndex))
 	deleteHandler := internal.NewDeleteHandler(
 		folder,
 		postgresBackups,
 		lessFunc,
-		internal.IsPermanentFunc(
-			makePostgresPermanentFunc(permanentBackups, permanentWals)),
+		options...,
 	)
 
 	return deleteHandler, nil
 }
 
+func deleteHandlerOptions() []internal.DeleteHandlerOption {
+	if !printPlanJSON {
+		return nil
+	}
+	return []internal.DeleteHandlerOption{internal.DeletePlanOutput(os.Stdout)}
+}
+
 func newPostgresBackupObject(incrementBase, incrementFrom string,
 	isFullBackup bool, creationTime time.Time, object storage.Object) PostgresBackupObject {
 	return PostgresBackupObject{
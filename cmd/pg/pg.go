@@ -31,6 +31,16 @@ var (
 			if viper.IsSet(internal.PgWalSize) {
 				postgres.SetWalSize(viper.GetUint64(internal.PgWalSize))
 			}
+			internal.ConfigureMetricsLabels(internal.PG, cmd)
+			internal.WriteMetricsTextFile(false)
+			// the wal-push calls of archive_command are short-lived and concurrent, they would race for the address
+			if cmd != walPushCmd || walPushDaemon {
+				err = internal.ConfigureAndRunDefaultWebServer()
+				tracelog.ErrorLogger.FatalOnError(err)
+			}
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			internal.WriteMetricsTextFile(true)
 		},
 	}
 )
@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const (
+	RestorePlanUsage            = "restore-plan"
+	RestorePlanShortDescription = "Show the backup and the recovery settings to recover up to the target"
+	RestorePlanLongDescription  = "Pick the newest backup to recover the cluster from up to the target time, LSN or " +
+		"transaction ID, check that the WAL segments up to the target are in storage " +
+		"and show the recovery settings to use. Fails if there is a gap in the WAL."
+
+	untilFlag        = "until"
+	untilDescription = "Recovery target: RFC 3339 time, LSN like 0/16B3748 or transaction ID"
+
+	restorePlanTimelineFlag        = "timeline"
+	restorePlanTimelineDescription = "Timeline to recover to, the highest one in storage by default"
+)
+
+var (
+	// restorePlanCmd represents the restorePlan command
+	restorePlanCmd = &cobra.Command{
+		Use:   RestorePlanUsage,
+		Short: RestorePlanShortDescription,
+		Long:  RestorePlanLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			target, err := postgres.ParseRecoveryTarget(restorePlanUntil)
+			tracelog.ErrorLogger.FatalOnError(err)
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+			postgres.HandleRestorePlan(folder, target, restorePlanTimeline, os.Stdout, restorePlanJSONOutput)
+		},
+	}
+	restorePlanUntil      string
+	restorePlanTimeline   uint32
+	restorePlanJSONOutput bool
+)
+
+func init() {
+	Cmd.AddCommand(restorePlanCmd)
+	restorePlanCmd.Flags().StringVar(&restorePlanUntil, untilFlag, "", untilDescription)
+	restorePlanCmd.Flags().Uint32Var(&restorePlanTimeline, restorePlanTimelineFlag, 0, restorePlanTimelineDescription)
+	restorePlanCmd.Flags().BoolVar(&restorePlanJSONOutput, useJSONOutputFlag, false, useJSONOutputDescription)
+	_ = restorePlanCmd.MarkFlagRequired(untilFlag)
+}
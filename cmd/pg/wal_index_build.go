@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	WalIndexBuildUsage            = "wal-index-build"
+	WalIndexBuildShortDescription = "Builds the WAL index of the WAL segments in storage."
+	WalIndexBuildLongDescription  = "Lists the WAL folder once and writes the WAL index pages of all the segments found, " +
+		"so the segments uploaded before " + internal.UseWalIndexSetting + " was turned on are indexed as well."
+)
+
+// walIndexBuildCmd represents the walIndexBuild command
+var walIndexBuildCmd = &cobra.Command{
+	Use:   WalIndexBuildUsage,
+	Short: WalIndexBuildShortDescription,
+	Long:  WalIndexBuildLongDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		tracelog.ErrorLogger.FatalOnError(err)
+		postgres.HandleWalIndexBuild(folder)
+	},
+}
+
+func init() {
+	Cmd.AddCommand(walIndexBuildCmd)
+}
@@ -1,21 +1,47 @@
 package pg
 
 import (
+	"context"
+	"os"
+	"syscall"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/asm"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	WalPushShortDescription = "Uploads a WAL file to storage"
+	WalPushDaemonFlag       = "daemon"
+	WalPushDaemonDesc       = "Run as the daemon uploading the WAL files sent to the " +
+		internal.WalPushDaemonSocketSetting + " socket"
 )
 
-const WalPushShortDescription = "Uploads a WAL file to storage"
+var walPushDaemon bool
 
 // walPushCmd represents the walPush command
 var walPushCmd = &cobra.Command{
 	Use:   "wal-push wal_filepath",
 	Short: WalPushShortDescription, // TODO : improve description
-	Args:  cobra.ExactArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if walPushDaemon {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		socketPath := viper.GetString(internal.WalPushDaemonSocketSetting)
+		if !walPushDaemon && socketPath != "" {
+			// the daemon does the upload, the storage is not configured here
+			err := postgres.PushToWalPushDaemon(socketPath, args[0])
+			tracelog.ErrorLogger.FatalOnError(err)
+			return
+		}
+
 		uploader, err := postgres.ConfigureWalUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
 
@@ -35,10 +61,21 @@ var walPushCmd = &cobra.Command{
 			uploader.PGArchiveStatusManager = asm.NewNopASM()
 		}
 
+		if walPushDaemon {
+			if socketPath == "" {
+				tracelog.ErrorLogger.Fatalf("%s is required to run the wal-push daemon\n", internal.WalPushDaemonSocketSetting)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
+			defer func() { _ = signalHandler.Close() }()
+			postgres.HandleWalPushDaemon(ctx, uploader, socketPath)
+			return
+		}
 		postgres.HandleWALPush(uploader, args[0])
 	},
 }
 
 func init() {
+	walPushCmd.Flags().BoolVar(&walPushDaemon, WalPushDaemonFlag, false, WalPushDaemonDesc)
 	Cmd.AddCommand(walPushCmd)
 }
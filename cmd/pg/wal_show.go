@@ -21,8 +21,18 @@ const (
 
 	disableBackupsLookupFlag        = "without-backups"
 	disableBackupsLookupDescription = "Disable backups lookup for each timeline."
+
+	walShowOutputFlag        = "output"
+	walShowOutputDescription = "Output format: table, json, dot (Graphviz timeline graph) or tree-json (timeline tree)."
 )
 
+var walShowOutputTypes = map[string]postgres.WalShowOutputType{
+	"table":     postgres.TableOutput,
+	"json":      postgres.JSONOutput,
+	"dot":       postgres.DotOutput,
+	"tree-json": postgres.TreeJSONOutput,
+}
+
 var (
 	// walShowCmd represents the walShow command
 	walShowCmd = &cobra.Command{
@@ -33,8 +43,14 @@ var (
 		Run: func(cmd *cobra.Command, args []string) {
 			folder, err := internal.ConfigureFolder()
 			tracelog.ErrorLogger.FatalOnError(err)
-			outputType := postgres.TableOutput
+			outputType, ok := walShowOutputTypes[walShowOutput]
+			if !ok {
+				tracelog.ErrorLogger.Fatalf("Unknown output format '%s'\n", walShowOutput)
+			}
 			if detailedJSONOutput {
+				if cmd.Flags().Changed(walShowOutputFlag) {
+					tracelog.ErrorLogger.Fatalf("--%s can't be used with --%s\n", detailedOutputFlag, walShowOutputFlag)
+				}
 				outputType = postgres.JSONOutput
 			}
 			outputWriter := postgres.NewWalShowOutputWriter(outputType, os.Stdout, !disableBackupsLookup)
@@ -43,10 +59,12 @@ var (
 	}
 	detailedJSONOutput   bool
 	disableBackupsLookup bool
+	walShowOutput        string
 )
 
 func init() {
 	Cmd.AddCommand(walShowCmd)
 	walShowCmd.Flags().BoolVar(&detailedJSONOutput, detailedOutputFlag, false, detailedOutputDescription)
 	walShowCmd.Flags().BoolVar(&disableBackupsLookup, disableBackupsLookupFlag, false, disableBackupsLookupDescription)
+	walShowCmd.Flags().StringVar(&walShowOutput, walShowOutputFlag, "table", walShowOutputDescription)
 }
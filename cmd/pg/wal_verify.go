@@ -15,7 +15,7 @@ import (
 
 const (
 	WalVerifyUsage            = "wal-verify"
-	WalVerifyShortDescription = "Verify WAL storage folder. Available checks: integrity, timeline."
+	WalVerifyShortDescription = "Verify WAL storage folder. Available checks: integrity, timeline, content."
 	WalVerifyLongDescription  = "Run a set of specified checks to ensure WAL storage health."
 
 	useJSONOutputFlag        = "json"
@@ -23,12 +23,14 @@ const (
 
 	checkIntegrityArg = "integrity"
 	checkTimelineArg  = "timeline"
+	checkContentArg   = "content"
 )
 
 var (
 	availableChecks = map[string]postgres.WalVerifyCheckType{
 		checkIntegrityArg: postgres.WalVerifyIntegrityCheck,
 		checkTimelineArg:  postgres.WalVerifyTimelineCheck,
+		checkContentArg:   postgres.WalVerifyContentCheck,
 	}
 	// walVerifyCmd represents the walVerify command
 	walVerifyCmd = &cobra.Command{
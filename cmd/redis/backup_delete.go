@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"os"
 	"time"
 
 	"github.com/wal-g/wal-g/internal/databases/redis"
@@ -12,16 +13,21 @@ import (
 )
 
 const (
-	retainAfterFlag  = "retain-after"
-	retainCountFlag  = "retain-count"
-	purgeGarbageFlag = "purge-garbage"
+	retainAfterFlag   = "retain-after"
+	retainCountFlag   = "retain-count"
+	purgeGarbageFlag  = "purge-garbage"
+	retainDailyFlag   = "retain-daily"
+	retainWeeklyFlag  = "retain-weekly"
+	retainMonthlyFlag = "retain-monthly"
 )
 
 var (
-	confirmed    bool
-	purgeGarbage bool
-	retainAfter  string
-	retainCount  uint
+	confirmed     bool
+	purgeGarbage  bool
+	retainAfter   string
+	retainCount   uint
+	retainPolicy  internal.RetainPolicy
+	printPlanJSON bool
 )
 
 // deleteCmd represents the delete command
@@ -50,6 +56,14 @@ func runDelete(cmd *cobra.Command, args []string) {
 		opts = append(opts, redis.PurgeRetainCount(int(retainCount)))
 	}
 
+	if !retainPolicy.IsEmpty() {
+		opts = append(opts, redis.PurgeRetainPolicy(retainPolicy))
+	}
+
+	if printPlanJSON {
+		opts = append(opts, redis.PurgePlanOutput(os.Stdout))
+	}
+
 	err := redis.HandlePurge(utility.BaseBackupPath, opts...)
 	tracelog.ErrorLogger.FatalOnError(err)
 }
@@ -57,7 +71,11 @@ func runDelete(cmd *cobra.Command, args []string) {
 func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteCmd.Flags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.Flags().BoolVar(&printPlanJSON, internal.DeletePlanJSONFlag, false, internal.DeletePlanJSONDescription)
 	deleteCmd.Flags().BoolVar(&purgeGarbage, purgeGarbageFlag, false, "Delete garbage in backup folder")
 	deleteCmd.Flags().StringVar(&retainAfter, retainAfterFlag, "", "Keep backups newer")
 	deleteCmd.Flags().UintVar(&retainCount, retainCountFlag, 0, "Keep minimum count, except permanent backups")
+	deleteCmd.Flags().IntVar(&retainPolicy.Daily, retainDailyFlag, 0, internal.DeleteRetainDailyDescription)
+	deleteCmd.Flags().IntVar(&retainPolicy.Weekly, retainWeeklyFlag, 0, internal.DeleteRetainWeeklyDescription)
+	deleteCmd.Flags().IntVar(&retainPolicy.Monthly, retainMonthlyFlag, 0, internal.DeleteRetainMonthlyDescription)
 }
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+var (
+	// backupVerifyCmd represents the backupVerify command
+	backupVerifyCmd = &cobra.Command{
+		Use:   "backup-verify backup-name",
+		Short: internal.BackupVerifyShortDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			targetBackupSelector, err := internal.NewBackupNameSelector(args[0])
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			internal.HandleBackupVerify(folder, targetBackupSelector, internal.VerifyStreamBackup,
+				os.Stdout, backupVerifyJSONOutput)
+		},
+	}
+	backupVerifyJSONOutput bool
+)
+
+func init() {
+	cmd.AddCommand(backupVerifyCmd)
+	backupVerifyCmd.Flags().BoolVar(&backupVerifyJSONOutput, internal.BackupVerifyJSONFlag,
+		false, internal.BackupVerifyJSONDescription)
+}
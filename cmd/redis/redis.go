@@ -24,6 +24,13 @@ var cmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		err := internal.AssertRequiredSettingsSet()
 		tracelog.ErrorLogger.FatalOnError(err)
+		internal.ConfigureMetricsLabels(internal.REDIS, cmd)
+		internal.WriteMetricsTextFile(false)
+		err = internal.ConfigureAndRunDefaultWebServer()
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		internal.WriteMetricsTextFile(true)
 	},
 }
 
@@ -161,6 +161,8 @@ func GetBackupByName(backupName, subfolder string, folder storage.Folder) (Backu
 }
 
 // TODO : unit tests
+// UploadSentinel uploads the checksum manifest of the backup objects and then the sentinel,
+// which marks the backup as complete
 func UploadSentinel(uploader UploaderProvider, sentinelDto interface{}, backupName string) error {
 	sentinelName := SentinelNameFromBackup(backupName)
 
@@ -169,6 +171,11 @@ func UploadSentinel(uploader UploaderProvider, sentinelDto interface{}, backupNa
 		return NewSentinelMarshallingError(sentinelName, err)
 	}
 
+	err = UploadChecksumManifest(uploader, backupName)
+	if err != nil {
+		return err
+	}
+
 	return uploader.Upload(sentinelName, bytes.NewReader(dtoBody))
 }
 
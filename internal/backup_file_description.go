@@ -13,10 +13,12 @@ type BackupFileDescription struct {
 	MTime         time.Time
 	CorruptBlocks *CorruptBlocksInfo `json:",omitempty"`
 	UpdatesCount  uint64
+	// Size is the size of the file on the disk at backup time
+	Size int64 `json:",omitempty"`
 }
 
 func NewBackupFileDescription(isIncremented, isSkipped bool, modTime time.Time) *BackupFileDescription {
-	return &BackupFileDescription{isIncremented, isSkipped, modTime, nil, 0}
+	return &BackupFileDescription{isIncremented, isSkipped, modTime, nil, 0, 0}
 }
 
 type CorruptBlocksInfo struct {
@@ -165,7 +165,22 @@ func FolderSize(folder storage.Folder, path string) (int64, error) {
 func SplitPurgingBackups(backups []TimedBackup,
 	retainCount *int,
 	retainAfter *time.Time) (purge, retain map[string]bool, err error) {
-	retain = make(map[string]bool)
+	purge, retainReasons, err := SplitPurgingBackupsWithReasons(backups, retainCount, retainAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	retain = make(map[string]bool, len(retainReasons))
+	for name := range retainReasons {
+		retain[name] = true
+	}
+	return purge, retain, nil
+}
+
+// SplitPurgingBackupsWithReasons partitions backups to delete and retain, providing the reasons to retain
+func SplitPurgingBackupsWithReasons(backups []TimedBackup,
+	retainCount *int,
+	retainAfter *time.Time) (purge map[string]bool, retain map[string][]string, err error) {
+	retain = make(map[string][]string)
 	purge = make(map[string]bool)
 
 	retainedCount := 0
@@ -173,7 +188,7 @@ func SplitPurgingBackups(backups []TimedBackup,
 		backup := backups[i]
 		if backup.IsPermanent() {
 			tracelog.DebugLogger.Printf("Preserving backup due to keep permanent policy: %s", backup.Name())
-			retain[backup.Name()] = true
+			retain[backup.Name()] = []string{"permanent"}
 			continue
 		}
 
@@ -181,13 +196,13 @@ func SplitPurgingBackups(backups []TimedBackup,
 			retainedCount++
 			tracelog.DebugLogger.Printf("Preserving backup due to retain count policy [%d/%d]: %s",
 				retainedCount, *retainCount, backup.Name())
-			retain[backup.Name()] = true
+			retain[backup.Name()] = []string{fmt.Sprintf("retain count %d/%d", retainedCount, *retainCount)}
 			continue
 		}
 
 		if retainAfter != nil && backup.StartTime().After(*retainAfter) { // TODO: fix condition, use func args
 			tracelog.DebugLogger.Printf("Preserving backup due to retain time policy: %s", backup.Name())
-			retain[backup.Name()] = true
+			retain[backup.Name()] = []string{"newer than " + retainAfter.Format(time.RFC3339)}
 			continue
 		}
 		purge[backup.Name()] = true
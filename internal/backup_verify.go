@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BackupVerifyUsage            = "backup-verify [backup_name | LATEST]"
+	BackupVerifyShortDescription = "Checks that the stored backup can be restored without restoring it"
+	BackupVerifyJSONFlag         = "json"
+	BackupVerifyJSONDescription  = "Show output in JSON format."
+)
+
+type BackupVerifyStatus int
+
+const (
+	BackupVerifyStatusOk BackupVerifyStatus = iota + 1
+	BackupVerifyStatusWarning
+	BackupVerifyStatusFailure
+)
+
+func (status BackupVerifyStatus) String() string {
+	return [...]string{"", "OK", "WARNING", "FAILURE"}[status]
+}
+
+// MarshalText marshals the BackupVerifyStatus enum as a string
+func (status BackupVerifyStatus) MarshalText() ([]byte, error) {
+	return utility.MarshalEnumToString(status)
+}
+
+// BackupVerifyIssue describes the problem found in the backup object (tar partition, stream)
+// or in the file stored in it
+type BackupVerifyIssue struct {
+	Status  BackupVerifyStatus `json:"status"`
+	Object  string             `json:"object"`
+	File    string             `json:"file,omitempty"`
+	Message string             `json:"message"`
+}
+
+// BackupVerifyResult is the report of backup-verify, the status is the worst status of the issues found
+type BackupVerifyResult struct {
+	BackupName     string              `json:"backup_name"`
+	Status         BackupVerifyStatus  `json:"status"`
+	CheckedObjects int                 `json:"checked_objects"`
+	CheckedFiles   int                 `json:"checked_files"`
+	Issues         []BackupVerifyIssue `json:"issues"`
+
+	mutex sync.Mutex
+}
+
+func NewBackupVerifyResult(backupName string) *BackupVerifyResult {
+	return &BackupVerifyResult{
+		BackupName: backupName,
+		Status:     BackupVerifyStatusOk,
+		Issues:     make([]BackupVerifyIssue, 0),
+	}
+}
+
+func (result *BackupVerifyResult) AddIssue(status BackupVerifyStatus, object, file, message string) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.Issues = append(result.Issues, BackupVerifyIssue{status, object, file, message})
+	if status > result.Status {
+		result.Status = status
+	}
+}
+
+func (result *BackupVerifyResult) AddCheckedObject(filesCount int) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.CheckedObjects++
+	result.CheckedFiles += filesCount
+}
+
+// Write prints the result as JSON or as plain text
+func (result *BackupVerifyResult) Write(output io.Writer, jsonOutput bool) error {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].Object != result.Issues[j].Object {
+			return result.Issues[i].Object < result.Issues[j].Object
+		}
+		return result.Issues[i].File < result.Issues[j].File
+	})
+	if jsonOutput {
+		return json.NewEncoder(output).Encode(result)
+	}
+
+	var outputBuffer bytes.Buffer
+	outputBuffer.WriteString(fmt.Sprintf("[backup-verify] %s status: %s\n", result.BackupName, result.Status))
+	outputBuffer.WriteString(fmt.Sprintf("[backup-verify] checked %d objects, %d files\n",
+		result.CheckedObjects, result.CheckedFiles))
+	for _, issue := range result.Issues {
+		location := issue.Object
+		if issue.File != "" {
+			location += ": " + issue.File
+		}
+		outputBuffer.WriteString(fmt.Sprintf("%s %s: %s\n", issue.Status, location, issue.Message))
+	}
+	_, err := io.Copy(output, &outputBuffer)
+	return err
+}
+
+type countingWriteCloser struct {
+	written int64
+}
+
+func (writer *countingWriteCloser) Write(p []byte) (int, error) {
+	writer.written += int64(len(p))
+	return len(p), nil
+}
+
+func (writer *countingWriteCloser) Close() error {
+	return nil
+}
+
+// VerifyStreamBackup downloads, decrypts and decompresses the backup stream
+// and checks it against the checksum manifest. The stream content is not parsed.
+func VerifyStreamBackup(backup Backup) *BackupVerifyResult {
+	result := NewBackupVerifyResult(backup.Name)
+	CheckChecksumManifest(backup, result)
+
+	stream := &countingWriteCloser{}
+	err := downloadAndDecompressStream(backup, stream)
+	if err != nil {
+		result.AddIssue(BackupVerifyStatusFailure, backup.Name, "", err.Error())
+		return result
+	}
+	tracelog.InfoLogger.Printf("Verified stream of backup %s, %d bytes\n", backup.Name, stream.written)
+	result.AddCheckedObject(0)
+	return result
+}
+
+// HandleBackupVerify verifies the selected backup and writes the report.
+// It exits with an error if the backup verification fails.
+func HandleBackupVerify(folder storage.Folder,
+	targetBackupSelector BackupSelector,
+	verify func(backup Backup) *BackupVerifyResult,
+	output io.Writer,
+	jsonOutput bool) {
+	backupName, err := targetBackupSelector.Select(folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+	backup, err := GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+
+	result := verify(backup)
+	err = result.Write(output, jsonOutput)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if result.Status == BackupVerifyStatusFailure {
+		tracelog.ErrorLogger.Fatalf("Backup %s verification failed\n", backup.Name)
+	}
+}
+
+// CheckChecksumManifest fetches the checksum manifest of the backup
+// and warns if the backup objects can't be verified against it
+func CheckChecksumManifest(backup Backup, result *BackupVerifyResult) ChecksumManifest {
+	manifestName := ChecksumManifestNameFromBackup(backup.Name)
+	manifest, err := FetchChecksumManifest(backup.Folder, backup.Name)
+	if err != nil {
+		result.AddIssue(BackupVerifyStatusWarning, manifestName, "", err.Error())
+		return NewChecksumManifest(map[string]string{})
+	}
+	if len(manifest.Objects) == 0 {
+		result.AddIssue(BackupVerifyStatusWarning, manifestName, "",
+			"backup has no checksum manifest, checksums are not verified")
+	}
+	return manifest
+}
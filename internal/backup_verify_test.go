@@ -0,0 +1,57 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestBackupVerifyResult_StatusIsTheWorstIssueStatus(t *testing.T) {
+	result := internal.NewBackupVerifyResult("base_1")
+	assert.Equal(t, internal.BackupVerifyStatusOk, result.Status)
+
+	result.AddIssue(internal.BackupVerifyStatusFailure, "part_2.tar.lz4", "/base/1/1234", "corrupted")
+	result.AddIssue(internal.BackupVerifyStatusWarning, "part_1.tar.lz4", "", "not listed")
+	assert.Equal(t, internal.BackupVerifyStatusFailure, result.Status)
+
+	var output bytes.Buffer
+	err := result.Write(&output, true)
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &decoded))
+	assert.Equal(t, "FAILURE", decoded["status"])
+	issues := decoded["issues"].([]interface{})
+	assert.Len(t, issues, 2)
+	assert.Equal(t, "part_1.tar.lz4", issues[0].(map[string]interface{})["object"])
+}
+
+func TestVerifyStreamBackup(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	uploader := internal.NewUploader(GetLz4Compressor(), folder)
+	backupName, err := uploader.PushStream(strings.NewReader("stream content"))
+	assert.NoError(t, err)
+	err = internal.UploadSentinel(uploader, struct{}{}, backupName)
+	assert.NoError(t, err)
+
+	result := internal.VerifyStreamBackup(internal.NewBackup(folder, backupName))
+	assert.Equal(t, internal.BackupVerifyStatusOk, result.Status)
+	assert.Equal(t, 1, result.CheckedObjects)
+
+	streamName := internal.GetStreamName(backupName, GetLz4Compressor().FileExtension())
+	err = folder.PutObject(streamName, strings.NewReader("corrupted"))
+	assert.NoError(t, err)
+	result = internal.VerifyStreamBackup(internal.NewBackup(folder, backupName))
+	assert.Equal(t, internal.BackupVerifyStatusFailure, result.Status)
+}
+
+func TestVerifyStreamBackup_MissingStream(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+
+	result := internal.VerifyStreamBackup(internal.NewBackup(folder, "stream_1"))
+	assert.Equal(t, internal.BackupVerifyStatusFailure, result.Status)
+}
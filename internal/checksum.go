@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const ChecksumAlgorithm = "sha256"
+
+type ChecksumMismatchError struct {
+	error
+}
+
+func NewChecksumMismatchError(objectPath, expected, actual string) ChecksumMismatchError {
+	return ChecksumMismatchError{errors.Errorf(
+		"checksum mismatch for '%s': expected %s %s, got %s", objectPath, ChecksumAlgorithm, expected, actual)}
+}
+
+func (err ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ObjectChecksums collects the checksums of the uploaded objects, keyed by the object path.
+// The sizes of the objects are kept along for the WAL index.
+type ObjectChecksums struct {
+	mutex     sync.Mutex
+	checksums map[string]string
+	sizes     map[string]int64
+}
+
+func NewObjectChecksums() *ObjectChecksums {
+	return &ObjectChecksums{checksums: make(map[string]string), sizes: make(map[string]int64)}
+}
+
+func (c *ObjectChecksums) Set(objectPath, checksum string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checksums[objectPath] = checksum
+}
+
+func (c *ObjectChecksums) SetSize(objectPath string, size int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sizes[objectPath] = size
+}
+
+// TakeSize removes and returns the size of the object
+func (c *ObjectChecksums) TakeSize(objectPath string) (int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	size, ok := c.sizes[objectPath]
+	delete(c.sizes, objectPath)
+	return size, ok
+}
+
+func (c *ObjectChecksums) Get(objectPath string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	checksum, ok := c.checksums[objectPath]
+	return checksum, ok
+}
+
+// Take removes and returns the checksums of the objects with the given path prefix.
+// Long-running uploaders take the checksums once they are stored somewhere, so the collection doesn't grow.
+func (c *ObjectChecksums) Take(prefix string) map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	taken := make(map[string]string)
+	for objectPath, checksum := range c.checksums {
+		if strings.HasPrefix(objectPath, prefix) {
+			taken[objectPath] = checksum
+			delete(c.checksums, objectPath)
+		}
+	}
+	for objectPath := range c.sizes {
+		if strings.HasPrefix(objectPath, prefix) {
+			delete(c.sizes, objectPath)
+		}
+	}
+	return taken
+}
+
+// ChecksumManifest lists the checksums of the backup objects.
+// It is uploaded next to the backup sentinel, object paths are relative to the backups folder.
+type ChecksumManifest struct {
+	Algorithm string            `json:"algorithm"`
+	Objects   map[string]string `json:"objects"`
+}
+
+func NewChecksumManifest(objects map[string]string) ChecksumManifest {
+	return ChecksumManifest{Algorithm: ChecksumAlgorithm, Objects: objects}
+}
+
+// ChecksumManifestNameFromBackup returns the name of the manifest uploaded along with the backup
+func ChecksumManifestNameFromBackup(backupName string) string {
+	return backupName + utility.ChecksumManifestSuffix
+}
+
+// UploadChecksumManifest uploads the checksums of the backup objects collected by the uploader.
+// Nothing is uploaded if the uploader hasn't uploaded any backup object.
+func UploadChecksumManifest(uploader UploaderProvider, backupName string) error {
+	objects := uploader.TakeObjectChecksums(backupName + "/")
+	if len(objects) == 0 {
+		return nil
+	}
+	manifestName := ChecksumManifestNameFromBackup(backupName)
+	dtoBody, err := json.Marshal(NewChecksumManifest(objects))
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal checksum manifest '%s'", manifestName)
+	}
+	return uploader.Upload(manifestName, bytes.NewReader(dtoBody))
+}
+
+// FetchChecksumManifest downloads the checksum manifest of the backup.
+// Backups made before the manifests were introduced have none, in this case the manifest is empty.
+func FetchChecksumManifest(folder storage.Folder, backupName string) (ChecksumManifest, error) {
+	manifestName := ChecksumManifestNameFromBackup(backupName)
+	reader, exists, err := TryDownloadFile(folder, manifestName)
+	if err != nil {
+		return ChecksumManifest{}, errors.Wrapf(err, "failed to download checksum manifest '%s'", manifestName)
+	}
+	if !exists {
+		tracelog.WarningLogger.Printf("Backup %s has no checksum manifest, checksums will not be verified\n", backupName)
+		return NewChecksumManifest(map[string]string{}), nil
+	}
+	defer utility.LoggedClose(reader, "")
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return ChecksumManifest{}, errors.Wrapf(err, "failed to download checksum manifest '%s'", manifestName)
+	}
+	var manifest ChecksumManifest
+	if err = json.Unmarshal(body, &manifest); err != nil {
+		return ChecksumManifest{}, errors.Wrapf(err, "failed to unmarshal checksum manifest '%s'", manifestName)
+	}
+	if manifest.Algorithm != ChecksumAlgorithm {
+		return ChecksumManifest{}, errors.Errorf("unsupported checksum algorithm '%s' in manifest '%s'",
+			manifest.Algorithm, manifestName)
+	}
+	return manifest, nil
+}
+
+// Checksum returns the expected checksum of the object, empty if the manifest doesn't list it
+func (manifest ChecksumManifest) Checksum(objectPath string) string {
+	return manifest.Objects[objectPath]
+}
+
+// ChecksumVerifier is implemented by the readers checking the content against the expected checksum
+type ChecksumVerifier interface {
+	// VerifyChecksum reads the rest of the content and compares its checksum with the expected one
+	VerifyChecksum() error
+}
+
+// VerifyChecksum verifies the reader content if the reader is a ChecksumVerifier
+func VerifyChecksum(reader io.Reader) error {
+	if verifier, ok := reader.(ChecksumVerifier); ok {
+		return verifier.VerifyChecksum()
+	}
+	return nil
+}
+
+type checksumVerifyingReader struct {
+	io.ReadCloser
+	objectPath string
+	expected   string
+	hash       hash.Hash
+	verified   bool
+	err        error
+}
+
+// NewChecksumVerifyingReader returns the reader which fails with ChecksumMismatchError at the end of the content
+// if its checksum differs from the expected one. Empty expected checksum disables the verification.
+func NewChecksumVerifyingReader(reader io.ReadCloser, objectPath, expected string) io.ReadCloser {
+	if expected == "" {
+		return reader
+	}
+	return &checksumVerifyingReader{ReadCloser: reader, objectPath: objectPath, expected: expected, hash: sha256.New()}
+}
+
+func (reader *checksumVerifyingReader) Read(p []byte) (int, error) {
+	if reader.verified {
+		return 0, reader.eof()
+	}
+	n, err := reader.ReadCloser.Read(p)
+	_, _ = reader.hash.Write(p[:n])
+	if err == io.EOF {
+		_ = reader.verify()
+		return n, reader.eof()
+	}
+	return n, err
+}
+
+func (reader *checksumVerifyingReader) VerifyChecksum() error {
+	if reader.verified {
+		return reader.err
+	}
+	// decompressors may stop before the end of the object, e.g. at the end of the compressed frame
+	if _, err := io.Copy(reader.hash, reader.ReadCloser); err != nil {
+		return err
+	}
+	return reader.verify()
+}
+
+func (reader *checksumVerifyingReader) verify() error {
+	reader.verified = true
+	actual := hex.EncodeToString(reader.hash.Sum(nil))
+	if actual != reader.expected {
+		reader.err = NewChecksumMismatchError(reader.objectPath, reader.expected, actual)
+		tracelog.ErrorLogger.Println(reader.err)
+	}
+	return reader.err
+}
+
+func (reader *checksumVerifyingReader) eof() error {
+	if reader.err != nil {
+		return reader.err
+	}
+	return io.EOF
+}
+
+// seekableContent is uploaded by storages without buffering, so it is not wrapped into other readers
+type seekableContent interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+// computeSeekableChecksum reads the content to compute its checksum and size, then rewinds it
+func computeSeekableChecksum(content seekableContent) (checksum string, size int64, err error) {
+	start, err := content.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, err
+	}
+	hash := sha256.New()
+	size, err = io.Copy(hash, content)
+	if err != nil {
+		return "", 0, err
+	}
+	_, err = content.Seek(start, io.SeekStart)
+	return hex.EncodeToString(hash.Sum(nil)), size, err
+}
@@ -0,0 +1,96 @@
+package internal_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUploader_Upload_RecordsChecksums(t *testing.T) {
+	uploader := internal.NewUploader(&testtools.MockCompressor{}, testtools.MakeDefaultInMemoryStorageFolder())
+
+	// MultiReader hides io.Seeker, so the content is streamed
+	err := uploader.Upload("stream", io.MultiReader(strings.NewReader("streamed content")))
+	assert.NoError(t, err)
+	err = uploader.Upload("seekable", bytes.NewReader([]byte("seekable content")))
+	assert.NoError(t, err)
+
+	checksum, ok := uploader.ObjectChecksum("stream")
+	assert.True(t, ok)
+	assert.Equal(t, sha256Hex("streamed content"), checksum)
+	checksum, ok = uploader.ObjectChecksum("seekable")
+	assert.True(t, ok)
+	assert.Equal(t, sha256Hex("seekable content"), checksum)
+
+	reader, err := uploader.UploadingFolder.ReadObject("seekable")
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "seekable content", string(content))
+}
+
+func TestUploadSentinel_UploadsChecksumManifest(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	uploader := internal.NewUploader(&testtools.MockCompressor{}, folder)
+
+	err := uploader.Upload("base_1/tar_partitions/part_1.tar.mock", strings.NewReader("part"))
+	assert.NoError(t, err)
+	err = uploader.Upload("base_2/tar_partitions/part_1.tar.mock", strings.NewReader("other backup"))
+	assert.NoError(t, err)
+	err = internal.UploadSentinel(uploader, struct{}{}, "base_1")
+	assert.NoError(t, err)
+
+	manifest, err := internal.FetchChecksumManifest(folder, "base_1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"base_1/tar_partitions/part_1.tar.mock": sha256Hex("part")}, manifest.Objects)
+	assert.Empty(t, uploader.TakeObjectChecksums("base_1/"))
+	assert.Len(t, uploader.TakeObjectChecksums("base_2/"), 1)
+}
+
+func TestFetchChecksumManifest_EmptyForOldBackups(t *testing.T) {
+	manifest, err := internal.FetchChecksumManifest(testtools.MakeDefaultInMemoryStorageFolder(), "base_1")
+	assert.NoError(t, err)
+	assert.Empty(t, manifest.Checksum("base_1/tar_partitions/part_1.tar.mock"))
+}
+
+func TestChecksumVerifyingReader_Match(t *testing.T) {
+	reader := internal.NewChecksumVerifyingReader(
+		ioutil.NopCloser(strings.NewReader("content")), "object", sha256Hex("content"))
+
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.NoError(t, internal.VerifyChecksum(reader))
+}
+
+func TestChecksumVerifyingReader_Mismatch(t *testing.T) {
+	reader := internal.NewChecksumVerifyingReader(
+		ioutil.NopCloser(strings.NewReader("corrupted")), "object", sha256Hex("content"))
+
+	_, err := ioutil.ReadAll(reader)
+	assert.IsType(t, internal.ChecksumMismatchError{}, err)
+	assert.IsType(t, internal.ChecksumMismatchError{}, internal.VerifyChecksum(reader))
+}
+
+func TestChecksumVerifyingReader_VerifiesUnreadContent(t *testing.T) {
+	reader := internal.NewChecksumVerifyingReader(
+		ioutil.NopCloser(strings.NewReader("content with trailing bytes")), "object", sha256Hex("content"))
+
+	buf := make([]byte, len("content"))
+	_, err := io.ReadFull(reader, buf)
+	assert.NoError(t, err)
+	assert.IsType(t, internal.ChecksumMismatchError{}, internal.VerifyChecksum(reader))
+}
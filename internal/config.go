@@ -13,6 +13,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/internal/webserver"
 )
 
@@ -73,6 +74,15 @@ const (
 	MaxDelayedSegmentsCount      = "WALG_INTEGRITY_MAX_DELAYED_WALS"
 	PrefetchDir                  = "WALG_PREFETCH_DIR"
 	PgReadyRename                = "PG_READY_RENAME"
+	UseWalIndexSetting           = "WALG_USE_WAL_INDEX"
+	WalPushDaemonSocketSetting   = "WALG_WAL_PUSH_DAEMON_SOCKET"
+	WalReceiveStandbysSetting    = "WALG_WAL_RECEIVE_STANDBYS"
+	WalReceivePartialInterval    = "WALG_WAL_RECEIVE_PARTIAL_INTERVAL"
+	WalReceivePartialBytes       = "WALG_WAL_RECEIVE_PARTIAL_BYTES"
+	WalFetchPartialSetting       = "WALG_WAL_FETCH_PARTIAL"
+	DeltaFromLocalWalSetting     = "WALG_DELTA_FROM_LOCAL_WAL"
+	BackupPushStateFileSetting   = "WALG_BACKUP_PUSH_STATE_FILE"
+	ReplicaMaxReplayLagSetting   = "WALG_REPLICA_MAX_REPLAY_LAG"
 
 	MongoDBUriSetting               = "MONGODB_URI"
 	MongoDBLastWriteUpdateInterval  = "MONGODB_LAST_WRITE_UPDATE_INTERVAL"
@@ -100,9 +110,26 @@ const (
 
 	GoMaxProcs = "GOMAXPROCS"
 
-	HTTPListen       = "HTTP_LISTEN"
-	HTTPExposePprof  = "HTTP_EXPOSE_PPROF"
-	HTTPExposeExpVar = "HTTP_EXPOSE_EXPVAR"
+	HTTPListen        = "HTTP_LISTEN"
+	HTTPExposePprof   = "HTTP_EXPOSE_PPROF"
+	HTTPExposeExpVar  = "HTTP_EXPOSE_EXPVAR"
+	HTTPExposeMetrics = "HTTP_EXPOSE_METRICS"
+
+	MetricsTextFileSetting = "WALG_METRICS_TEXTFILE"
+
+	FaultLatencySetting         = "WALG_FAULT_LATENCY"
+	FaultErrorRateSetting       = "WALG_FAULT_ERROR_RATE"
+	FaultPartialReadRateSetting = "WALG_FAULT_PARTIAL_READ_RATE"
+	FaultListingLagSetting      = "WALG_FAULT_LISTING_LAG"
+	FaultSeedSetting            = "WALG_FAULT_SEED"
+
+	StorageRetryAttemptsSetting   = "WALG_STORAGE_RETRY_ATTEMPTS"
+	StorageRetryMinBackoffSetting = "WALG_STORAGE_RETRY_MIN_BACKOFF"
+	StorageRetryMaxBackoffSetting = "WALG_STORAGE_RETRY_MAX_BACKOFF"
+	StorageRetryJitterSetting     = "WALG_STORAGE_RETRY_JITTER"
+
+	UploadFanOutPolicySetting     = "WALG_UPLOAD_FAN_OUT_POLICY"
+	FailoverStorageConfigsSetting = "WALG_FAILOVER_STORAGE_CONFIGS"
 
 	SQLServerBlobHostname     = "SQLSERVER_BLOB_HOSTNAME"
 	SQLServerBlobCertFile     = "SQLSERVER_BLOB_CERT_FILE"
@@ -125,23 +152,27 @@ var (
 	defaultConfigValues map[string]string
 
 	commonDefaultConfigValues = map[string]string{
-		DownloadConcurrencySetting:   "10",
-		UploadConcurrencySetting:     "16",
-		UploadDiskConcurrencySetting: "1",
-		UploadQueueSetting:           "2",
-		PreventWalOverwriteSetting:   "false",
-		UploadWalMetadata:            "NOMETADATA",
-		DeltaMaxStepsSetting:         "0",
-		CompressionMethodSetting:     "lz4",
-		UseWalDeltaSetting:           "false",
-		TarSizeThresholdSetting:      "1073741823", // (1 << 30) - 1
-		TotalBgUploadedLimit:         "32",
-		UseReverseUnpackSetting:      "false",
-		SkipRedundantTarsSetting:     "false",
-		VerifyPageChecksumsSetting:   "false",
-		StoreAllCorruptBlocksSetting: "false",
-		UseRatingComposerSetting:     "false",
-		MaxDelayedSegmentsCount:      "0",
+		DownloadConcurrencySetting:    "10",
+		UploadConcurrencySetting:      "16",
+		UploadDiskConcurrencySetting:  "1",
+		UploadQueueSetting:            "2",
+		PreventWalOverwriteSetting:    "false",
+		UploadWalMetadata:             "NOMETADATA",
+		DeltaMaxStepsSetting:          "0",
+		CompressionMethodSetting:      "lz4",
+		UseWalDeltaSetting:            "false",
+		TarSizeThresholdSetting:       "1073741823", // (1 << 30) - 1
+		TotalBgUploadedLimit:          "32",
+		UseReverseUnpackSetting:       "false",
+		SkipRedundantTarsSetting:      "false",
+		VerifyPageChecksumsSetting:    "false",
+		StoreAllCorruptBlocksSetting:  "false",
+		UseRatingComposerSetting:      "false",
+		MaxDelayedSegmentsCount:       "0",
+		StorageRetryAttemptsSetting:   "3",
+		StorageRetryMinBackoffSetting: "1s",
+		StorageRetryMaxBackoffSetting: "10s",
+		StorageRetryJitterSetting:     "0.2",
 	}
 
 	MongoDefaultSettings = map[string]string{
@@ -155,7 +186,8 @@ var (
 	}
 
 	PGDefaultSettings = map[string]string{
-		PgWalSize: "16",
+		PgWalSize:                  "16",
+		ReplicaMaxReplayLagSetting: "1h",
 	}
 
 	AllowedSettings map[string]bool
@@ -264,9 +296,29 @@ var (
 		GoMaxProcs: true,
 
 		// Web server
-		HTTPListen:       true,
-		HTTPExposePprof:  true,
-		HTTPExposeExpVar: true,
+		HTTPListen:        true,
+		HTTPExposePprof:   true,
+		HTTPExposeExpVar:  true,
+		HTTPExposeMetrics: true,
+
+		MetricsTextFileSetting: true,
+
+		// Storage fault injection
+		FaultLatencySetting:         true,
+		FaultErrorRateSetting:       true,
+		FaultPartialReadRateSetting: true,
+		FaultListingLagSetting:      true,
+		FaultSeedSetting:            true,
+
+		// Storage retries
+		StorageRetryAttemptsSetting:   true,
+		StorageRetryMinBackoffSetting: true,
+		StorageRetryMaxBackoffSetting: true,
+		StorageRetryJitterSetting:     true,
+
+		// Several storages
+		UploadFanOutPolicySetting:     true,
+		FailoverStorageConfigsSetting: true,
 	}
 
 	PGAllowedSettings = map[string]bool{
@@ -283,6 +335,21 @@ var (
 		"PGPASSFILE":      true,
 		PrefetchDir:       true,
 		PgReadyRename:     true,
+
+		// WAL archiving
+		UseWalIndexSetting:         true,
+		WalPushDaemonSocketSetting: true,
+		WalReceiveStandbysSetting:  true,
+		WalReceivePartialInterval:  true,
+		WalReceivePartialBytes:     true,
+		WalFetchPartialSetting:     true,
+
+		// Delta backups
+		DeltaFromLocalWalSetting: true,
+
+		// Backup push
+		BackupPushStateFileSetting: true,
+		ReplicaMaxReplayLagSetting: true,
 	}
 
 	MongoAllowedSettings = map[string]bool{
@@ -328,6 +395,7 @@ var (
 	HTTPSettingExposeFuncs = map[string]func(webserver.WebServer){
 		HTTPExposePprof:          webserver.EnablePprofEndpoints,
 		HTTPExposeExpVar:         webserver.EnableExpVarEndpoints,
+		HTTPExposeMetrics:        metrics.EnableMetricsEndpoint,
 		OplogPushStatsExposeHTTP: nil,
 	}
 	Turbo bool
@@ -468,6 +536,29 @@ func ConfigureAndRunDefaultWebServer() error {
 	return nil
 }
 
+// ConfigureMetricsLabels attaches database and subcommand names to all exported metrics
+func ConfigureMetricsLabels(dbName string, cmd *cobra.Command) {
+	command := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	metrics.SetConstLabels(map[string]string{
+		"db":      strings.ToLower(dbName),
+		"command": command,
+	})
+}
+
+// WriteMetricsTextFile writes the run summary for the node_exporter textfile collector if it is configured.
+// It is called with success=false on the command start, so runs interrupted by a fatal error are reported as failed.
+func WriteMetricsTextFile(success bool) {
+	path, ok := GetSetting(MetricsTextFileSetting)
+	if !ok {
+		return
+	}
+	metrics.FinishRun(success)
+	err := metrics.DefaultRegistry.WriteTextFile(path)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to write metrics to %s: %v", path, err)
+	}
+}
+
 func AddConfigFlags(Cmd *cobra.Command) {
 	for k := range AllowedSettings {
 		flagName := toFlagName(k)
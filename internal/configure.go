@@ -126,14 +126,72 @@ func configureLimiters() {
 
 // TODO : unit tests
 func ConfigureFolder() (storage.Folder, error) {
-	folder, err := ConfigureFolderForSpecificConfig(viper.GetViper())
+	folder, replicas, err := configureUploadFolder()
 	if err != nil {
 		return nil, err
 	}
-
+	for _, configFile := range GetFailoverStorageConfigs() {
+		replica, err := FolderFromConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		replica, err = configureFolderFaultHandling(replica)
+		if err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, replica)
+	}
+	if len(replicas) > 0 {
+		folder = NewFailoverFolder(folder, replicas)
+	}
 	return ConfigureStoragePrefix(folder), nil
 }
 
+// configureUploadFolder returns the folder everything is uploaded to.
+// If the uploads fan out to several storages, the storages except the first one are returned as the read replicas.
+func configureUploadFolder() (folder storage.Folder, replicas []storage.Folder, err error) {
+	fanOutPolicy, isFanOut, err := GetFanOutPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isFanOut {
+		folder, err = ConfigureFolderForSpecificConfig(viper.GetViper())
+		if err != nil {
+			return nil, nil, err
+		}
+		folder, err = configureFolderFaultHandling(folder)
+		return folder, nil, err
+	}
+
+	folders, err := ConfigureFoldersForSpecificConfig(viper.GetViper())
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range folders {
+		folders[i], err = configureFolderFaultHandling(folders[i])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return NewFanOutFolder(folders, fanOutPolicy), folders[1:], nil
+}
+
+// configureFolderFaultHandling wraps the storage folder into the fault injection and the retries if configured
+func configureFolderFaultHandling(folder storage.Folder) (storage.Folder, error) {
+	faultInjectionConfig, err := GetFaultInjectionConfig()
+	if err != nil {
+		return nil, err
+	}
+	if faultInjectionConfig.IsEnabled() {
+		tracelog.WarningLogger.Printf("Storage fault injection is enabled: %+v\n", faultInjectionConfig)
+		folder = NewFaultInjectingFolder(folder, faultInjectionConfig)
+	}
+	if retryPolicy := GetRetryPolicy(); retryPolicy.IsEnabled() {
+		folder = NewRetryingFolder(folder, retryPolicy)
+	}
+	return folder, nil
+}
+
 func ConfigureStoragePrefix(folder storage.Folder) storage.Folder {
 	prefix := viper.GetString(StoragePrefixSetting)
 	if prefix != "" {
@@ -149,21 +207,37 @@ func ConfigureStoragePrefix(folder storage.Folder) storage.Folder {
 func ConfigureFolderForSpecificConfig(config *viper.Viper) (storage.Folder, error) {
 	skippedPrefixes := make([]string, 0)
 	for _, adapter := range StorageAdapters {
-		prefix, ok := getWaleCompatibleSettingFrom(adapter.prefixName, config)
+		folder, ok, err := adapter.configure(config)
 		if !ok {
 			skippedPrefixes = append(skippedPrefixes, "WALG_"+adapter.prefixName)
 			continue
 		}
-		if adapter.prefixPreprocessor != nil {
-			prefix = adapter.prefixPreprocessor(prefix)
-		}
-
-		settings := adapter.loadSettings(config)
-		return adapter.configureFolder(prefix, settings)
+		return folder, err
 	}
 	return nil, newUnconfiguredStorageError(skippedPrefixes)
 }
 
+// ConfigureFoldersForSpecificConfig returns the folders of all the storages configured, in 'StorageAdapters' order
+func ConfigureFoldersForSpecificConfig(config *viper.Viper) ([]storage.Folder, error) {
+	skippedPrefixes := make([]string, 0)
+	folders := make([]storage.Folder, 0)
+	for _, adapter := range StorageAdapters {
+		folder, ok, err := adapter.configure(config)
+		if !ok {
+			skippedPrefixes = append(skippedPrefixes, "WALG_"+adapter.prefixName)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	if len(folders) == 0 {
+		return nil, newUnconfiguredStorageError(skippedPrefixes)
+	}
+	return folders, nil
+}
+
 func getWalFolderPath() string {
 	if !viper.IsSet(PgDataSetting) {
 		return DefaultDataFolderPath
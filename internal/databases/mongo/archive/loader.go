@@ -219,7 +219,12 @@ func (su *StorageUploader) UploadOplogArchive(stream io.Reader, firstTS, lastTS
 	}
 
 	// providing io.ReaderAt+io.ReadSeeker to s3 upload enables buffer pool usage
-	return su.Upload(arch.Filename(), bytes.NewReader(su.buf.Bytes()))
+	if err := su.Upload(arch.Filename(), bytes.NewReader(su.buf.Bytes())); err != nil {
+		return err
+	}
+	// oplog archives have no checksum manifest, don't keep their checksums during the endless oplog push
+	su.TakeObjectChecksums(arch.Filename())
+	return nil
 }
 
 // UploadGap uploads mark indicating archiving gap.
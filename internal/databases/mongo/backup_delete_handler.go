@@ -1,13 +1,21 @@
 package mongo
 
 import (
+	"io"
+
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
 )
 
 // HandleBackupDelete deletes backup.
-func HandleBackupDelete(backupName string, downloader archive.Downloader, purger archive.Purger, dryRun bool) error {
+// If planOutput is not nil, the deletion plan is written there in JSON format.
+func HandleBackupDelete(backupName string,
+	downloader archive.Downloader,
+	purger archive.Purger,
+	dryRun bool,
+	planOutput io.Writer) error {
 	backup, err := downloader.BackupMeta(backupName)
 	if err != nil {
 		return err
@@ -15,12 +23,21 @@ func HandleBackupDelete(backupName string, downloader archive.Downloader, purger
 
 	if dryRun {
 		tracelog.InfoLogger.Printf("Skipping backup deletion due to dry-run: %+v", backup)
-		return nil
+		return writeBackupDeletePlan(backup, dryRun, planOutput)
 	}
 
 	if err := purger.DeleteBackups([]models.Backup{backup}); err != nil {
 		return err
 	}
 	tracelog.InfoLogger.Printf("Backup was deleted: %+v", backup)
-	return nil
+	return writeBackupDeletePlan(backup, dryRun, planOutput)
+}
+
+func writeBackupDeletePlan(backup models.Backup, dryRun bool, planOutput io.Writer) error {
+	if planOutput == nil {
+		return nil
+	}
+	plan := internal.NewDeletePlan(!dryRun)
+	plan.AddBackupToDelete(backup.BackupName, backup.StartLocalTime, backup.DataSize)
+	return plan.Write(planOutput)
 }
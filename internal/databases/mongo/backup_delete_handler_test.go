@@ -75,7 +75,7 @@ func TestHandleBackupDelete(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := HandleBackupDelete(tt.args.backupName, tt.args.downloader, tt.args.purger, tt.args.dryRun)
+			err := HandleBackupDelete(tt.args.backupName, tt.args.downloader, tt.args.purger, tt.args.dryRun, nil)
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
 				return
@@ -3,9 +3,11 @@ package mongo
 import (
 	"fmt"
 	"os/exec"
+	"time"
 
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -13,6 +15,7 @@ import (
 func HandleBackupPush(uploader archive.Uploader,
 	metaConstructor internal.MetaConstructor,
 	backupCmd *exec.Cmd) error {
+	defer metrics.BackupPushDuration.ObserveDuration(time.Now())
 	if err := metaConstructor.Init(); err != nil {
 		return fmt.Errorf("can not initiate meta provider: %+v", err)
 	}
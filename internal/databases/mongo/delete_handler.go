@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"io"
 	"time"
 
 	"github.com/wal-g/tracelog"
@@ -12,6 +13,9 @@ import (
 type PurgeSettings struct {
 	retainCount  *int
 	retainAfter  *time.Time
+	retainPolicy internal.RetainPolicy
+	planOutput   io.Writer
+	plan         *internal.DeletePlan
 	purgeOplog   bool
 	purgeGarbage bool
 	dryRun       bool
@@ -40,6 +44,20 @@ func PurgeOplog(purgeOplog bool) PurgeOption {
 	}
 }
 
+// PurgeRetainPolicy ...
+func PurgeRetainPolicy(retainPolicy internal.RetainPolicy) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.retainPolicy = retainPolicy
+	}
+}
+
+// PurgePlanOutput ...
+func PurgePlanOutput(output io.Writer) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.planOutput = output
+	}
+}
+
 // PurgeGarbage ...
 func PurgeGarbage(purgeGarbage bool) PurgeOption {
 	return func(args *PurgeSettings) {
@@ -60,6 +78,9 @@ func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters .
 	for _, setter := range setters {
 		setter(&opts)
 	}
+	if opts.planOutput != nil {
+		opts.plan = internal.NewDeletePlan(!opts.dryRun)
+	}
 
 	backupTimes, garbage, err := downloader.ListBackups()
 	if err != nil {
@@ -73,7 +94,7 @@ func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters .
 
 	if opts.purgeOplog {
 		// TODO: fix error if retainBackups is empty
-		if err := HandleOplogPurge(downloader, purger, opts.retainAfter, opts.dryRun); err != nil {
+		if err := handleOplogPurge(downloader, purger, opts.retainAfter, opts.dryRun, opts.plan); err != nil {
 			return err
 		}
 	}
@@ -87,6 +108,9 @@ func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters .
 		}
 	}
 
+	if opts.plan != nil {
+		return opts.plan.Write(opts.planOutput)
+	}
 	return nil
 }
 
@@ -108,13 +132,29 @@ func HandleBackupsPurge(backupTimes []internal.BackupTime,
 	timedBackups := archive.MongoModelToTimedBackup(backups)
 
 	internal.SortTimedBackup(timedBackups)
-	purgeBackups, retainBackups, err := internal.SplitPurgingBackups(timedBackups, opts.retainCount, opts.retainAfter)
+	purgeBackups, retainReasons, err := internal.SplitPurgingBackupsWithReasons(timedBackups,
+		opts.retainCount, opts.retainAfter)
 
 	if err != nil {
 		return nil, nil, err
 	}
+	if !opts.retainPolicy.IsEmpty() {
+		internal.RetainByPolicy(timedBackups, opts.retainPolicy, purgeBackups, retainReasons)
+	}
+	retainBackups := make(map[string]bool, len(retainReasons))
+	for name := range retainReasons {
+		retainBackups[name] = true
+	}
 
 	purge, retain = archive.SplitMongoBackups(backups, purgeBackups, retainBackups)
+	if opts.plan != nil {
+		for _, backup := range purge {
+			opts.plan.AddBackupToDelete(backup.BackupName, backup.StartLocalTime, backup.DataSize)
+		}
+		for _, backup := range retain {
+			opts.plan.AddRetainedBackup(backup.BackupName, backup.StartLocalTime, retainReasons[backup.BackupName])
+		}
+	}
 	tracelog.InfoLogger.Printf("Backups selected to be deleted: %v", archive.BackupNamesFromBackups(purge))
 	tracelog.InfoLogger.Printf("Backups selected to be retained: %v", archive.BackupNamesFromBackups(retain))
 
@@ -2,9 +2,11 @@ package mongo
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
 )
@@ -22,6 +24,14 @@ func LoadBackups(downloader archive.Downloader) ([]models.Backup, error) {
 
 // HandleOplogPurge delete oplog archives according to settings
 func HandleOplogPurge(downloader archive.Downloader, purger archive.Purger, retainAfter *time.Time, dryRun bool) error {
+	return handleOplogPurge(downloader, purger, retainAfter, dryRun, nil)
+}
+
+func handleOplogPurge(downloader archive.Downloader,
+	purger archive.Purger,
+	retainAfter *time.Time,
+	dryRun bool,
+	plan *internal.DeletePlan) error {
 	archives, err := downloader.ListOplogArchives()
 	if err != nil {
 		return fmt.Errorf("can not load oplog archives: %+v", err)
@@ -50,6 +60,12 @@ func HandleOplogPurge(downloader archive.Downloader, purger archive.Purger, reta
 
 	purgeArchives := archive.SelectPurgingOplogArchives(archives, backups, &retainArchivesAfterTS)
 	tracelog.DebugLogger.Printf("Oplog archives selected to be deleted: %v", purgeArchives)
+	if plan != nil {
+		for _, arch := range purgeArchives {
+			// archive sizes are not stored in the metadata
+			plan.AddLogToDelete(strings.TrimSuffix(models.OplogArchBasePath, "/"), arch.Filename(), 0)
+		}
+	}
 	if !dryRun {
 		if err := purger.DeleteOplogArchives(purgeArchives); err != nil {
 			return fmt.Errorf("can not purge oplog archives: %+v", err)
@@ -3,14 +3,17 @@ package mysql
 import (
 	"os"
 	"os/exec"
+	"time"
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 )
 
 func HandleBackupPush(uploader *internal.Uploader, backupCmd *exec.Cmd, isPermanent bool, userData string) {
+	defer metrics.BackupPushDuration.ObserveDuration(time.Now())
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
 
 	db, err := getMySQLConnection()
@@ -28,6 +28,7 @@ var UtilityFilePaths = map[string]bool{
 
 var patternPgBackupName = fmt.Sprintf("base_%[1]s(_D_%[1]s)?", PatternTimelineAndLogSegNo)
 var regexpPgBackupName = regexp.MustCompile(patternPgBackupName)
+var pgControlTarRegexp = regexp.MustCompile(`^.*?pg_control\.tar(\..+$|$)`)
 
 // Backup contains information about a valid Postgres backup
 // generated and uploaded by WAL-G.
@@ -103,8 +104,10 @@ func (backup *Backup) FetchMeta() (ExtendedMetadataDto, error) {
 	return extendedMetadataDto, nil
 }
 
-func checkDBDirectoryForUnwrap(dbDataDirectory string, sentinelDto BackupSentinelDto) error {
-	if !sentinelDto.IsIncremental() {
+func checkDBDirectoryForUnwrap(dbDataDirectory string, sentinelDto BackupSentinelDto, isResumed bool) error {
+	if isResumed {
+		tracelog.InfoLogger.Printf("Resuming the fetch into %s\n", dbDataDirectory)
+	} else if !sentinelDto.IsIncremental() {
 		isEmpty, err := isDirectoryEmpty(dbDataDirectory)
 		if err != nil {
 			return err
@@ -153,7 +156,12 @@ func setTablespacePaths(spec TablespaceSpec) error {
 		if err != nil {
 			return fmt.Errorf("error creating folder for tablespace %v", err)
 		}
-		err = os.Symlink(location.Location, filepath.Join(basePrefix, location.Symlink))
+		symlinkPath := filepath.Join(basePrefix, location.Symlink)
+		if target, err := os.Readlink(symlinkPath); err == nil && target == location.Location {
+			// created by the interrupted fetch
+			continue
+		}
+		err = os.Symlink(location.Location, symlinkPath)
 		if err != nil {
 			return fmt.Errorf("error creating tablespace symkink %v", err)
 		}
@@ -162,24 +170,22 @@ func setTablespacePaths(spec TablespaceSpec) error {
 }
 
 // check that directory is empty before unwrap
-func (backup *Backup) unwrapToEmptyDirectory(
-	dbDataDirectory string, sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool, createIncrementalFiles bool,
-) error {
-	err := checkDBDirectoryForUnwrap(dbDataDirectory, sentinelDto)
+func (backup *Backup) unwrapToEmptyDirectory(dbDataDirectory string, sentinelDto BackupSentinelDto,
+	filesToUnwrap map[string]bool, createIncrementalFiles bool, journal *internal.ExtractJournal) error {
+	err := checkDBDirectoryForUnwrap(dbDataDirectory, sentinelDto, journal.IsResumed())
 	if err != nil {
 		return err
 	}
 
-	return backup.unwrapOld(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles)
+	return backup.unwrapOld(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles, journal)
 }
 
 // TODO : unit tests
-// Do the job of unpacking Backup object
-func (backup *Backup) unwrapOld(
-	dbDataDirectory string, sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool, createIncrementalFiles bool,
-) error {
+// Do the job of unpacking Backup object, the tars recorded in the journal are skipped
+func (backup *Backup) unwrapOld(dbDataDirectory string, sentinelDto BackupSentinelDto,
+	filesToUnwrap map[string]bool, createIncrementalFiles bool, journal *internal.ExtractJournal) error {
 	tarInterpreter := NewFileTarInterpreter(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles)
-	tarsToExtract, pgControlKey, err := backup.getTarsToExtract(sentinelDto, filesToUnwrap, false)
+	tarsToExtract, pgControlTar, err := backup.getTarsToExtract(sentinelDto, filesToUnwrap, false)
 	if err != nil {
 		return err
 	}
@@ -187,18 +193,17 @@ func (backup *Backup) unwrapOld(
 	// Check name for backwards compatibility. Will check for `pg_control` if WALG version of backup.
 	needPgControl := IsPgControlRequired(*backup, sentinelDto)
 
-	if pgControlKey == "" && needPgControl {
+	if pgControlTar == nil && needPgControl {
 		return newPgControlNotFoundError()
 	}
 
-	err = internal.ExtractAll(tarInterpreter, tarsToExtract)
+	err = internal.ExtractAllWithJournal(tarInterpreter, tarsToExtract, journal)
 	if err != nil {
 		return err
 	}
 
 	if needPgControl {
-		err = internal.ExtractAll(tarInterpreter, []internal.ReaderMaker{
-			internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey)})
+		err = internal.ExtractAllWithJournal(tarInterpreter, []internal.ReaderMaker{pgControlTar}, journal)
 		if err != nil {
 			return errors.Wrap(err, "failed to extract pg_control")
 		}
@@ -219,7 +224,7 @@ func isDirectoryEmpty(directoryPath string) (bool, error) {
 	var isEmpty = true
 
 	searchLambda := func(path string, info os.FileInfo, err error) error {
-		if path != directoryPath {
+		if path != directoryPath && filepath.Base(path) != internal.ExtractJournalFileName {
 			isEmpty = false
 			tracelog.InfoLogger.Printf("found file '%s' in directory: '%s'\n", path, directoryPath)
 		}
@@ -231,26 +236,29 @@ func isDirectoryEmpty(directoryPath string) (bool, error) {
 
 // TODO : init tests
 func (backup *Backup) getTarsToExtract(sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool,
-	skipRedundantTars bool) (tarsToExtract []internal.ReaderMaker, pgControlKey string, err error) {
+	skipRedundantTars bool) (tarsToExtract []internal.ReaderMaker, pgControlTar internal.ReaderMaker, err error) {
 	tarNames, err := backup.GetTarNames()
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+	manifest, err := internal.FetchChecksumManifest(backup.Folder, backup.Name)
+	if err != nil {
+		return nil, nil, err
 	}
 	tracelog.DebugLogger.Printf("Tars to extract: '%+v'\n", tarNames)
 	tarsToExtract = make([]internal.ReaderMaker, 0, len(tarNames))
 
-	pgControlRe := regexp.MustCompile(`^.*?pg_control\.tar(\..+$|$)`)
 	for _, tarName := range tarNames {
 		// Separate the pg_control tarName from the others to
 		// extract it at the end, as to prevent server startup
 		// with incomplete backup restoration.  But only if it
 		// exists: it won't in the case of WAL-E backup
 		// backwards compatibility.
-		if pgControlRe.MatchString(tarName) {
-			if pgControlKey != "" {
+		if pgControlTarRegexp.MatchString(tarName) {
+			if pgControlTar != nil {
 				panic("expect only one pg_control tar name match")
 			}
-			pgControlKey = tarName
+			pgControlTar = backup.newTarReaderMaker(tarName, manifest)
 			continue
 		}
 
@@ -258,10 +266,15 @@ func (backup *Backup) getTarsToExtract(sentinelDto BackupSentinelDto, filesToUnw
 			continue
 		}
 
-		tarToExtract := internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), tarName)
-		tarsToExtract = append(tarsToExtract, tarToExtract)
+		tarsToExtract = append(tarsToExtract, backup.newTarReaderMaker(tarName, manifest))
 	}
-	return tarsToExtract, pgControlKey, nil
+	return tarsToExtract, pgControlTar, nil
+}
+
+// newTarReaderMaker creates the reader of the tar partition verified against the backup checksum manifest
+func (backup *Backup) newTarReaderMaker(tarName string, manifest internal.ChecksumManifest) internal.ReaderMaker {
+	tarPath := backup.Name + internal.TarPartitionFolderName + tarName
+	return internal.NewVerifyingStorageReaderMaker(backup.getTarPartitionFolder(), tarName, manifest.Checksum(tarPath))
 }
 
 func (backup *Backup) GetFilesToUnwrap(fileMask string) (map[string]bool, error) {
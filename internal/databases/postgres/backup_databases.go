@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/walparser"
+)
+
+// firstNormalObjectID is the first OID assigned after initdb, the databases with
+// the lower OIDs (template0, template1 and postgres) are always restored
+const firstNormalObjectID = 16384
+
+// relationFilenameRegexp matches the segments of all the relation forks
+var relationFilenameRegexp = regexp.MustCompile(`^\d+(_fsm|_vm|_init)?([.]\d+)?$`)
+
+// DatabaseDescription is the database of the cluster recorded at backup time
+type DatabaseDescription struct {
+	Oid           walparser.Oid `json:"Oid"`
+	TablespaceOid walparser.Oid `json:"TablespaceOid"`
+}
+
+// DatabaseDescriptions maps the database names to their OIDs
+type DatabaseDescriptions map[string]DatabaseDescription
+
+func newDatabaseDescriptions(conn *pgx.Conn) (DatabaseDescriptions, error) {
+	databases, err := getDatabaseInfos(conn)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make(DatabaseDescriptions, len(databases))
+	for _, database := range databases {
+		descriptions[database.name] = DatabaseDescription{Oid: database.oid, TablespaceOid: database.tblSpcOid}
+	}
+	return descriptions, nil
+}
+
+// getFilesToUnwrap selects the files of the backup by the mask and, if the database name is set, by the database
+func getFilesToUnwrap(backup Backup, fileMask, databaseName string) (map[string]bool, error) {
+	filesToUnwrap, err := backup.GetFilesToUnwrap(fileMask)
+	if err != nil || databaseName == "" {
+		return filesToUnwrap, err
+	}
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return nil, err
+	}
+	return selectDatabaseFiles(sentinelDto, filesToUnwrap, databaseName)
+}
+
+// selectDatabaseFiles leaves only the files of the database, the system databases and the cluster-wide files.
+// The relation files of the other databases are replaced by the stubs once the backup is fetched.
+func selectDatabaseFiles(sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool,
+	databaseName string) (map[string]bool, error) {
+	database, err := findDatabase(sentinelDto, databaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedFiles := make(map[string]bool)
+	for file := range filesToUnwrap {
+		if isRestoredDatabaseFile(file, database) {
+			selectedFiles[file] = true
+		}
+	}
+
+	skippedDatabases := make([]string, 0)
+	for name, description := range sentinelDto.Databases {
+		if description.Oid != database.Oid && description.Oid >= firstNormalObjectID {
+			skippedDatabases = append(skippedDatabases, name)
+		}
+	}
+	if len(skippedDatabases) > 0 {
+		sort.Strings(skippedDatabases)
+		tracelog.WarningLogger.Printf("Databases %v are not restored, drop them once the cluster is started\n",
+			skippedDatabases)
+	}
+	return selectedFiles, nil
+}
+
+func findDatabase(sentinelDto BackupSentinelDto, databaseName string) (DatabaseDescription, error) {
+	if sentinelDto.Databases == nil {
+		return DatabaseDescription{},
+			errors.New("the backup has no database list, it is taken remotely or by an older WAL-G")
+	}
+	database, ok := sentinelDto.Databases[databaseName]
+	if !ok {
+		return DatabaseDescription{}, errors.Errorf("database '%s' is not found in the backup", databaseName)
+	}
+	return database, nil
+}
+
+func isRestoredDatabaseFile(file string, database DatabaseDescription) bool {
+	oid, isDatabaseFile := getDatabaseFileOid(file)
+	return !isDatabaseFile || oid == database.Oid || oid < firstNormalObjectID
+}
+
+// createSkippedDatabaseStubs creates the relation files of the databases not restored by backup-fetch --database.
+// WAL replay still changes those relations and fails the recovery on a page missing in the file,
+// so the stubs are sparse files of the sizes recorded at backup time. The other files of these databases,
+// PG_VERSION included, are not created, so the databases can't be connected to until they are dropped.
+func createSkippedDatabaseStubs(backup Backup, dbDataDirectory, databaseName string) error {
+	if databaseName == "" {
+		return nil
+	}
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return err
+	}
+	database, err := findDatabase(sentinelDto, databaseName)
+	if err != nil {
+		return err
+	}
+	stubsCount, err := createDatabaseStubs(dbDataDirectory, sentinelDto.Files, database)
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Created %d stubs of the relation files of the databases not restored\n", stubsCount)
+	return nil
+}
+
+// createDatabaseStubs creates the stubs of the relation files of all the databases except the restored one
+func createDatabaseStubs(dbDataDirectory string, files internal.BackupFileList,
+	database DatabaseDescription) (int, error) {
+	stubsCount := 0
+	for file, description := range files {
+		if isRestoredDatabaseFile(file, database) || !relationFilenameRegexp.MatchString(path.Base(file)) {
+			continue
+		}
+		err := createSparseFile(path.Join(dbDataDirectory, file), description.Size)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to create the stub of '%s'", file)
+		}
+		stubsCount++
+	}
+	return stubsCount, nil
+}
+
+// createSparseFile creates the file of the size without writing the data, the existing file is left as is
+func createSparseFile(filePath string, size int64) error {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = file.Truncate(size)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// getDatabaseFileOid returns the database OID of the file inside the database directory,
+// which is either base/<database>/ or pg_tblspc/<tablespace>/<version>/<database>/
+func getDatabaseFileOid(file string) (walparser.Oid, bool) {
+	parts := strings.Split(strings.TrimPrefix(file, "/"), "/")
+	var oidPart int
+	switch {
+	case parts[0] == DefaultTablespace:
+		oidPart = 1
+	case parts[0] == NonDefaultTablespace:
+		oidPart = 3
+	default:
+		return 0, false
+	}
+	if len(parts) <= oidPart+1 {
+		// the database directory itself
+		return 0, false
+	}
+	oid, err := strconv.ParseUint(parts[oidPart], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return walparser.Oid(oid), true
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestSelectDatabaseFiles(t *testing.T) {
+	sentinelDto := BackupSentinelDto{Databases: DatabaseDescriptions{
+		"template1": {Oid: 1, TablespaceOid: 1663},
+		"postgres":  {Oid: 13414, TablespaceOid: 1663},
+		"tenant1":   {Oid: 16384, TablespaceOid: 1663},
+		"tenant2":   {Oid: 16390, TablespaceOid: 16385},
+	}}
+	filesToUnwrap := map[string]bool{
+		"/PG_VERSION":                            true,
+		"/backup_label":                          true,
+		"/global/1262":                           true,
+		"/base/1":                                true,
+		"/base/1/1259":                           true,
+		"/base/13414/1259":                       true,
+		"/base/16384":                            true,
+		"/base/16384/16400":                      true,
+		"/base/16390":                            true,
+		"/base/16390/16401":                      true,
+		"/base/pgsql_tmp/pgsql_tmp1.0":           true,
+		"/pg_tblspc/16385/PG_13_202007201/16390": true,
+		"/pg_tblspc/16385/PG_13_202007201/16390/1": true,
+		"/pg_tblspc/16385/PG_13_202007201/16384/2": true,
+	}
+
+	selectedFiles, err := selectDatabaseFiles(sentinelDto, filesToUnwrap, "tenant1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"/PG_VERSION":                            true,
+		"/backup_label":                          true,
+		"/global/1262":                           true,
+		"/base/1":                                true,
+		"/base/1/1259":                           true,
+		"/base/13414/1259":                       true,
+		"/base/16384":                            true,
+		"/base/16384/16400":                      true,
+		"/base/16390":                            true,
+		"/base/pgsql_tmp/pgsql_tmp1.0":           true,
+		"/pg_tblspc/16385/PG_13_202007201/16390": true,
+		"/pg_tblspc/16385/PG_13_202007201/16384/2": true,
+	}, selectedFiles)
+}
+
+func TestSelectDatabaseFiles_UnknownDatabase(t *testing.T) {
+	_, err := selectDatabaseFiles(BackupSentinelDto{Databases: DatabaseDescriptions{}}, map[string]bool{}, "tenant1")
+	assert.Error(t, err)
+
+	_, err = selectDatabaseFiles(BackupSentinelDto{}, map[string]bool{}, "tenant1")
+	assert.Error(t, err)
+}
+
+func TestCreateDatabaseStubs(t *testing.T) {
+	dataDirectory, err := ioutil.TempDir("", "database_stubs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDirectory)
+	// the file of the restored database is already extracted
+	restoredFile := filepath.Join(dataDirectory, "base", "16384", "16400")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(restoredFile), 0755))
+	assert.NoError(t, ioutil.WriteFile(restoredFile, []byte{1}, 0600))
+
+	files := internal.BackupFileList{
+		"/global/1262":                                 {Size: 8192},
+		"/base/1/1259":                                 {Size: 8192},
+		"/base/16384/16400":                            {Size: 16384},
+		"/base/16390/PG_VERSION":                       {Size: 3},
+		"/base/16390/pg_filenode.map":                  {Size: 512},
+		"/base/16390/16401":                            {Size: 1 << 30},
+		"/base/16390/16401.1":                          {Size: 8192},
+		"/base/16390/16401_vm":                         {Size: 8192},
+		"/base/16390/16402":                            {},
+		"/pg_tblspc/16385/PG_13_202007201/16390/16403": {Size: 24576},
+	}
+	stubsCount, err := createDatabaseStubs(dataDirectory, files, DatabaseDescription{Oid: 16384, TablespaceOid: 1663})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, stubsCount)
+
+	// the relation files of the other databases keep their sizes, so WAL replay finds all their pages
+	for _, file := range []string{"/base/16390/16401", "/base/16390/16401.1", "/base/16390/16401_vm",
+		"/base/16390/16402", "/pg_tblspc/16385/PG_13_202007201/16390/16403"} {
+		fileInfo, err := os.Stat(filepath.Join(dataDirectory, file))
+		assert.NoError(t, err)
+		if err == nil {
+			assert.Equal(t, files[file].Size, fileInfo.Size(), file)
+		}
+	}
+	// neither the other files of these databases nor the files restored from the backup are stubbed
+	for _, file := range []string{"/base/16390/PG_VERSION", "/base/16390/pg_filenode.map", "/global/1262",
+		"/base/1/1259"} {
+		_, err := os.Stat(filepath.Join(dataDirectory, file))
+		assert.True(t, os.IsNotExist(err), file)
+	}
+	restoredData, err := ioutil.ReadFile(restoredFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, restoredData)
+}
@@ -63,7 +63,7 @@ func chooseTablespaceSpecification(sentinelDtoSpec, spec *TablespaceSpec) *Table
 // TODO : unit tests
 // deltaFetchRecursion function composes Backup object and recursively searches for necessary base backup
 func deltaFetchRecursionOld(backupName string, folder storage.Folder, dbDataDirectory string,
-	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool) error {
+	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool, journal *internal.ExtractJournal) error {
 	backup := NewBackup(folder.GetSubFolder(utility.BaseBackupPath), backupName)
 	sentinelDto, err := backup.GetSentinel()
 	if err != nil {
@@ -78,7 +78,8 @@ func deltaFetchRecursionOld(backupName string, folder storage.Folder, dbDataDire
 		if err != nil {
 			return err
 		}
-		err = deltaFetchRecursionOld(*sentinelDto.IncrementFrom, folder, dbDataDirectory, tablespaceSpec, baseFilesToUnwrap)
+		err = deltaFetchRecursionOld(*sentinelDto.IncrementFrom, folder, dbDataDirectory, tablespaceSpec,
+			baseFilesToUnwrap, journal)
 		if err != nil {
 			return err
 		}
@@ -86,13 +87,16 @@ func deltaFetchRecursionOld(backupName string, folder storage.Folder, dbDataDire
 			*(sentinelDto.IncrementFrom), *(sentinelDto.IncrementFromLSN), *(sentinelDto.BackupStartLSN))
 	}
 
-	return backup.unwrapToEmptyDirectory(dbDataDirectory, sentinelDto, filesToUnwrap, false)
+	return backup.unwrapToEmptyDirectory(dbDataDirectory, sentinelDto, filesToUnwrap, false, journal)
 }
 
-func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string) func(rootFolder storage.Folder, backup internal.Backup) {
+// GetPgFetcherOld returns the fetcher that records the extracted tars in the journal,
+// so the interrupted fetch can be resumed
+func GetPgFetcherOld(dbDataDirectory, fileMask, databaseName, restoreSpecPath string, resume bool,
+) func(rootFolder storage.Folder, backup internal.Backup) {
 	return func(rootFolder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
-		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(fileMask)
+		filesToUnwrap, err := getFilesToUnwrap(pgBackup, fileMask, databaseName)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		var spec *TablespaceSpec
@@ -102,8 +106,15 @@ func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string) func(roo
 			errMessege := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
 			tracelog.ErrorLogger.FatalfOnError(errMessege, err)
 		}
-		err = deltaFetchRecursionOld(backup.Name, rootFolder, utility.ResolveSymlink(dbDataDirectory), spec, filesToUnwrap)
+		dataDirectory := utility.ResolveSymlink(dbDataDirectory)
+		journal, err := internal.OpenExtractJournal(dataDirectory, resume)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		err = deltaFetchRecursionOld(backup.Name, rootFolder, dataDirectory, spec, filesToUnwrap, journal)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		err = createSkippedDatabaseStubs(pgBackup, dataDirectory, databaseName)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		err = journal.Remove()
+		tracelog.ErrorLogger.FatalfOnError("Failed to remove the fetch journal: %v\n", err)
 	}
 }
 
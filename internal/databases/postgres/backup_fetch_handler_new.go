@@ -9,11 +9,11 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
-func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedundantTars bool,
+func GetPgFetcherNew(dbDataDirectory, fileMask, databaseName, restoreSpecPath string, skipRedundantTars bool,
 ) func(folder storage.Folder, backup internal.Backup) {
 	return func(folder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
-		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(fileMask)
+		filesToUnwrap, err := getFilesToUnwrap(pgBackup, fileMask, databaseName)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		var spec *TablespaceSpec
@@ -36,6 +36,8 @@ func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedu
 			utility.ResolveSymlink(dbDataDirectory), folder, spec, filesToUnwrap, skipRedundantTars)
 		err = deltaFetchRecursionNew(config)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		err = createSkippedDatabaseStubs(pgBackup, config.dbDataDirectory, databaseName)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 	}
 }
 
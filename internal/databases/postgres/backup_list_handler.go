@@ -44,14 +44,14 @@ func WriteBackupListDetails(backupDetails []BackupDetail, output io.Writer) erro
 	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
 	defer writer.Flush()
 	//nolint:lll
-	_, err := fmt.Fprintln(writer, "name\tmodified\twal_segment_backup_start\tstart_time\tfinish_time\thostname\tdata_dir\tpg_version\tstart_lsn\tfinish_lsn\tis_permanent")
+	_, err := fmt.Fprintln(writer, "name\tmodified\twal_segment_backup_start\tstart_time\tfinish_time\thostname\tdata_dir\tpg_version\tstart_lsn\tfinish_lsn\tis_permanent\tsource_role\tupstream")
 	if err != nil {
 		return err
 	}
 	for i := 0; i < len(backupDetails); i++ {
 		b := backupDetails[i]
 		//nolint:lll
-		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", b.BackupName, internal.FormatTime(b.Time), b.WalFileName, internal.FormatTime(b.StartTime), internal.FormatTime(b.FinishTime), b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent)
+		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", b.BackupName, internal.FormatTime(b.Time), b.WalFileName, internal.FormatTime(b.StartTime), internal.FormatTime(b.FinishTime), b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent, b.SourceRole, b.Upstream)
 		if err != nil {
 			return err
 		}
@@ -65,12 +65,12 @@ func WritePrettyBackupListDetails(backupDetails []BackupDetail, output io.Writer
 	writer.SetOutputMirror(output)
 	defer writer.Render()
 	//nolint:lll
-	writer.AppendHeader(table.Row{"#", "Name", "Modified", "WAL segment backup start", "Start time", "Finish time", "Hostname", "Datadir", "PG Version", "Start LSN", "Finish LSN", "Permanent"})
+	writer.AppendHeader(table.Row{"#", "Name", "Modified", "WAL segment backup start", "Start time", "Finish time", "Hostname", "Datadir", "PG Version", "Start LSN", "Finish LSN", "Permanent", "Source role", "Upstream"})
 	for idx := range backupDetails {
 		b := &backupDetails[idx]
 		writer.AppendRow(
 			table.Row{idx, b.BackupName, internal.PrettyFormatTime(b.Time), b.WalFileName,
 				internal.PrettyFormatTime(b.StartTime), internal.PrettyFormatTime(b.FinishTime),
-				b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent})
+				b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent, b.SourceRole, b.Upstream})
 	}
 }
@@ -72,12 +72,12 @@ var emptyColonsBackups = []postgres.BackupDetail{
 }
 
 func TestWritePrettyBackupList_LongColumnsValues(t *testing.T) {
-	expectedRes := "+---+-----------+----------+-----------------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| # | NAME      | MODIFIED | WAL SEGMENT BACKUP START          | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT |\n" +
-		"+---+-----------+----------+-----------------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| 0 | backup000 | -        | veryVeryVeryVeryVeryLongWallName0 | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"| 1 | backup001 | -        | veryVeryVeryVeryVeryLongWallName1 | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"+---+-----------+----------+-----------------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n"
+	expectedRes := "+---+-----------+----------+-----------------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| # | NAME      | MODIFIED | WAL SEGMENT BACKUP START          | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT | SOURCE ROLE | UPSTREAM |\n" +
+		"+---+-----------+----------+-----------------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| 0 | backup000 | -        | veryVeryVeryVeryVeryLongWallName0 | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"| 1 | backup001 | -        | veryVeryVeryVeryVeryLongWallName1 | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"+---+-----------+----------+-----------------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n"
 	b := bytes.Buffer{}
 	postgres.WritePrettyBackupListDetails(longBackups, &b)
 
@@ -85,12 +85,12 @@ func TestWritePrettyBackupList_LongColumnsValues(t *testing.T) {
 }
 
 func TestWritePrettyBackupList_ShortColumnsValues(t *testing.T) {
-	expectedRes := "+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| # | NAME | MODIFIED | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT |\n" +
-		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| 0 | b0   | -        | shortWallName0           | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"| 1 | b1   | -        | shortWallName1           | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n"
+	expectedRes := "+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| # | NAME | MODIFIED | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT | SOURCE ROLE | UPSTREAM |\n" +
+		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| 0 | b0   | -        | shortWallName0           | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"| 1 | b1   | -        | shortWallName1           | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n"
 	b := bytes.Buffer{}
 	postgres.WritePrettyBackupListDetails(shortBackups, &b)
 
@@ -98,10 +98,10 @@ func TestWritePrettyBackupList_ShortColumnsValues(t *testing.T) {
 }
 
 func TestWritePrettyBackupList_WriteNoBackupList(t *testing.T) {
-	expectedRes := "+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| # | NAME | MODIFIED | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT |\n" +
-		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n"
+	expectedRes := "+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| # | NAME | MODIFIED | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT | SOURCE ROLE | UPSTREAM |\n" +
+		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n"
 	backups := make([]postgres.BackupDetail, 0)
 
 	b := bytes.Buffer{}
@@ -111,13 +111,13 @@ func TestWritePrettyBackupList_WriteNoBackupList(t *testing.T) {
 }
 
 func TestWritePrettyBackupList_EmptyColumnsValues(t *testing.T) {
-	expectedRes := "+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| # | NAME | MODIFIED | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT |\n" +
-		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| 0 |      | -        | shortWallName0           | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"| 1 | b1   | -        |                          | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"| 2 |      | -        |                          | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n"
+	expectedRes := "+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| # | NAME | MODIFIED | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT | SOURCE ROLE | UPSTREAM |\n" +
+		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| 0 |      | -        | shortWallName0           | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"| 1 | b1   | -        |                          | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"| 2 |      | -        |                          | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"+---+------+----------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n"
 	b := bytes.Buffer{}
 	postgres.WritePrettyBackupListDetails(emptyColonsBackups, &b)
 
@@ -125,7 +125,7 @@ func TestWritePrettyBackupList_EmptyColumnsValues(t *testing.T) {
 }
 
 func TestWriteBackupList_NoBackups(t *testing.T) {
-	expectedRes := "name modified wal_segment_backup_start start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n"
+	expectedRes := "name modified wal_segment_backup_start start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n"
 	backups := make([]postgres.BackupDetail, 0)
 
 	b := bytes.Buffer{}
@@ -135,10 +135,10 @@ func TestWriteBackupList_NoBackups(t *testing.T) {
 }
 
 func TestWriteBackupList_EmptyColumnsValues(t *testing.T) {
-	expectedRes := "name modified wal_segment_backup_start start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"     -        shortWallName0           -          -                             0          0         0          false\n" +
-		"b1   -                                 -          -                             0          0         0          false\n" +
-		"     -                                 -          -                             0          0         0          false\n"
+	expectedRes := "name modified wal_segment_backup_start start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"     -        shortWallName0           -          -                             0          0         0          false                    \n" +
+		"b1   -                                 -          -                             0          0         0          false                    \n" +
+		"     -                                 -          -                             0          0         0          false                    \n"
 	b := bytes.Buffer{}
 	postgres.WriteBackupListDetails(emptyColonsBackups, &b)
 
@@ -146,9 +146,9 @@ func TestWriteBackupList_EmptyColumnsValues(t *testing.T) {
 }
 
 func TestWriteBackupList_ShortColumnsValues(t *testing.T) {
-	expectedRes := "name modified wal_segment_backup_start start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"b0   -        shortWallName0           -          -                             0          0         0          false\n" +
-		"b1   -        shortWallName1           -          -                             0          0         0          false\n"
+	expectedRes := "name modified wal_segment_backup_start start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"b0   -        shortWallName0           -          -                             0          0         0          false                    \n" +
+		"b1   -        shortWallName1           -          -                             0          0         0          false                    \n"
 
 	b := bytes.Buffer{}
 	postgres.WriteBackupListDetails(shortBackups, &b)
@@ -157,9 +157,9 @@ func TestWriteBackupList_ShortColumnsValues(t *testing.T) {
 }
 
 func TestWriteBackupList_LongColumnsValues(t *testing.T) {
-	expectedRes := "name      modified wal_segment_backup_start          start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"backup000 -        veryVeryVeryVeryVeryLongWallName0 -          -                             0          0         0          false\n" +
-		"backup001 -        veryVeryVeryVeryVeryLongWallName1 -          -                             0          0         0          false\n"
+	expectedRes := "name      modified wal_segment_backup_start          start_time finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"backup000 -        veryVeryVeryVeryVeryLongWallName0 -          -                             0          0         0          false                    \n" +
+		"backup001 -        veryVeryVeryVeryVeryLongWallName1 -          -                             0          0         0          false                    \n"
 
 	b := bytes.Buffer{}
 	postgres.WriteBackupListDetails(longBackups, &b)
@@ -34,13 +34,13 @@ func TestBackupListCorrectOutput(t *testing.T) {
 }
 
 func TestBackupListCorrectPrettyOutput(t *testing.T) {
-	const expected = "+---+--------+-----------------------------------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| # | NAME   | MODIFIED                          | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT |\n" +
-		"+---+--------+-----------------------------------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n" +
-		"| 0 | base_1 | Sunday, 01-Jan-17 01:01:01 UTC    | ZZZZZZZZZZZZZZZZZZZZZZZZ | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"| 1 | base_0 | Monday, 01-Jan-18 01:01:01 UTC    | ZZZZZZZZZZZZZZZZZZZZZZZZ | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"| 2 | base_2 | Wednesday, 01-Jan-20 01:01:01 UTC | ZZZZZZZZZZZZZZZZZZZZZZZZ | -          | -           |          |         |          0 |         0 |          0 | false     |\n" +
-		"+---+--------+-----------------------------------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+\n"
+	const expected = "+---+--------+-----------------------------------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| # | NAME   | MODIFIED                          | WAL SEGMENT BACKUP START | START TIME | FINISH TIME | HOSTNAME | DATADIR | PG VERSION | START LSN | FINISH LSN | PERMANENT | SOURCE ROLE | UPSTREAM |\n" +
+		"+---+--------+-----------------------------------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n" +
+		"| 0 | base_1 | Sunday, 01-Jan-17 01:01:01 UTC    | ZZZZZZZZZZZZZZZZZZZZZZZZ | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"| 1 | base_0 | Monday, 01-Jan-18 01:01:01 UTC    | ZZZZZZZZZZZZZZZZZZZZZZZZ | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"| 2 | base_2 | Wednesday, 01-Jan-20 01:01:01 UTC | ZZZZZZZZZZZZZZZZZZZZZZZZ | -          | -           |          |         |          0 |         0 |          0 | false     |             |          |\n" +
+		"+---+--------+-----------------------------------+--------------------------+------------+-------------+----------+---------+------------+-----------+------------+-----------+-------------+----------+\n"
 
 	folder := testtools.CreatePostgresMockStorageFolderWithTimeMetadata(t, testtools.NoCreationTime)
 	backups, err := internal.GetBackups(folder)
@@ -54,10 +54,10 @@ func TestBackupListCorrectPrettyOutput(t *testing.T) {
 }
 
 func TestBackupListCorrectOrderingCreationTimeGaps(t *testing.T) {
-	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"base_1 2017-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ -                    -                             0          0         0          false\n" +
-		"base_0 2018-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ -                    -                             0          0         0          false\n" +
-		"base_2 2020-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false\n"
+	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"base_1 2017-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ -                    -                             0          0         0          false                    \n" +
+		"base_0 2018-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ -                    -                             0          0         0          false                    \n" +
+		"base_2 2020-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false                    \n"
 
 	folder := testtools.CreatePostgresMockStorageFolderWithTimeMetadata(t, testtools.CreationTimeGaps)
 	backups, err := internal.GetBackups(folder)
@@ -72,10 +72,10 @@ func TestBackupListCorrectOrderingCreationTimeGaps(t *testing.T) {
 }
 
 func TestBackupListCorrectOrderingModificationTimeGaps(t *testing.T) {
-	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"base_0 -                    ZZZZZZZZZZZZZZZZZZZZZZZZ 1997-01-01T01:01:01Z -                             0          0         0          false\n" +
-		"base_2 2020-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false\n" +
-		"base_1 -                    ZZZZZZZZZZZZZZZZZZZZZZZZ 1999-01-01T01:01:01Z -                             0          0         0          false\n"
+	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"base_0 -                    ZZZZZZZZZZZZZZZZZZZZZZZZ 1997-01-01T01:01:01Z -                             0          0         0          false                    \n" +
+		"base_2 2020-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false                    \n" +
+		"base_1 -                    ZZZZZZZZZZZZZZZZZZZZZZZZ 1999-01-01T01:01:01Z -                             0          0         0          false                    \n"
 
 	folder := testtools.CreatePostgresMockStorageFolderWithTimeMetadata(t, testtools.ModificationTimeGaps)
 	backups, err := internal.GetBackups(folder)
@@ -90,10 +90,10 @@ func TestBackupListCorrectOrderingModificationTimeGaps(t *testing.T) {
 }
 
 func TestBackupListCorrectOrderingNoTimeGaps(t *testing.T) {
-	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"base_0 2018-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1997-01-01T01:01:01Z -                             0          0         0          false\n" +
-		"base_2 2020-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false\n" +
-		"base_1 2017-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1999-01-01T01:01:01Z -                             0          0         0          false\n"
+	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"base_0 2018-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1997-01-01T01:01:01Z -                             0          0         0          false                    \n" +
+		"base_2 2020-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false                    \n" +
+		"base_1 2017-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1999-01-01T01:01:01Z -                             0          0         0          false                    \n"
 
 	folder := testtools.CreatePostgresMockStorageFolderWithTimeMetadata(t, testtools.NoTimeGaps)
 	backups, err := internal.GetBackups(folder)
@@ -108,10 +108,10 @@ func TestBackupListCorrectOrderingNoTimeGaps(t *testing.T) {
 }
 
 func TestBackupListCorrectOrderingTimeGaps(t *testing.T) {
-	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent\n" +
-		"base_2 -                    ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false\n" +
-		"base_1 2017-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ -                    -                             0          0         0          false\n" +
-		"base_0 2018-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1997-01-01T01:01:01Z -                             0          0         0          false\n"
+	const expected = "name   modified             wal_segment_backup_start start_time           finish_time hostname data_dir pg_version start_lsn finish_lsn is_permanent source_role upstream\n" +
+		"base_2 -                    ZZZZZZZZZZZZZZZZZZZZZZZZ 1998-01-01T01:01:01Z -                             0          0         0          false                    \n" +
+		"base_1 2017-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ -                    -                             0          0         0          false                    \n" +
+		"base_0 2018-01-01T01:01:01Z ZZZZZZZZZZZZZZZZZZZZZZZZ 1997-01-01T01:01:01Z -                             0          0         0          false                    \n"
 
 	folder := testtools.CreatePostgresMockStorageFolderWithTimeMetadata(t, testtools.CreationAndModificationTimeGaps)
 	backups, err := internal.GetBackups(folder)
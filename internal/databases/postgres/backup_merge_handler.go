@@ -0,0 +1,515 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// mergedFile is the state of the file of the merged backup. The increments are read
+// from the newest backup to the oldest one, so the page found first is the actual one.
+type mergedFile struct {
+	header *tar.Header
+	// size is the size of the file in the newest backup, -1 until it is known
+	size int64
+	// pages holds the spool offsets of the pages read from the increments
+	pages  map[uint32]int64
+	isDone bool
+}
+
+// backupMerger builds the full backup from the delta backup and its base backups
+type backupMerger struct {
+	tarBallQueue *internal.TarBallQueue
+	crypter      crypto.Crypter
+
+	// spool keeps the pages of the increments until the full file is read
+	spool     *os.File
+	spoolSize int64
+
+	mutex       sync.Mutex
+	files       map[string]*mergedFile
+	tarFileSets TarFileSets
+
+	pgControlHeader *tar.Header
+	pgControl       []byte
+}
+
+// HandleBackupMerge merges the delta backup with its base backups into the full backup
+// named after the delta backup LSN. Neither Postgres nor PGDATA is needed.
+func HandleBackupMerge(uploader *WalUploader, backupName string) {
+	mergedBackupName, err := mergeBackup(uploader, backupName)
+	tracelog.ErrorLogger.FatalfOnError("Failed to merge the backup: %v\n", err)
+	tracelog.InfoLogger.Printf("Wrote backup with name %s", mergedBackupName)
+}
+
+func mergeBackup(uploader *WalUploader, backupName string) (string, error) {
+	baseBackupFolder := uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
+	backup, err := GetBackupByName(backupName, utility.BaseBackupPath, uploader.UploadingFolder)
+	if err != nil {
+		return "", err
+	}
+	chain, sentinels, err := getDeltaChain(backup)
+	if err != nil {
+		return "", err
+	}
+
+	mergedBackupName := getMergedBackupName(backup.Name)
+	mergedBackup := NewBackup(baseBackupFolder, mergedBackupName)
+	exists, err := mergedBackup.CheckExistence()
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errors.Errorf("backup %s already exists", mergedBackupName)
+	}
+	tracelog.InfoLogger.Printf("Merging %d backups into %s\n", len(chain), mergedBackupName)
+
+	backupUploader := uploader.Uploader.Clone()
+	backupUploader.UploadingFolder = baseBackupFolder
+	merger, err := newBackupMerger(sentinels[0], mergedBackupName, backupUploader)
+	if err != nil {
+		return "", err
+	}
+	defer merger.removeSpool()
+
+	err = merger.mergeTars(chain, sentinels)
+	if err != nil {
+		return "", err
+	}
+	err = merger.uploadPgControl(backupUploader.Compressor.FileExtension())
+	if err != nil {
+		return "", err
+	}
+	backupUploader.Finish()
+	if backupUploader.Failed.Load().(bool) {
+		return "", errors.New("failed to upload the tars of the merged backup")
+	}
+
+	sentinelDto, err := merger.newSentinelDto(sentinels[0], backupUploader)
+	if err != nil {
+		return "", err
+	}
+	err = uploadMergedMetadata(backupUploader, backup, mergedBackupName, sentinelDto)
+	if err != nil {
+		return "", err
+	}
+	return mergedBackupName, internal.UploadSentinel(backupUploader, sentinelDto, mergedBackupName)
+}
+
+// getDeltaChain returns the delta backup and its base backups, from the newest to the oldest
+func getDeltaChain(backup Backup) ([]Backup, []BackupSentinelDto, error) {
+	chain := make([]Backup, 0)
+	sentinels := make([]BackupSentinelDto, 0)
+	for {
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !hasConsistentIncrement(sentinelDto) {
+			return nil, nil, errors.Errorf("backup %s has an inconsistent delta sentinel", backup.Name)
+		}
+		if len(chain) == 0 && !sentinelDto.IsIncremental() {
+			return nil, nil, errors.Errorf("backup %s is not a delta backup", backup.Name)
+		}
+		if sentinelDto.Files == nil {
+			return nil, nil, errors.Errorf("backup %s has no file list in the sentinel", backup.Name)
+		}
+		chain = append(chain, backup)
+		sentinels = append(sentinels, sentinelDto)
+		if !sentinelDto.IsIncremental() {
+			return chain, sentinels, nil
+		}
+		backup = NewBackup(backup.Folder, *sentinelDto.IncrementFrom)
+	}
+}
+
+// hasConsistentIncrement reports whether IsIncremental can be called without panicking
+func hasConsistentIncrement(sentinelDto BackupSentinelDto) bool {
+	return sentinelDto.IncrementFrom == nil ||
+		sentinelDto.IncrementFromLSN != nil && sentinelDto.IncrementFullName != nil && sentinelDto.IncrementCount != nil
+}
+
+// getMergedBackupName returns the name of the full backup taken at the LSN of the delta backup
+func getMergedBackupName(deltaBackupName string) string {
+	return strings.SplitN(deltaBackupName, "_D_", 2)[0]
+}
+
+func newBackupMerger(sentinelDto BackupSentinelDto, backupName string,
+	uploader *internal.Uploader) (*backupMerger, error) {
+	spool, err := ioutil.TempFile("", "wal-g-merge-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the page spool")
+	}
+	files := make(map[string]*mergedFile, len(sentinelDto.Files))
+	for name := range sentinelDto.Files {
+		files[name] = &mergedFile{size: -1, pages: make(map[uint32]int64)}
+	}
+	tarBallQueue := internal.NewTarBallQueue(viper.GetInt64(internal.TarSizeThresholdSetting),
+		internal.NewStorageTarBallMaker(backupName, uploader))
+	return &backupMerger{
+		tarBallQueue: tarBallQueue,
+		crypter:      internal.ConfigureCrypter(),
+		spool:        spool,
+		files:        files,
+		tarFileSets:  make(TarFileSets),
+	}, nil
+}
+
+func (merger *backupMerger) removeSpool() {
+	utility.LoggedClose(merger.spool, "failed to close the page spool")
+	if err := os.Remove(merger.spool.Name()); err != nil {
+		tracelog.WarningLogger.Printf("Failed to remove the page spool: %v\n", err)
+	}
+}
+
+// mergeTars reads the backups from the newest to the oldest. The files of the older backups
+// are read only until their full copy is found.
+func (merger *backupMerger) mergeTars(chain []Backup, sentinels []BackupSentinelDto) error {
+	err := merger.tarBallQueue.StartQueue()
+	if err != nil {
+		return err
+	}
+	for i, backup := range chain {
+		err = merger.mergeBackupTars(backup, sentinels[i], i == 0)
+		if err != nil {
+			return err
+		}
+	}
+	for name, file := range merger.files {
+		if !file.isDone {
+			return errors.Errorf("no full copy of '%s' is found in the base backups", name)
+		}
+	}
+	return merger.tarBallQueue.FinishQueue()
+}
+
+func (merger *backupMerger) mergeBackupTars(backup Backup, sentinelDto BackupSentinelDto, isNewest bool) error {
+	tracelog.InfoLogger.Printf("Reading backup %s\n", backup.Name)
+	tarNames, err := backup.GetTarNames()
+	if err != nil {
+		return err
+	}
+	manifest, err := internal.FetchChecksumManifest(backup.Folder, backup.Name)
+	if err != nil {
+		return err
+	}
+	concurrency, err := internal.GetMaxDownloadConcurrency()
+	if err != nil {
+		return err
+	}
+
+	pendingFiles := make(map[string]bool)
+	for name, file := range merger.files {
+		if !file.isDone {
+			pendingFiles[name] = true
+		}
+	}
+	downloadingSemaphore := semaphore.NewWeighted(int64(concurrency))
+	errorGroup, ctx := errgroup.WithContext(context.Background())
+	for _, tarName := range tarNames {
+		isPgControlTar := pgControlTarRegexp.MatchString(tarName)
+		if !isNewest && (isPgControlTar || !shouldUnwrapTar(tarName, sentinelDto, pendingFiles)) {
+			continue
+		}
+		if err = downloadingSemaphore.Acquire(ctx, 1); err != nil {
+			break
+		}
+		tarName := tarName
+		errorGroup.Go(func() error {
+			defer downloadingSemaphore.Release(1)
+			readerMaker := backup.newTarReaderMaker(tarName, manifest)
+			return errors.Wrapf(merger.mergeTar(readerMaker, sentinelDto, isNewest, isPgControlTar),
+				"failed to merge %s of %s", tarName, backup.Name)
+		})
+	}
+	return errorGroup.Wait()
+}
+
+func (merger *backupMerger) mergeTar(readerMaker internal.ReaderMaker, sentinelDto BackupSentinelDto,
+	isNewest, isPgControlTar bool) error {
+	extractingReader, pipeWriter := io.Pipe()
+	decompressionErrors := make(chan error, 1)
+	go func() {
+		err := internal.DecryptAndDecompressTar(&internal.EmptyWriteIgnorer{WriteCloser: pipeWriter},
+			readerMaker, merger.crypter)
+		_ = pipeWriter.CloseWithError(err)
+		decompressionErrors <- err
+	}()
+
+	err := merger.mergeTarFiles(tar.NewReader(extractingReader), sentinelDto, isNewest, isPgControlTar)
+	if err != nil {
+		_ = extractingReader.CloseWithError(err)
+		<-decompressionErrors
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, extractingReader)
+	return <-decompressionErrors
+}
+
+func (merger *backupMerger) mergeTarFiles(tarReader *tar.Reader, sentinelDto BackupSentinelDto,
+	isNewest, isPgControlTar bool) error {
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid tar structure")
+		}
+
+		merger.mutex.Lock()
+		file, ok := merger.files[header.Name]
+		merger.mutex.Unlock()
+		switch {
+		case isPgControlTar && header.Name == PgControlPath:
+			err = merger.readPgControl(header, tarReader, file)
+		case !ok:
+			// the backup label and the tablespace map are not listed in the sentinel
+			if isNewest {
+				err = merger.writeFile(header, tarReader)
+			}
+		case file.isDone:
+		case sentinelDto.Files[header.Name].IsIncremented:
+			err = merger.readIncrement(header, tarReader, file)
+		default:
+			err = merger.writeMergedFile(header, tarReader, file)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to merge '%s'", header.Name)
+		}
+	}
+}
+
+func (merger *backupMerger) readPgControl(header *tar.Header, content io.Reader, file *mergedFile) error {
+	pgControl, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	merger.pgControlHeader = header
+	merger.pgControl = pgControl
+	if file != nil {
+		file.isDone = true
+	}
+	return nil
+}
+
+// readIncrement spools the pages of the increment that are not found in the newer backups
+func (merger *backupMerger) readIncrement(header *tar.Header, increment io.Reader, file *mergedFile) error {
+	fileSize, diffBlockCount, diffMap, err := GetIncrementHeaderFields(increment)
+	if err != nil {
+		return err
+	}
+	if file.header == nil {
+		file.header = header
+	}
+	if file.size < 0 {
+		file.size = int64(fileSize)
+	}
+	page := make([]byte, DatabasePageSize)
+	for i := uint32(0); i < diffBlockCount; i++ {
+		blockNo := binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
+		_, err = io.ReadFull(increment, page)
+		if err != nil {
+			return err
+		}
+		if _, ok := file.pages[blockNo]; ok || int64(blockNo)*DatabasePageSize >= file.size {
+			continue
+		}
+		offset := atomic.AddInt64(&merger.spoolSize, DatabasePageSize) - DatabasePageSize
+		_, err = merger.spool.WriteAt(page, offset)
+		if err != nil {
+			return errors.Wrap(err, "failed to spool the page")
+		}
+		file.pages[blockNo] = offset
+	}
+	if !isTarReaderEmpty(increment) {
+		return newUnexpectedTarDataError()
+	}
+	return nil
+}
+
+// writeMergedFile writes the full copy of the file with the pages of the increments applied
+func (merger *backupMerger) writeMergedFile(header *tar.Header, content io.Reader, file *mergedFile) error {
+	file.isDone = true
+	baseSize := header.Size
+	if file.header != nil {
+		mergedHeader := *file.header
+		header = &mergedHeader
+	}
+	if header.Typeflag != tar.TypeReg {
+		return merger.writeFile(header, nil)
+	}
+	if file.size < 0 {
+		file.size = baseSize
+	}
+	header.Size = file.size
+	if len(file.pages) == 0 && file.size == baseSize {
+		return merger.writeFile(header, content)
+	}
+
+	return merger.writeFile(header, &mergedPageReader{
+		base:      content,
+		spool:     merger.spool,
+		pages:     file.pages,
+		size:      file.size,
+		baseSize:  baseSize,
+		page:      make([]byte, DatabasePageSize),
+		basePage:  make([]byte, DatabasePageSize),
+		pageIndex: -1,
+	})
+}
+
+// writeFile packs the file into the merged backup, the content is nil for the headers without data
+func (merger *backupMerger) writeFile(header *tar.Header, content io.Reader) error {
+	tarBall := merger.tarBallQueue.Deque()
+	tarBall.SetUp(merger.crypter)
+	merger.mutex.Lock()
+	merger.tarFileSets[tarBall.Name()] = append(merger.tarFileSets[tarBall.Name()], header.Name)
+	merger.mutex.Unlock()
+
+	if content == nil {
+		err := tarBall.TarWriter().WriteHeader(header)
+		merger.tarBallQueue.EnqueueBack(tarBall)
+		return err
+	}
+	packedFileSize, err := internal.PackFileTo(tarBall, header, content)
+	if err == nil && packedFileSize != header.Size {
+		err = newTarSizeError(packedFileSize, header.Size)
+	}
+	if err != nil {
+		merger.tarBallQueue.EnqueueBack(tarBall)
+		return err
+	}
+	return merger.tarBallQueue.CheckSizeAndEnqueueBack(tarBall)
+}
+
+func (merger *backupMerger) uploadPgControl(compressorFileExtension string) error {
+	if merger.pgControlHeader == nil {
+		return newPgControlNotFoundError()
+	}
+	tarBall := merger.tarBallQueue.NewTarBall(false)
+	tarBall.SetUp(merger.crypter, "pg_control.tar."+compressorFileExtension)
+	_, err := internal.PackFileTo(tarBall, merger.pgControlHeader, bytes.NewReader(merger.pgControl))
+	if err != nil {
+		return err
+	}
+	return merger.tarBallQueue.CloseTarball(tarBall)
+}
+
+func (merger *backupMerger) newSentinelDto(deltaSentinelDto BackupSentinelDto,
+	uploader *internal.Uploader) (BackupSentinelDto, error) {
+	compressedSize, err := uploader.UploadedDataSize()
+	if err != nil {
+		return BackupSentinelDto{}, err
+	}
+	files := make(internal.BackupFileList, len(deltaSentinelDto.Files))
+	for name, description := range deltaSentinelDto.Files {
+		files[name] = internal.BackupFileDescription{MTime: description.MTime, Size: description.Size}
+	}
+	return BackupSentinelDto{
+		BackupStartLSN:   deltaSentinelDto.BackupStartLSN,
+		Files:            files,
+		TarFileSets:      merger.tarFileSets,
+		PgVersion:        deltaSentinelDto.PgVersion,
+		BackupFinishLSN:  deltaSentinelDto.BackupFinishLSN,
+		SystemIdentifier: deltaSentinelDto.SystemIdentifier,
+		UncompressedSize: atomic.LoadInt64(merger.tarBallQueue.AllTarballsSize),
+		CompressedSize:   compressedSize,
+		TablespaceSpec:   deltaSentinelDto.TablespaceSpec,
+		UserData:         deltaSentinelDto.UserData,
+		Databases:        deltaSentinelDto.Databases,
+	}, nil
+}
+
+// uploadMergedMetadata uploads the metadata of the delta backup with the sizes of the merged backup
+func uploadMergedMetadata(uploader *internal.Uploader, deltaBackup Backup, backupName string,
+	sentinelDto BackupSentinelDto) error {
+	meta, err := deltaBackup.FetchMeta()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to fetch the metadata of %s: %v\n", deltaBackup.Name, err)
+		meta = NewExtendedMetadataDto(false, "", utility.TimeNowCrossPlatformUTC(), sentinelDto)
+	}
+	meta.IsPermanent = false
+	meta.UncompressedSize = sentinelDto.UncompressedSize
+	meta.CompressedSize = sentinelDto.CompressedSize
+
+	metaFile := storage.JoinPath(backupName, utility.MetadataFileName)
+	dtoBody, err := json.Marshal(meta)
+	if err != nil {
+		return internal.NewSentinelMarshallingError(metaFile, err)
+	}
+	return uploader.Upload(metaFile, bytes.NewReader(dtoBody))
+}
+
+// mergedPageReader reads the full file page by page, taking the pages of the increments
+// from the spool and the rest from the base copy of the file
+type mergedPageReader struct {
+	base     io.Reader
+	spool    io.ReaderAt
+	pages    map[uint32]int64
+	size     int64
+	baseSize int64
+
+	page      []byte
+	basePage  []byte
+	pageIndex int64
+	offset    int64
+}
+
+func (reader *mergedPageReader) Read(p []byte) (int, error) {
+	if reader.offset >= reader.size {
+		return 0, io.EOF
+	}
+	pageIndex := reader.offset / DatabasePageSize
+	if pageIndex != reader.pageIndex {
+		err := reader.readPage(pageIndex)
+		if err != nil {
+			return 0, err
+		}
+	}
+	pageOffset := reader.offset % DatabasePageSize
+	pageEnd := utility.Min(int(DatabasePageSize), int(reader.size-pageIndex*DatabasePageSize))
+	n := copy(p, reader.page[pageOffset:pageEnd])
+	reader.offset += int64(n)
+	return n, nil
+}
+
+func (reader *mergedPageReader) readPage(pageIndex int64) error {
+	reader.pageIndex = pageIndex
+	basePageSize := 0
+	if pageIndex*DatabasePageSize < reader.baseSize {
+		// the base copy is read in full to keep the tar reader in sync
+		var err error
+		basePageSize, err = io.ReadFull(reader.base, reader.basePage)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+	if offset, ok := reader.pages[uint32(pageIndex)]; ok {
+		_, err := reader.spool.ReadAt(reader.page, offset)
+		return err
+	}
+	copy(reader.page, reader.basePage[:basePageSize])
+	for i := basePageSize; i < len(reader.page); i++ {
+		reader.page[i] = 0
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	testMergeFullBackup  = "base_000000010000000000000002"
+	testMergeDeltaBackup = "base_000000010000000000000004_D_000000010000000000000002"
+)
+
+type testTarFile struct {
+	name    string
+	content []byte
+}
+
+func testPage(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, int(DatabasePageSize))
+}
+
+func testPages(fills ...byte) []byte {
+	pages := make([]byte, 0)
+	for _, fill := range fills {
+		pages = append(pages, testPage(fill)...)
+	}
+	return pages
+}
+
+func testIncrement(fileSize uint64, blocks []uint32, pages []byte) []byte {
+	var increment bytes.Buffer
+	increment.Write(IncrementFileHeader)
+	increment.Write(utility.ToBytes(fileSize))
+	increment.Write(utility.ToBytes(uint32(len(blocks))))
+	for _, blockNo := range blocks {
+		increment.Write(utility.ToBytes(blockNo))
+	}
+	increment.Write(pages)
+	return increment.Bytes()
+}
+
+func putTestTar(t *testing.T, baseBackupFolder storage.Folder, backupName, tarName string, files []testTarFile) {
+	var tarBuffer bytes.Buffer
+	tarWriter := tar.NewWriter(&tarBuffer)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0600, Size: int64(len(file.content)), Typeflag: tar.TypeReg}
+		if file.content == nil {
+			header.Typeflag = tar.TypeDir
+		}
+		assert.NoError(t, tarWriter.WriteHeader(header))
+		_, err := tarWriter.Write(file.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, baseBackupFolder.PutObject(backupName+internal.TarPartitionFolderName+tarName, &tarBuffer))
+}
+
+func putTestSentinel(t *testing.T, baseBackupFolder storage.Folder, backupName string, sentinelDto BackupSentinelDto) {
+	sentinel, err := json.Marshal(sentinelDto)
+	assert.NoError(t, err)
+	assert.NoError(t, baseBackupFolder.PutObject(internal.SentinelNameFromBackup(backupName), bytes.NewReader(sentinel)))
+}
+
+func readTestBackup(t *testing.T, backup Backup) map[string][]byte {
+	tarNames, err := backup.GetTarNames()
+	assert.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, tarName := range tarNames {
+		var tarBuffer bytes.Buffer
+		err = internal.DecryptAndDecompressTar(&tarBuffer,
+			backup.newTarReaderMaker(tarName, internal.ChecksumManifest{}), nil)
+		assert.NoError(t, err)
+		tarReader := tar.NewReader(&tarBuffer)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			content, err := ioutil.ReadAll(tarReader)
+			assert.NoError(t, err)
+			files[header.Name] = content
+		}
+	}
+	return files
+}
+
+func TestMergeBackup(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	fullLSN, deltaLSN := uint64(0x2000028), uint64(0x4000028)
+	fullName := testMergeFullBackup
+	incrementCount := 1
+
+	putTestTar(t, baseBackupFolder, testMergeFullBackup, "part_1.tar", []testTarFile{
+		{"/base", nil},
+		{"/base/1/100", testPages(0xA0, 0xA1, 0xA2)},
+		{"/base/1/200", testPages(0xC0)},
+		{"/base/1/300", testPages(0xD0)},
+		{"/PG_VERSION", []byte("13\n")},
+	})
+	putTestTar(t, baseBackupFolder, testMergeFullBackup, "pg_control.tar", []testTarFile{
+		{PgControlPath, []byte("old control")},
+	})
+	putTestSentinel(t, baseBackupFolder, testMergeFullBackup, BackupSentinelDto{
+		BackupStartLSN:  &fullLSN,
+		BackupFinishLSN: &fullLSN,
+		PgVersion:       130000,
+		Files: internal.BackupFileList{
+			"/base":       {},
+			"/base/1/100": {},
+			"/base/1/200": {},
+			"/base/1/300": {},
+			"/PG_VERSION": {},
+		},
+		TarFileSets: TarFileSets{"part_1.tar": {"/base", "/base/1/100", "/base/1/200", "/base/1/300", "/PG_VERSION"}},
+	})
+
+	putTestTar(t, baseBackupFolder, testMergeDeltaBackup, "part_1.tar", []testTarFile{
+		{"/base", nil},
+		{"/base/1/100", testIncrement(uint64(4*DatabasePageSize), []uint32{1, 3}, testPages(0xB1, 0xB3))},
+		{"/PG_VERSION", []byte("13\n")},
+		{"/backup_label", []byte("label")},
+	})
+	putTestTar(t, baseBackupFolder, testMergeDeltaBackup, "pg_control.tar", []testTarFile{
+		{PgControlPath, []byte("new control")},
+	})
+	putTestSentinel(t, baseBackupFolder, testMergeDeltaBackup, BackupSentinelDto{
+		BackupStartLSN:    &deltaLSN,
+		BackupFinishLSN:   &deltaLSN,
+		IncrementFromLSN:  &fullLSN,
+		IncrementFrom:     &fullName,
+		IncrementFullName: &fullName,
+		IncrementCount:    &incrementCount,
+		PgVersion:         130000,
+		Files: internal.BackupFileList{
+			"/base":       {},
+			"/base/1/100": {IsIncremented: true},
+			"/base/1/200": {IsSkipped: true},
+			"/PG_VERSION": {},
+		},
+		TarFileSets: TarFileSets{"part_1.tar": {"/base", "/base/1/100", "/PG_VERSION", "/backup_label"}},
+	})
+
+	mergedBackupName, err := mergeBackup(NewWalUploader(lz4.Compressor{}, folder, nil), testMergeDeltaBackup)
+	assert.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000004", mergedBackupName)
+
+	mergedBackup := NewBackup(baseBackupFolder, mergedBackupName)
+	sentinelDto, err := mergedBackup.GetSentinel()
+	assert.NoError(t, err)
+	assert.False(t, sentinelDto.IsIncremental())
+	assert.Equal(t, deltaLSN, *sentinelDto.BackupStartLSN)
+	assert.Len(t, sentinelDto.Files, 4)
+	assert.False(t, sentinelDto.Files["/base/1/100"].IsIncremented)
+	assert.False(t, sentinelDto.Files["/base/1/200"].IsSkipped)
+
+	files := readTestBackup(t, mergedBackup)
+	assert.Equal(t, testPages(0xA0, 0xB1, 0xA2, 0xB3), files["/base/1/100"])
+	assert.Equal(t, testPages(0xC0), files["/base/1/200"])
+	assert.NotContains(t, files, "/base/1/300")
+	assert.Equal(t, []byte("label"), files["/backup_label"])
+	assert.Equal(t, []byte("new control"), files[PgControlPath])
+
+	_, err = mergeBackup(NewWalUploader(lz4.Compressor{}, folder, nil), testMergeDeltaBackup)
+	assert.Error(t, err)
+}
+
+func TestMergeBackup_NotDeltaBackup(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	lsn := uint64(0x2000028)
+	putTestSentinel(t, folder.GetSubFolder(utility.BaseBackupPath), testMergeFullBackup, BackupSentinelDto{
+		BackupStartLSN: &lsn,
+		Files:          internal.BackupFileList{},
+	})
+
+	_, err := mergeBackup(NewWalUploader(lz4.Compressor{}, folder, nil), testMergeFullBackup)
+	assert.Error(t, err)
+}
+
+func TestMergeBackup_InconsistentSentinel(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	lsn := uint64(0x4000028)
+	fullName := testMergeFullBackup
+	putTestSentinel(t, folder.GetSubFolder(utility.BaseBackupPath), testMergeDeltaBackup, BackupSentinelDto{
+		BackupStartLSN: &lsn,
+		IncrementFrom:  &fullName,
+		Files:          internal.BackupFileList{},
+	})
+
+	assert.NotPanics(t, func() {
+		_, err := mergeBackup(NewWalUploader(lz4.Compressor{}, folder, nil), testMergeDeltaBackup)
+		assert.Error(t, err)
+	})
+}
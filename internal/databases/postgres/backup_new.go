@@ -77,7 +77,7 @@ func (backup *Backup) unwrapNew(
 	}
 
 	tarInterpreter := NewFileTarInterpreter(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles)
-	tarsToExtract, pgControlKey, err := backup.getTarsToExtract(sentinelDto, filesToUnwrap, skipRedundantTars)
+	tarsToExtract, pgControlTar, err := backup.getTarsToExtract(sentinelDto, filesToUnwrap, skipRedundantTars)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +85,7 @@ func (backup *Backup) unwrapNew(
 	// Check name for backwards compatibility. Will check for `pg_control` if WALG version of backup.
 	needPgControl := IsPgControlRequired(*backup, sentinelDto)
 
-	if pgControlKey == "" && needPgControl {
+	if pgControlTar == nil && needPgControl {
 		return nil, newPgControlNotFoundError()
 	}
 
@@ -100,7 +100,7 @@ func (backup *Backup) unwrapNew(
 	}
 
 	if needPgControl {
-		readerMakers := []internal.ReaderMaker{internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey)}
+		readerMakers := []internal.ReaderMaker{pgControlTar}
 		err = internal.ExtractAll(tarInterpreter, readerMakers)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to extract pg_control")
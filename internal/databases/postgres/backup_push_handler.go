@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/metrics"
 
 	"github.com/jackc/pgconn"
 
@@ -70,6 +71,9 @@ type CurBackupInfo struct {
 	uncompressedSize int64
 	compressedSize   int64
 	incrementCount   int
+	databases        DatabaseDescriptions
+	sourceRole       string
+	upstream         string
 }
 
 // PrevBackupInfo holds all information that is harvest during the backup process
@@ -149,11 +153,16 @@ func (bh *BackupHandler) createAndPushBackup() {
 
 	err = bh.startBackup()
 	tracelog.ErrorLogger.FatalOnError(err)
+	err = bh.checkBackupSource(folder.GetSubFolder(utility.WalPath))
+	tracelog.ErrorLogger.FatalOnError(err)
 	bh.handleDeltaBackup(folder)
+	err = bh.setupBackupPushState()
+	tracelog.ErrorLogger.FatalfOnError("Failed to set up the backup-push state: %v", err)
 	tarFileSets := bh.uploadBackup()
 	sentinelDto := bh.setupDTO(tarFileSets)
 	bh.markBackups(folder, sentinelDto)
 	bh.uploadMetadata(sentinelDto)
+	bh.workers.bundle.pushState.remove()
 
 	// logging backup set name
 	tracelog.InfoLogger.Printf("Wrote backup with name %s", bh.curBackupInfo.name)
@@ -166,6 +175,10 @@ func (bh *BackupHandler) startBackup() (err error) {
 	if err != nil {
 		return
 	}
+	bh.curBackupInfo.databases, err = newDatabaseDescriptions(bh.workers.conn)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to record the databases, backup-fetch --database will not work: %v\n", err)
+	}
 
 	tracelog.DebugLogger.Println("Running StartBackup.")
 	backupName, backupStartLSN, err := bh.workers.bundle.StartBackup(
@@ -194,7 +207,12 @@ func (bh *BackupHandler) handleDeltaBackup(folder storage.Folder) {
 			tracelog.ErrorLogger.FatalOnError(newBackupFromOtherBD())
 		}
 		if bh.workers.uploader.getUseWalDelta() {
-			err := bh.workers.bundle.DownloadDeltaMap(folder.GetSubFolder(utility.WalPath), bh.curBackupInfo.startLSN)
+			var localWalDirectory string
+			if viper.GetBool(internal.DeltaFromLocalWalSetting) {
+				localWalDirectory = bh.getLocalWalDirectory()
+			}
+			err := bh.workers.bundle.DownloadDeltaMapWithLocalWals(folder.GetSubFolder(utility.WalPath),
+				localWalDirectory, bh.curBackupInfo.startLSN)
 			if err == nil {
 				tracelog.InfoLogger.Println("Successfully loaded delta map, delta backup will be made with provided " +
 					"delta map")
@@ -208,6 +226,14 @@ func (bh *BackupHandler) handleDeltaBackup(folder storage.Folder) {
 	}
 }
 
+// getLocalWalDirectory returns the pg_wal directory, which holds the WAL segments not archived yet
+func (bh *BackupHandler) getLocalWalDirectory() string {
+	if bh.pgInfo.pgVersion < 100000 {
+		return filepath.Join(bh.pgInfo.pgDataDirectory, "pg_xlog")
+	}
+	return filepath.Join(bh.pgInfo.pgDataDirectory, "pg_wal")
+}
+
 func (bh *BackupHandler) setupDTO(tarFileSets TarFileSets) (sentinelDto BackupSentinelDto) {
 	var tablespaceSpec *TablespaceSpec
 	if !bh.workers.bundle.TablespaceSpec.empty() {
@@ -231,7 +257,8 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 	bundle := bh.workers.bundle
 	// Start a new tar bundle, walk the pgDataDirectory and upload everything there.
 	tracelog.InfoLogger.Println("Starting a new tar bundle")
-	err := bundle.StartQueue(internal.NewStorageTarBallMaker(bh.curBackupInfo.name, bh.workers.uploader.Uploader))
+	err := bundle.StartQueue(internal.NewResumedStorageTarBallMaker(bh.curBackupInfo.name,
+		bh.workers.uploader.Uploader, bundle.pushState.getLastPartNumber()))
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	tarBallComposerMaker, err := NewTarBallComposerMaker(bh.arguments.tarBallComposerType, bh.workers.conn,
@@ -252,6 +279,10 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 	tracelog.DebugLogger.Println("Finishing queue ...")
 	err = bundle.FinishQueue()
 	tracelog.ErrorLogger.FatalOnError(err)
+	bundle.deltaMapStats.logSummary()
+	if err = bundle.pushState.save(); err != nil {
+		tracelog.WarningLogger.Printf("Failed to save the backup-push state: %v\n", err)
+	}
 
 	tracelog.DebugLogger.Println("Uploading pg_control ...")
 	err = bundle.UploadPgControl(bh.workers.uploader.Compressor.FileExtension())
@@ -265,6 +296,9 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 	bh.curBackupInfo.uncompressedSize = atomic.LoadInt64(bundle.TarBallQueue.AllTarballsSize)
 	bh.curBackupInfo.compressedSize, err = bh.workers.uploader.UploadedDataSize()
 	tracelog.ErrorLogger.FatalOnError(err)
+	reusedUncompressedSize, reusedCompressedSize := bundle.pushState.addReusedParts(tarFileSets)
+	bh.curBackupInfo.uncompressedSize += reusedUncompressedSize
+	bh.curBackupInfo.compressedSize += reusedCompressedSize
 	tarFileSets[labelFilesTarBallName] = append(tarFileSets[labelFilesTarBallName], labelFilesList...)
 	timelineChanged := bundle.checkTimelineChanged(bh.workers.conn)
 	tracelog.DebugLogger.Printf("Labelfiles tarball name: %s", labelFilesTarBallName)
@@ -288,6 +322,7 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 // HandleBackupPush handles the backup being read from Postgres or filesystem and being pushed to the repository
 // TODO : unit tests
 func (bh *BackupHandler) HandleBackupPush() {
+	defer metrics.BackupPushDuration.ObserveDuration(time.Now())
 	folder := bh.workers.uploader.UploadingFolder
 	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
 	tracelog.DebugLogger.Printf("Base backup folder: %s", baseBackupFolder)
@@ -546,6 +581,8 @@ func (bh *BackupHandler) configureDeltaBackup() (err error) {
 func (bh *BackupHandler) uploadExtendedMetadata(sentinelDto BackupSentinelDto) (err error) {
 	meta := NewExtendedMetadataDto(bh.arguments.isPermanent, bh.pgInfo.pgDataDirectory,
 		bh.curBackupInfo.startTime, sentinelDto)
+	meta.SourceRole = bh.curBackupInfo.sourceRole
+	meta.Upstream = bh.curBackupInfo.upstream
 
 	metaFile := storage.JoinPath(bh.curBackupInfo.name, utility.MetadataFileName)
 	dtoBody, err := json.Marshal(meta)
@@ -0,0 +1,316 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// backupPushStateSaveInterval limits how often the state is saved while the files are packed
+const backupPushStateSaveInterval = time.Minute
+
+// backupPushStateFile is the file packed into the uploaded part
+type backupPushStateFile struct {
+	Size          int64     `json:"Size"`
+	MTime         time.Time `json:"MTime"`
+	Inode         uint64    `json:"Inode,omitempty"`
+	CTime         time.Time `json:"CTime"`
+	IsIncremented bool      `json:"IsIncremented,omitempty"`
+}
+
+func newBackupPushStateFile(fileInfo os.FileInfo, isIncremented bool) backupPushStateFile {
+	file := backupPushStateFile{Size: fileInfo.Size(), MTime: fileInfo.ModTime(), IsIncremented: isIncremented}
+	file.Inode, file.CTime, _ = getFileIdentity(fileInfo)
+	return file
+}
+
+// isUnchanged compares the file with the one packed. A write right after the packing keeps the mtime
+// on the file systems with coarse timestamps, so the mtime with no sub-microsecond part is not trusted.
+func (file backupPushStateFile) isUnchanged(fileInfo os.FileInfo) bool {
+	if fileInfo.Size() != file.Size || !fileInfo.ModTime().Equal(file.MTime) ||
+		file.MTime.Nanosecond()%int(time.Microsecond) == 0 {
+		return false
+	}
+	inode, cTime, ok := getFileIdentity(fileInfo)
+	return !ok || inode == file.Inode && cTime.Equal(file.CTime)
+}
+
+// backupPushStatePart is the tar part uploaded by backup-push
+type backupPushStatePart struct {
+	Checksum string `json:"Checksum,omitempty"`
+	// TarSize is the size of the packed file contents
+	TarSize int64 `json:"TarSize"`
+	// NotReusable marks the parts with the increments read by the delta map, they miss the pages
+	// changed after the start of the interrupted backup
+	NotReusable bool                           `json:"NotReusable,omitempty"`
+	Files       map[string]backupPushStateFile `json:"Files"`
+}
+
+// backupPushState is saved to WALG_BACKUP_PUSH_STATE_FILE while backup-push uploads the parts,
+// so the next backup-push reuses the uploaded parts if the interrupted one fails
+type backupPushState struct {
+	path         string
+	uploader     *internal.Uploader
+	mutex        sync.Mutex
+	lastSaveTime time.Time
+
+	reusedParts          map[string]*backupPushStatePart
+	reusedFiles          map[string]backupPushStateFile
+	reusedCompressedSize int64
+
+	BackupName       string                          `json:"BackupName"`
+	StartLSN         uint64                          `json:"StartLSN"`
+	Timeline         uint32                          `json:"Timeline"`
+	PgDataDirectory  string                          `json:"PgDataDirectory"`
+	SystemIdentifier *uint64                         `json:"SystemIdentifier,omitempty"`
+	DeltaBaseName    string                          `json:"DeltaBaseName,omitempty"`
+	Parts            map[string]*backupPushStatePart `json:"Parts"`
+}
+
+func readBackupPushState(path string) (*backupPushState, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the backup-push state")
+	}
+	var state backupPushState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the backup-push state %s", path)
+	}
+	return &state, nil
+}
+
+// setupBackupPushState creates the state of backup-push if WALG_BACKUP_PUSH_STATE_FILE is set.
+// The state left by the interrupted backup-push of the same cluster and delta base is resumed.
+func (bh *BackupHandler) setupBackupPushState() error {
+	statePath, ok := internal.GetSetting(internal.BackupPushStateFileSetting)
+	if !ok {
+		return nil
+	}
+	state, err := bh.openBackupPushState(statePath)
+	if err != nil {
+		return err
+	}
+	bh.workers.bundle.pushState = state
+	return nil
+}
+
+func (bh *BackupHandler) openBackupPushState(statePath string) (*backupPushState, error) {
+	state := &backupPushState{
+		path:             statePath,
+		uploader:         bh.workers.uploader.Uploader,
+		reusedParts:      make(map[string]*backupPushStatePart),
+		reusedFiles:      make(map[string]backupPushStateFile),
+		BackupName:       bh.curBackupInfo.name,
+		StartLSN:         bh.curBackupInfo.startLSN,
+		Timeline:         bh.workers.bundle.Timeline,
+		PgDataDirectory:  bh.pgInfo.pgDataDirectory,
+		SystemIdentifier: bh.pgInfo.systemIdentifier,
+		DeltaBaseName:    bh.prevBackupInfo.name,
+		Parts:            make(map[string]*backupPushStatePart),
+	}
+	prevState, err := readBackupPushState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	if prevState != nil {
+		if reason := state.getNotResumableReason(prevState); reason != "" {
+			tracelog.InfoLogger.Printf("Not resuming backup %s: %s\n", prevState.BackupName, reason)
+		} else if err = bh.resumeBackupPushState(state, prevState); err != nil {
+			return nil, err
+		}
+	}
+	return state, state.save()
+}
+
+func (state *backupPushState) getNotResumableReason(prevState *backupPushState) string {
+	switch {
+	case prevState.PgDataDirectory != state.PgDataDirectory:
+		return fmt.Sprintf("it is taken from the data directory %s", prevState.PgDataDirectory)
+	case prevState.SystemIdentifier != nil && state.SystemIdentifier != nil &&
+		*prevState.SystemIdentifier != *state.SystemIdentifier:
+		return "it is taken from another cluster"
+	case prevState.Timeline != state.Timeline:
+		return fmt.Sprintf("it is taken on the timeline %d", prevState.Timeline)
+	case prevState.StartLSN > state.StartLSN:
+		return fmt.Sprintf("its start LSN %x is ahead of the current one", prevState.StartLSN)
+	case prevState.DeltaBaseName != state.DeltaBaseName:
+		return fmt.Sprintf("it has the delta base '%s'", prevState.DeltaBaseName)
+	}
+	return ""
+}
+
+// resumeBackupPushState continues the interrupted backup under its name. The uploaded parts are reused
+// if all their files are unchanged since they were packed, the other objects of the backup are deleted.
+func (bh *BackupHandler) resumeBackupPushState(state *backupPushState, prevState *backupPushState) error {
+	folder := bh.workers.uploader.UploadingFolder
+	sentinelExists, err := folder.Exists(internal.SentinelNameFromBackup(prevState.BackupName))
+	if err != nil {
+		return errors.Wrapf(err, "failed to check the sentinel of backup %s", prevState.BackupName)
+	}
+	if sentinelExists {
+		tracelog.InfoLogger.Printf("Not resuming backup %s: it is already finished\n", prevState.BackupName)
+		return nil
+	}
+
+	tarPartitionFolder := folder.GetSubFolder(prevState.BackupName + internal.TarPartitionFolderName)
+	objects, _, err := tarPartitionFolder.ListFolder()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the parts of backup %s", prevState.BackupName)
+	}
+	state.BackupName = prevState.BackupName
+	objectsToDelete := make([]string, 0)
+	for _, object := range objects {
+		part, ok := prevState.Parts[object.GetName()]
+		if !ok || !part.isReusable(state.PgDataDirectory) {
+			objectsToDelete = append(objectsToDelete, object.GetName())
+			continue
+		}
+		state.Parts[object.GetName()] = part
+		state.reusedParts[object.GetName()] = part
+		for name, file := range part.Files {
+			state.reusedFiles[name] = file
+		}
+		state.reusedCompressedSize += object.GetSize()
+		state.uploader.SetObjectChecksum(state.getPartPath(object.GetName()), part.Checksum)
+	}
+	if err = tarPartitionFolder.DeleteObjects(objectsToDelete); err != nil {
+		return errors.Wrapf(err, "failed to delete the parts of backup %s", prevState.BackupName)
+	}
+
+	bh.curBackupInfo.name = state.BackupName
+	tracelog.InfoLogger.Printf("Resuming backup %s started at LSN %x, reusing %d of %d uploaded parts\n",
+		state.BackupName, prevState.StartLSN, len(state.Parts), len(objects))
+	return nil
+}
+
+// isReusable checks that the files of the part are not changed since they were packed
+func (part *backupPushStatePart) isReusable(directory string) bool {
+	if part.NotReusable || part.Checksum == "" {
+		return false
+	}
+	for name, file := range part.Files {
+		fileInfo, err := os.Stat(filepath.Join(directory, name))
+		if err != nil || !file.isUnchanged(fileInfo) {
+			return false
+		}
+	}
+	return true
+}
+
+// getReusedFile returns the file packed into the reused part
+func (state *backupPushState) getReusedFile(name string) (backupPushStateFile, bool) {
+	if state == nil {
+		return backupPushStateFile{}, false
+	}
+	file, ok := state.reusedFiles[name]
+	return file, ok
+}
+
+// addPackedFile records the file packed into the tar, the state errors do not fail the backup
+func (state *backupPushState) addPackedFile(tarName string, cfi *ComposeFileInfo, isReusable bool) {
+	if state == nil {
+		return
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	part, ok := state.Parts[tarName]
+	if !ok {
+		part = &backupPushStatePart{Files: make(map[string]backupPushStateFile)}
+		state.Parts[tarName] = part
+	}
+	part.Files[cfi.header.Name] = newBackupPushStateFile(cfi.fileInfo, cfi.isIncremented)
+	part.TarSize += cfi.header.Size
+	part.NotReusable = part.NotReusable || !isReusable
+
+	if time.Since(state.lastSaveTime) >= backupPushStateSaveInterval {
+		if err := state.saveLocked(); err != nil {
+			tracelog.WarningLogger.Printf("Failed to save the backup-push state: %v\n", err)
+		}
+	}
+}
+
+// addReusedParts adds the reused parts to the tar file sets and returns their sizes
+func (state *backupPushState) addReusedParts(tarFileSets TarFileSets) (uncompressedSize int64, compressedSize int64) {
+	if state == nil {
+		return 0, 0
+	}
+	for name, part := range state.reusedParts {
+		for fileName := range part.Files {
+			tarFileSets[name] = append(tarFileSets[name], fileName)
+		}
+		uncompressedSize += part.TarSize
+	}
+	return uncompressedSize, state.reusedCompressedSize
+}
+
+// getLastPartNumber returns the greatest number of the reused parts
+func (state *backupPushState) getLastPartNumber() int {
+	lastPartNumber := 0
+	if state == nil {
+		return lastPartNumber
+	}
+	for name := range state.reusedParts {
+		var partNumber int
+		if _, err := fmt.Sscanf(name, "part_%d.tar", &partNumber); err == nil {
+			lastPartNumber = utility.Max(lastPartNumber, partNumber)
+		}
+	}
+	return lastPartNumber
+}
+
+func (state *backupPushState) getPartPath(name string) string {
+	return state.BackupName + internal.TarPartitionFolderName + name
+}
+
+func (state *backupPushState) save() error {
+	if state == nil {
+		return nil
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.saveLocked()
+}
+
+// saveLocked saves the parts uploaded completely, i.e. the ones with the checksums computed by the uploader
+func (state *backupPushState) saveLocked() error {
+	state.lastSaveTime = time.Now()
+	uploadedParts := make(map[string]*backupPushStatePart)
+	for name, part := range state.Parts {
+		if part.Checksum == "" {
+			part.Checksum, _ = state.uploader.ObjectChecksum(state.getPartPath(name))
+		}
+		if part.Checksum != "" {
+			uploadedParts[name] = part
+		}
+	}
+	allParts := state.Parts
+	state.Parts = uploadedParts
+	data, err := json.Marshal(state)
+	state.Parts = allParts
+	if err != nil {
+		return err
+	}
+	return utility.ReplaceFile(state.path, data)
+}
+
+// remove deletes the state once the backup is finished
+func (state *backupPushState) remove() {
+	if state == nil {
+		return
+	}
+	if err := os.Remove(state.path); err != nil && !os.IsNotExist(err) {
+		tracelog.WarningLogger.Printf("Failed to remove the backup-push state %s: %v\n", state.path, err)
+	}
+}
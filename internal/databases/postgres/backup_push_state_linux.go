@@ -0,0 +1,18 @@
+// +build linux
+
+package postgres
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// getFileIdentity returns the inode and the status change time of the file
+func getFileIdentity(fileInfo os.FileInfo) (inode uint64, cTime time.Time, ok bool) {
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return stat.Ino, time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec)), true
+}
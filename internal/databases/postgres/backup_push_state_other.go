@@ -0,0 +1,13 @@
+// +build !linux,!darwin
+
+package postgres
+
+import (
+	"os"
+	"time"
+)
+
+// getFileIdentity is not supported on this platform, the files are compared by the size and the mtime only
+func getFileIdentity(fileInfo os.FileInfo) (inode uint64, cTime time.Time, ok bool) {
+	return 0, time.Time{}, false
+}
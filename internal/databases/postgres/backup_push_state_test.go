@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+func addTestPackedFile(t *testing.T, state *backupPushState, directory, tarName, fileName string) {
+	fileInfo, err := os.Stat(filepath.Join(directory, fileName))
+	assert.NoError(t, err)
+	header := &tar.Header{Name: fileName, Size: fileInfo.Size()}
+	state.addPackedFile(tarName, NewComposeFileInfo(filepath.Join(directory, fileName), fileInfo, false, false, header), true)
+}
+
+func TestBackupPushState_ResumesUnchangedParts(t *testing.T) {
+	directory, err := ioutil.TempDir("", "backup_push_state")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	// the mtime with the sub-microsecond part is trusted
+	packedTime := time.Unix(1600000000, 123456789)
+	for _, name := range []string{"unchanged", "changed", "not_uploaded"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, name), []byte(name), 0600))
+		assert.NoError(t, os.Chtimes(filepath.Join(directory, name), packedTime, packedTime))
+	}
+	statePath := filepath.Join(directory, "state.json")
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+
+	interruptedBackup := &BackupHandler{
+		curBackupInfo: CurBackupInfo{name: "base_000000010000000000000002", startLSN: 0x2000028},
+		pgInfo:        BackupPgInfo{pgDataDirectory: directory},
+		workers:       BackupWorkers{uploader: NewWalUploader(lz4.Compressor{}, folder, nil), bundle: &Bundle{}},
+	}
+	state, err := interruptedBackup.openBackupPushState(statePath)
+	assert.NoError(t, err)
+	addTestPackedFile(t, state, directory, "part_001.tar.lz4", "/unchanged")
+	addTestPackedFile(t, state, directory, "part_002.tar.lz4", "/changed")
+	addTestPackedFile(t, state, directory, "part_003.tar.lz4", "/not_uploaded")
+	for _, name := range []string{"part_001.tar.lz4", "part_002.tar.lz4", "part_003.tar.lz4"} {
+		assert.NoError(t, folder.PutObject(state.getPartPath(name), bytes.NewReader([]byte(name))))
+	}
+	state.uploader.SetObjectChecksum(state.getPartPath("part_001.tar.lz4"), "checksum1")
+	state.uploader.SetObjectChecksum(state.getPartPath("part_002.tar.lz4"), "checksum2")
+	assert.NoError(t, state.save())
+
+	changedTime := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(directory, "changed"), changedTime, changedTime))
+
+	resumedBackup := &BackupHandler{
+		curBackupInfo: CurBackupInfo{name: "base_000000010000000000000004", startLSN: 0x4000028},
+		pgInfo:        BackupPgInfo{pgDataDirectory: directory},
+		workers:       BackupWorkers{uploader: NewWalUploader(lz4.Compressor{}, folder, nil), bundle: &Bundle{}},
+	}
+	resumedState, err := resumedBackup.openBackupPushState(statePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000002", resumedBackup.curBackupInfo.name)
+	assert.Equal(t, 1, resumedState.getLastPartNumber())
+
+	_, isReused := resumedState.getReusedFile("/unchanged")
+	assert.True(t, isReused)
+	_, isReused = resumedState.getReusedFile("/changed")
+	assert.False(t, isReused)
+
+	objects, _, err := folder.GetSubFolder(resumedState.BackupName + internal.TarPartitionFolderName).ListFolder()
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	checksum, ok := resumedState.uploader.ObjectChecksum(resumedState.getPartPath("part_001.tar.lz4"))
+	assert.True(t, ok)
+	assert.Equal(t, "checksum1", checksum)
+
+	tarFileSets := TarFileSets{"part_002.tar.lz4": {"/changed"}}
+	uncompressedSize, compressedSize := resumedState.addReusedParts(tarFileSets)
+	assert.Equal(t, TarFileSets{"part_001.tar.lz4": {"/unchanged"}, "part_002.tar.lz4": {"/changed"}}, tarFileSets)
+	assert.Equal(t, int64(len("unchanged")), uncompressedSize)
+	assert.Equal(t, int64(len("part_001.tar.lz4")), compressedSize)
+}
+
+func TestBackupPushState_NotResumedForOtherDeltaBase(t *testing.T) {
+	directory, err := ioutil.TempDir("", "backup_push_state")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	statePath := filepath.Join(directory, "state.json")
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+
+	interruptedBackup := &BackupHandler{
+		curBackupInfo: CurBackupInfo{name: "base_000000010000000000000002", startLSN: 0x2000028},
+		pgInfo:        BackupPgInfo{pgDataDirectory: directory},
+		workers:       BackupWorkers{uploader: NewWalUploader(lz4.Compressor{}, folder, nil), bundle: &Bundle{}},
+	}
+	_, err = interruptedBackup.openBackupPushState(statePath)
+	assert.NoError(t, err)
+
+	resumedBackup := &BackupHandler{
+		curBackupInfo:  CurBackupInfo{name: "base_000000010000000000000004", startLSN: 0x4000028},
+		prevBackupInfo: PrevBackupInfo{name: "base_000000010000000000000001"},
+		pgInfo:         BackupPgInfo{pgDataDirectory: directory},
+		workers:        BackupWorkers{uploader: NewWalUploader(lz4.Compressor{}, folder, nil), bundle: &Bundle{}},
+	}
+	_, err = resumedBackup.openBackupPushState(statePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000004", resumedBackup.curBackupInfo.name)
+}
+
+func TestBackupPushStateFile_IsUnchanged(t *testing.T) {
+	directory, err := ioutil.TempDir("", "backup_push_state")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	path := filepath.Join(directory, "file")
+	packedTime := time.Unix(1600000000, 123456789)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("packed"), 0600))
+	assert.NoError(t, os.Chtimes(path, packedTime, packedTime))
+	fileInfo, err := os.Stat(path)
+	assert.NoError(t, err)
+	file := newBackupPushStateFile(fileInfo, false)
+	assert.True(t, file.isUnchanged(fileInfo))
+
+	// rewritten with the same size and the mtime restored, the status change time differs
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("copied"), 0600))
+	assert.NoError(t, os.Chtimes(path, packedTime, packedTime))
+	fileInfo, err = os.Stat(path)
+	assert.NoError(t, err)
+	if _, _, ok := getFileIdentity(fileInfo); ok {
+		assert.False(t, file.isUnchanged(fileInfo))
+	}
+
+	// the mtime of a whole number of microseconds may come from a file system with coarse timestamps
+	coarseTime := time.Unix(1600000000, 123000000)
+	assert.NoError(t, os.Chtimes(path, coarseTime, coarseTime))
+	fileInfo, err = os.Stat(path)
+	assert.NoError(t, err)
+	file = newBackupPushStateFile(fileInfo, false)
+	assert.False(t, file.isUnchanged(fileInfo))
+}
@@ -32,6 +32,8 @@ type BackupSentinelDto struct {
 	CompressedSize   int64           `json:"CompressedSize"`
 	TablespaceSpec   *TablespaceSpec `json:"Spec"`
 
+	Databases DatabaseDescriptions `json:"Databases,omitempty"`
+
 	UserData interface{} `json:"UserData,omitempty"`
 }
 
@@ -58,6 +60,7 @@ func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec, tarFileSet
 	sentinel.UncompressedSize = bh.curBackupInfo.uncompressedSize
 	sentinel.CompressedSize = bh.curBackupInfo.compressedSize
 	sentinel.TarFileSets = tarFileSets
+	sentinel.Databases = bh.curBackupInfo.databases
 	return sentinel
 }
 
@@ -74,6 +77,10 @@ type ExtendedMetadataDto struct {
 	IsPermanent      bool      `json:"is_permanent"`
 	SystemIdentifier *uint64   `json:"system_identifier"`
 
+	// SourceRole is either primary or replica, Upstream is the server the replica receives WAL from
+	SourceRole string `json:"source_role,omitempty"`
+	Upstream   string `json:"upstream,omitempty"`
+
 	UncompressedSize int64 `json:"uncompressed_size"`
 	CompressedSize   int64 `json:"compressed_size"`
 
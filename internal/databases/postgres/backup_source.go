@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// The roles of the server the backup is taken from
+const (
+	BackupSourcePrimary = "primary"
+	BackupSourceReplica = "replica"
+)
+
+type replicaTimelineError struct {
+	error
+}
+
+func newReplicaTimelineError(format string, args ...interface{}) replicaTimelineError {
+	return replicaTimelineError{errors.Errorf(format, args...)}
+}
+
+func (err replicaTimelineError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// checkBackupSource records the role of the server the backup is taken from. On a standby it also records
+// the upstream, checks that the standby is on the latest archived timeline and warns about the replay lag.
+func (bh *BackupHandler) checkBackupSource(walFolder storage.Folder) error {
+	if !bh.workers.bundle.Replica {
+		bh.curBackupInfo.sourceRole = BackupSourcePrimary
+		return nil
+	}
+	bh.curBackupInfo.sourceRole = BackupSourceReplica
+	tracelog.InfoLogger.Println("Backup is taken from a standby")
+
+	queryRunner, err := NewPgQueryRunner(bh.workers.conn)
+	if err != nil {
+		return errors.Wrap(err, "checkBackupSource: failed to build query runner")
+	}
+	bh.curBackupInfo.upstream, err = queryRunner.GetWalReceiverUpstream()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get the upstream of the standby: %v\n", err)
+	}
+
+	err = checkReplicaTimeline(walFolder, bh.workers.bundle.Timeline, bh.curBackupInfo.startLSN)
+	if err != nil {
+		return err
+	}
+
+	replayLag, err := queryRunner.GetReplayLag()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get the replay lag of the standby: %v\n", err)
+		return nil
+	}
+	_, err = checkReplayLag(replayLag)
+	return err
+}
+
+// checkReplayLag warns if the standby replays WAL more than WALG_REPLICA_MAX_REPLAY_LAG behind,
+// the zero setting turns the check off. It returns whether the standby lags behind.
+func checkReplayLag(replayLag time.Duration) (bool, error) {
+	maxReplayLag, err := internal.GetDurationSetting(internal.ReplicaMaxReplayLagSetting)
+	if err != nil {
+		return false, err
+	}
+	if maxReplayLag <= 0 || replayLag <= maxReplayLag {
+		return false, nil
+	}
+	tracelog.WarningLogger.Printf("The standby replays WAL %v behind, the backup lags behind the primary\n", replayLag)
+	return true, nil
+}
+
+// checkReplicaTimeline checks that the timeline of the standby is the latest one in the archive.
+// The newer archived timeline is fine only if it has switched from the standby timeline after the backup start,
+// i.e. the standby has not followed the promoted server yet.
+func checkReplicaTimeline(walFolder storage.Folder, timeline uint32, startLSN uint64) error {
+	if timeline > 1 {
+		_, exists, err := findWalObject(walFolder, fmt.Sprintf(walHistoryFileFormat, timeline), walObjectExtensions())
+		if err != nil {
+			return errors.Wrap(err, "failed to look up the .history files")
+		}
+		if !exists {
+			tracelog.WarningLogger.Printf("History of the standby timeline %d is not archived yet\n", timeline)
+		}
+	}
+	latestTimeline, err := getLatestArchivedTimeline(walFolder, timeline)
+	if err != nil {
+		return err
+	}
+	if latestTimeline <= timeline {
+		return nil
+	}
+
+	latestHistoryRecords, err := getTimeLineHistoryRecords(latestTimeline, walFolder)
+	if err != nil {
+		return err
+	}
+	for _, record := range latestHistoryRecords {
+		if record.timeline != timeline {
+			continue
+		}
+		if record.lsn <= startLSN {
+			return newReplicaTimelineError("The standby is on timeline %d, which the archived timeline %d "+
+				"has switched from at LSN %x before the backup start LSN %x", timeline, latestTimeline, record.lsn, startLSN)
+		}
+		tracelog.WarningLogger.Printf("The archive has timeline %d switched from the standby timeline %d "+
+			"at LSN %x, the standby has not followed it yet\n", latestTimeline, timeline, record.lsn)
+		return nil
+	}
+	return newReplicaTimelineError("The standby timeline %d is not in the history of the archived timeline %d",
+		timeline, latestTimeline)
+}
+
+// getLatestArchivedTimeline probes the .history files upwards from the timeline, like Postgres does
+// for recovery_target_timeline = 'latest', and returns the last one found
+func getLatestArchivedTimeline(walFolder storage.Folder, timeline uint32) (uint32, error) {
+	extensions := walObjectExtensions()
+	for ; ; timeline++ {
+		_, exists, err := findWalObject(walFolder, fmt.Sprintf(walHistoryFileFormat, timeline+1), extensions)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to look up the .history files")
+		}
+		if !exists {
+			return timeline, nil
+		}
+	}
+}
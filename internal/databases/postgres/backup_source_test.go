@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+func putTestHistoryFile(t *testing.T, walFolder storage.Folder, timeline uint32, contents string) {
+	var compressedData bytes.Buffer
+	compressingWriter := lz4.Compressor{}.NewWriter(&compressedData)
+	_, err := compressingWriter.Write([]byte(contents))
+	assert.NoError(t, err)
+	assert.NoError(t, compressingWriter.Close())
+	historyFileName := fmt.Sprintf(walHistoryFileFormat+"."+lz4.FileExtension, timeline)
+	assert.NoError(t, walFolder.PutObject(historyFileName, &compressedData))
+}
+
+func TestCheckReplicaTimeline(t *testing.T) {
+	walFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	putTestHistoryFile(t, walFolder, 2, "1\t0/3000000\tno recovery target specified\n")
+	putTestHistoryFile(t, walFolder, 3,
+		"1\t0/3000000\tno recovery target specified\n2\t0/5000000\tno recovery target specified\n")
+
+	assert.NoError(t, checkReplicaTimeline(walFolder, 3, 0x6000028))
+	// the standby has not followed the switch to timeline 3 yet
+	assert.NoError(t, checkReplicaTimeline(walFolder, 2, 0x4000028))
+
+	err := checkReplicaTimeline(walFolder, 2, 0x5000028)
+	assert.IsType(t, replicaTimelineError{}, err)
+	err = checkReplicaTimeline(walFolder, 1, 0x4000028)
+	assert.IsType(t, replicaTimelineError{}, err)
+}
+
+func TestCheckReplicaTimeline_OtherBranch(t *testing.T) {
+	walFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	putTestHistoryFile(t, walFolder, 2, "1\t0/3000000\tno recovery target specified\n")
+	putTestHistoryFile(t, walFolder, 3, "1\t0/4000000\tno recovery target specified\n")
+
+	err := checkReplicaTimeline(walFolder, 2, 0x3000028)
+	assert.IsType(t, replicaTimelineError{}, err)
+}
+
+func TestCheckReplicaTimeline_HistoryGap(t *testing.T) {
+	walFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	putTestHistoryFile(t, walFolder, 2, "1\t0/3000000\tno recovery target specified\n")
+	// the histories of timelines 3 and 4 are not archived
+	putTestHistoryFile(t, walFolder, 5, "1\t0/3000000\tno recovery target specified\n"+
+		"2\t0/5000000\tno recovery target specified\n4\t0/7000000\tno recovery target specified\n")
+
+	// the probe stops at the first missing history file, like the one of Postgres
+	latestTimeline, err := getLatestArchivedTimeline(walFolder, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), latestTimeline)
+	latestTimeline, err = getLatestArchivedTimeline(walFolder, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5), latestTimeline)
+
+	err = checkReplicaTimeline(walFolder, 4, 0x8000028)
+	assert.IsType(t, replicaTimelineError{}, err)
+	assert.NoError(t, checkReplicaTimeline(walFolder, 5, 0x8000028))
+}
+
+func TestCheckReplayLag(t *testing.T) {
+	defer viper.Set(internal.ReplicaMaxReplayLagSetting, viper.GetString(internal.ReplicaMaxReplayLagSetting))
+
+	viper.Set(internal.ReplicaMaxReplayLagSetting, "10m")
+	isLagging, err := checkReplayLag(5 * time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, isLagging)
+	isLagging, err = checkReplayLag(10 * time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, isLagging)
+	isLagging, err = checkReplayLag(11 * time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, isLagging)
+
+	// the zero threshold turns the check off
+	viper.Set(internal.ReplicaMaxReplayLagSetting, "0s")
+	isLagging, err = checkReplayLag(24 * time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, isLagging)
+
+	viper.Set(internal.ReplicaMaxReplayLagSetting, "ten minutes")
+	_, err = checkReplayLag(time.Minute)
+	assert.Error(t, err)
+}
+
+func TestParseConninfoUpstream(t *testing.T) {
+	assert.Equal(t, "primary.example.com:5433",
+		parseConninfoUpstream("user=replicator password=******** host=primary.example.com port=5433 sslmode=prefer"))
+	assert.Equal(t, "10.0.0.1", parseConninfoUpstream("user=replicator hostaddr='10.0.0.1'"))
+	assert.Equal(t, "", parseConninfoUpstream(""))
+}
@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"golang.org/x/sync/semaphore"
+)
+
+// backupVerifier checks the tars of the backup against its sentinel
+type backupVerifier struct {
+	backup      Backup
+	sentinelDto BackupSentinelDto
+	manifest    internal.ChecksumManifest
+	result      *internal.BackupVerifyResult
+	verifyPages bool
+
+	mutex         sync.Mutex
+	foundFiles    map[string]bool
+	hasUnreadTars bool
+}
+
+// VerifyBackup downloads, decrypts and decompresses every tar of the backup without unpacking it.
+// It checks the tar structure, the file lists of the sentinel and the page checksums of the paged files.
+func VerifyBackup(backup Backup) *internal.BackupVerifyResult {
+	result := internal.NewBackupVerifyResult(backup.Name)
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		result.AddIssue(internal.BackupVerifyStatusFailure,
+			internal.SentinelNameFromBackup(backup.Name), "", err.Error())
+		return result
+	}
+	tarNames, err := backup.GetTarNames()
+	if err != nil {
+		result.AddIssue(internal.BackupVerifyStatusFailure, backup.Name, "", err.Error())
+		return result
+	}
+
+	verifier := &backupVerifier{
+		backup:      backup,
+		sentinelDto: sentinelDto,
+		manifest:    internal.CheckChecksumManifest(backup.Backup, result),
+		result:      result,
+		// increments of the old backups can't be told from the regular files
+		verifyPages: sentinelDto.Files != nil || !sentinelDto.IsIncremental(),
+		foundFiles:  make(map[string]bool),
+	}
+	if !verifier.verifyPages {
+		result.AddIssue(internal.BackupVerifyStatusWarning, backup.Name, "",
+			"backup sentinel has no file list, page checksums are not verified")
+	}
+
+	err = verifier.verifyTars(tarNames)
+	if err != nil {
+		result.AddIssue(internal.BackupVerifyStatusFailure, backup.Name, "", err.Error())
+		return result
+	}
+	verifier.checkTarFileSets(tarNames)
+	verifier.checkSentinelFiles()
+	return result
+}
+
+func (verifier *backupVerifier) verifyTars(tarNames []string) error {
+	concurrency, err := internal.GetMaxDownloadConcurrency()
+	if err != nil {
+		return err
+	}
+	downloadingContext := context.TODO()
+	downloadingSemaphore := semaphore.NewWeighted(int64(concurrency))
+	for _, tarName := range tarNames {
+		_ = downloadingSemaphore.Acquire(downloadingContext, 1)
+		go func(tarName string) {
+			defer downloadingSemaphore.Release(1)
+			verifier.verifyTar(tarName)
+		}(tarName)
+	}
+	return downloadingSemaphore.Acquire(downloadingContext, int64(concurrency))
+}
+
+func (verifier *backupVerifier) verifyTar(tarName string) {
+	tracelog.InfoLogger.Printf("Verifying %s\n", tarName)
+	files, err := verifier.readTar(tarName)
+	if err != nil {
+		verifier.result.AddIssue(internal.BackupVerifyStatusFailure, tarName, "", err.Error())
+		verifier.mutex.Lock()
+		verifier.hasUnreadTars = true
+		verifier.mutex.Unlock()
+		return
+	}
+	verifier.result.AddCheckedObject(len(files))
+
+	verifier.mutex.Lock()
+	for file := range files {
+		verifier.foundFiles[file] = true
+	}
+	verifier.mutex.Unlock()
+
+	expectedFiles, ok := verifier.sentinelDto.TarFileSets[tarName]
+	if !ok {
+		return
+	}
+	for _, file := range expectedFiles {
+		if !files[file] {
+			verifier.result.AddIssue(internal.BackupVerifyStatusFailure, tarName, file,
+				"file listed in the sentinel tar file sets is missing from the tar")
+		}
+	}
+}
+
+// readTar reads the whole tar and returns the names of the files found in it
+func (verifier *backupVerifier) readTar(tarName string) (map[string]bool, error) {
+	readerMaker := verifier.backup.newTarReaderMaker(tarName, verifier.manifest)
+	extractingReader, pipeWriter := io.Pipe()
+	decompressionErrors := make(chan error, 1)
+	go func() {
+		err := internal.DecryptAndDecompressTar(&internal.EmptyWriteIgnorer{WriteCloser: pipeWriter},
+			readerMaker, internal.ConfigureCrypter())
+		_ = pipeWriter.CloseWithError(err)
+		decompressionErrors <- err
+	}()
+
+	files, err := verifier.readTarFiles(tarName, extractingReader)
+	// read the padding after the end of the archive, so the checksum of the tar is verified
+	_, _ = io.Copy(ioutil.Discard, extractingReader)
+	if decompressionErr := <-decompressionErrors; decompressionErr != nil {
+		return nil, decompressionErr
+	}
+	return files, err
+}
+
+func (verifier *backupVerifier) readTarFiles(tarName string, extractingReader io.Reader) (map[string]bool, error) {
+	files := make(map[string]bool)
+	tarReader := tar.NewReader(extractingReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid tar structure")
+		}
+		files[header.Name] = true
+
+		if _, listed := verifier.sentinelDto.Files[header.Name]; verifier.sentinelDto.Files != nil &&
+			!listed && !UtilityFilePaths[header.Name] {
+			verifier.result.AddIssue(internal.BackupVerifyStatusWarning, tarName, header.Name,
+				"file is not listed in the backup sentinel")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = verifier.verifyFile(tarName, header, tarReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read '%s'", header.Name)
+		}
+	}
+}
+
+func (verifier *backupVerifier) verifyFile(tarName string, header *tar.Header, fileReader io.Reader) error {
+	if !verifier.verifyPages {
+		_, err := io.Copy(ioutil.Discard, fileReader)
+		return err
+	}
+	var corruptBlocks []uint32
+	var err error
+	// the tar header holds the increment size, so the increments are not checked with isPagedFile,
+	// only the paged files are incremented
+	if verifier.sentinelDto.Files[header.Name].IsIncremented {
+		corruptBlocks, err = verifyIncrementPages(header.Name, fileReader)
+	} else {
+		corruptBlocks, err = verifyFile(header.Name, header.FileInfo(), fileReader, false)
+	}
+	if err != nil {
+		return err
+	}
+	if len(corruptBlocks) > 0 {
+		description := internal.BackupFileDescription{}
+		description.SetCorruptBlocks(corruptBlocks, false)
+		verifier.result.AddIssue(internal.BackupVerifyStatusFailure, tarName, header.Name,
+			fmt.Sprintf("%d pages with invalid checksums, some of them: %v",
+				description.CorruptBlocks.CorruptBlocksCount, description.CorruptBlocks.SomeCorruptBlocks))
+	}
+	return nil
+}
+
+// checkTarFileSets checks that the tars listed in the sentinel are stored and vice versa
+func (verifier *backupVerifier) checkTarFileSets(tarNames []string) {
+	if len(verifier.sentinelDto.TarFileSets) == 0 {
+		return
+	}
+	storedTars := make(map[string]bool, len(tarNames))
+	for _, tarName := range tarNames {
+		storedTars[tarName] = true
+		_, listed := verifier.sentinelDto.TarFileSets[tarName]
+		if !listed && !pgControlTarRegexp.MatchString(tarName) {
+			verifier.result.AddIssue(internal.BackupVerifyStatusWarning, tarName, "",
+				"tar is not listed in the backup sentinel")
+		}
+	}
+	for tarName := range verifier.sentinelDto.TarFileSets {
+		if !storedTars[tarName] {
+			verifier.result.AddIssue(internal.BackupVerifyStatusFailure, tarName, "",
+				"tar listed in the backup sentinel is missing from storage")
+		}
+	}
+}
+
+// checkSentinelFiles checks that every file of the sentinel, except the ones skipped in the increment, is stored.
+// The files of the unread tars are unknown, so the check is skipped if some tar can't be read.
+func (verifier *backupVerifier) checkSentinelFiles() {
+	if verifier.hasUnreadTars {
+		return
+	}
+	for file, description := range verifier.sentinelDto.Files {
+		if description.IsSkipped || verifier.foundFiles[file] {
+			continue
+		}
+		verifier.result.AddIssue(internal.BackupVerifyStatusFailure, verifier.backup.Name, file,
+			"file listed in the backup sentinel is missing from the backup tars")
+	}
+}
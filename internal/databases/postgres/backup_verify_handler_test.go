@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const testVerifyBackup = "base_000000010000000000000004_D_000000010000000000000002"
+
+func testVerifySentinel() BackupSentinelDto {
+	startLSN := uint64(0x4000000)
+	incrementFromLSN := uint64(0x2000000)
+	incrementFrom := testMergeFullBackup
+	incrementCount := 1
+	return BackupSentinelDto{
+		BackupStartLSN:    &startLSN,
+		IncrementFromLSN:  &incrementFromLSN,
+		IncrementFrom:     &incrementFrom,
+		IncrementFullName: &incrementFrom,
+		IncrementCount:    &incrementCount,
+		Files: internal.BackupFileList{
+			"/PG_VERSION":  {},
+			"/base/1/1234": {},
+			"/base/1/1235": {IsIncremented: true},
+		},
+		TarFileSets: TarFileSets{"part_1.tar": {"/PG_VERSION", "/base/1/1234", "/base/1/1235"}},
+	}
+}
+
+func testVerifyTarFiles() []testTarFile {
+	return []testTarFile{
+		{"/PG_VERSION", []byte("13")},
+		{"/base/1/1234", testPages(0, 0)},
+		{"/base/1/1235", testIncrement(uint64(2*DatabasePageSize), []uint32{1}, testPages(0))},
+	}
+}
+
+// putTestVerifyBackup stores the backup and the checksum manifest of its tars
+func putTestVerifyBackup(t *testing.T, sentinelDto BackupSentinelDto, files []testTarFile) storage.Folder {
+	baseBackupFolder := memory.NewFolder("", memory.NewStorage()).GetSubFolder("basebackups_005/")
+	putTestTar(t, baseBackupFolder, testVerifyBackup, "part_1.tar", files)
+	putTestSentinel(t, baseBackupFolder, testVerifyBackup, sentinelDto)
+
+	tarPath := testVerifyBackup + internal.TarPartitionFolderName + "part_1.tar"
+	reader, err := baseBackupFolder.ReadObject(tarPath)
+	assert.NoError(t, err)
+	tar, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	checksum := sha256.Sum256(tar)
+	putTestChecksumManifest(t, baseBackupFolder, map[string]string{tarPath: hex.EncodeToString(checksum[:])})
+	return baseBackupFolder
+}
+
+func putTestChecksumManifest(t *testing.T, baseBackupFolder storage.Folder, objects map[string]string) {
+	manifest, err := json.Marshal(internal.NewChecksumManifest(objects))
+	assert.NoError(t, err)
+	err = baseBackupFolder.PutObject(internal.ChecksumManifestNameFromBackup(testVerifyBackup), bytes.NewReader(manifest))
+	assert.NoError(t, err)
+}
+
+func assertHasVerifyFailure(t *testing.T, result *internal.BackupVerifyResult, object, file string) {
+	assert.Equal(t, internal.BackupVerifyStatusFailure, result.Status)
+	for _, issue := range result.Issues {
+		if issue.Status == internal.BackupVerifyStatusFailure && issue.Object == object && issue.File == file {
+			return
+		}
+	}
+	t.Errorf("no failure of %s %s in %v", object, file, result.Issues)
+}
+
+func TestVerifyBackup(t *testing.T) {
+	folder := putTestVerifyBackup(t, testVerifySentinel(), testVerifyTarFiles())
+
+	result := VerifyBackup(NewBackup(folder, testVerifyBackup))
+	assert.Equal(t, internal.BackupVerifyStatusOk, result.Status, result.Issues)
+	assert.Equal(t, 1, result.CheckedObjects)
+	assert.Equal(t, 3, result.CheckedFiles)
+}
+
+func TestVerifyBackup_MissingTar(t *testing.T) {
+	sentinelDto := testVerifySentinel()
+	sentinelDto.TarFileSets["part_2.tar"] = []string{"/base/1/1236"}
+	folder := putTestVerifyBackup(t, sentinelDto, testVerifyTarFiles())
+
+	result := VerifyBackup(NewBackup(folder, testVerifyBackup))
+	assertHasVerifyFailure(t, result, "part_2.tar", "")
+}
+
+func TestVerifyBackup_ChecksumMismatch(t *testing.T) {
+	folder := putTestVerifyBackup(t, testVerifySentinel(), testVerifyTarFiles())
+	tarPath := testVerifyBackup + internal.TarPartitionFolderName + "part_1.tar"
+	checksum := sha256.Sum256([]byte("another tar"))
+	putTestChecksumManifest(t, folder, map[string]string{tarPath: hex.EncodeToString(checksum[:])})
+
+	result := VerifyBackup(NewBackup(folder, testVerifyBackup))
+	assertHasVerifyFailure(t, result, "part_1.tar", "")
+}
+
+func TestVerifyBackup_FileMissingFromTar(t *testing.T) {
+	files := testVerifyTarFiles()
+	folder := putTestVerifyBackup(t, testVerifySentinel(), files[:2])
+
+	result := VerifyBackup(NewBackup(folder, testVerifyBackup))
+	assertHasVerifyFailure(t, result, "part_1.tar", "/base/1/1235")
+	assertHasVerifyFailure(t, result, testVerifyBackup, "/base/1/1235")
+}
+
+func TestVerifyBackup_CorruptIncrement(t *testing.T) {
+	files := testVerifyTarFiles()
+	// the increment is cut in the middle of its block map
+	files[2].content = files[2].content[:len(IncrementFileHeader)+18]
+	folder := putTestVerifyBackup(t, testVerifySentinel(), files)
+
+	result := VerifyBackup(NewBackup(folder, testVerifyBackup))
+	assertHasVerifyFailure(t, result, "part_1.tar", "")
+}
+
+func TestVerifyBackup_CorruptPage(t *testing.T) {
+	files := testVerifyTarFiles()
+	// the page is not new and its checksum doesn't match
+	files[1].content = testPages(0, 1)
+	folder := putTestVerifyBackup(t, testVerifySentinel(), files)
+
+	result := VerifyBackup(NewBackup(folder, testVerifyBackup))
+	assertHasVerifyFailure(t, result, "part_1.tar", "/base/1/1234")
+}
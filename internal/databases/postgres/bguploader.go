@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -219,6 +220,11 @@ func (b *BgUploader) upload(walStatusFilename string) bool {
 		tracelog.ErrorLogger.Print("Error of background uploader: ", err)
 		return false
 	}
+	metrics.UploadedWalSegments.Inc()
+
+	if err := saveWalChecksum(walFilename, b.uploader.Uploader); err != nil {
+		tracelog.ErrorLogger.Printf("Error saving checksum of wal file %s: %v", walFilename, err)
+	}
 
 	if err := b.uploader.ArchiveStatusManager.MarkWalUploaded(walFilename); err != nil {
 		tracelog.ErrorLogger.Printf("Error marking wal file %s as uploaded: %v", walFilename, err)
@@ -76,6 +76,8 @@ type Bundle struct {
 	DeltaMap           PagedFileDeltaMap
 	TablespaceSpec     TablespaceSpec
 
+	deltaMapStats    *deltaMapStatistics
+	pushState        *backupPushState
 	forceIncremental bool
 	TarSizeThreshold int64
 }
@@ -293,6 +295,12 @@ func (bundle *Bundle) addToBundle(path string, info os.FileInfo) error {
 		}
 		incrementBaseLsn := bundle.getIncrementBaseLsn()
 		isIncremented := incrementBaseLsn != nil && (wasInBase || bundle.forceIncremental) && isPagedFile(info, path)
+		if reusedFile, ok := bundle.pushState.getReusedFile(fileInfoHeader.Name); ok {
+			// File is already uploaded by the interrupted backup-push
+			tracelog.DebugLogger.Println("Skipped due to reused part: " + path)
+			bundle.TarBallComposer.GetFiles().AddFile(fileInfoHeader, info, reusedFile.IsIncremented)
+			return nil
+		}
 		bundle.TarBallComposer.AddFile(NewComposeFileInfo(path, info, wasInBase, isIncremented, fileInfoHeader))
 	} else {
 		err := bundle.TarBallComposer.AddHeader(fileInfoHeader, info)
@@ -423,11 +431,19 @@ func (bundle *Bundle) getDeltaBitmapFor(filePath string) (*roaring.Bitmap, error
 }
 
 func (bundle *Bundle) DownloadDeltaMap(folder storage.Folder, backupStartLSN uint64) error {
-	deltaMap, err := getDeltaMap(folder, bundle.Timeline, *bundle.IncrementFromLsn, backupStartLSN)
+	return bundle.DownloadDeltaMapWithLocalWals(folder, "", backupStartLSN)
+}
+
+// DownloadDeltaMapWithLocalWals reads the WAL segments not archived yet from the localWalDirectory,
+// so the delta map covers the WAL up to the backup start without waiting for the archive
+func (bundle *Bundle) DownloadDeltaMapWithLocalWals(folder storage.Folder, localWalDirectory string,
+	backupStartLSN uint64) error {
+	deltaMap, err := getDeltaMap(folder, localWalDirectory, bundle.Timeline, *bundle.IncrementFromLsn, backupStartLSN)
 	if err != nil {
 		return err
 	}
 	bundle.DeltaMap = deltaMap
+	bundle.deltaMapStats = &deltaMapStatistics{}
 	return nil
 }
 
@@ -28,17 +28,20 @@ type RegularBundleFiles struct {
 
 func (files *RegularBundleFiles) AddSkippedFile(tarHeader *tar.Header, fileInfo os.FileInfo) {
 	files.Store(tarHeader.Name,
-		internal.BackupFileDescription{IsSkipped: true, IsIncremented: false, MTime: fileInfo.ModTime()})
+		internal.BackupFileDescription{IsSkipped: true, IsIncremented: false, MTime: fileInfo.ModTime(),
+			Size: fileInfo.Size()})
 }
 
 func (files *RegularBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool) {
 	files.Store(tarHeader.Name,
-		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()})
+		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime(),
+			Size: fileInfo.Size()})
 }
 
 func (files *RegularBundleFiles) AddFileWithCorruptBlocks(tarHeader *tar.Header, fileInfo os.FileInfo,
 	isIncremented bool, corruptedBlocks []uint32, storeAllBlocks bool) {
-	fileDescription := internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()}
+	fileDescription := internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime(),
+		Size: fileInfo.Size()}
 	fileDescription.SetCorruptBlocks(corruptedBlocks, storeAllBlocks)
 	files.Store(tarHeader.Name, fileDescription)
 }
@@ -65,7 +68,7 @@ func (files *StatBundleFiles) AddFileWithCorruptBlocks(tarHeader *tar.Header,
 	storeAllBlocks bool) {
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
 	fileDescription := internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime(),
-		UpdatesCount: updatesCount}
+		UpdatesCount: updatesCount, Size: fileInfo.Size()}
 	fileDescription.SetCorruptBlocks(corruptedBlocks, storeAllBlocks)
 	files.Store(tarHeader.Name, fileDescription)
 }
@@ -74,14 +77,14 @@ func (files *StatBundleFiles) AddSkippedFile(tarHeader *tar.Header, fileInfo os.
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
 	files.Store(tarHeader.Name,
 		internal.BackupFileDescription{IsSkipped: true, IsIncremented: false,
-			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount})
+			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount, Size: fileInfo.Size()})
 }
 
 func (files *StatBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool) {
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
 	files.Store(tarHeader.Name,
 		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented,
-			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount})
+			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount, Size: fileInfo.Size()})
 }
 
 func (files *StatBundleFiles) GetUnderlyingMap() *sync.Map {
@@ -2,6 +2,9 @@ package postgres_test
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -240,3 +243,28 @@ func TestLoadDeltaMap_WalTail(t *testing.T) {
 	assert.Equal(t, []uint32{4, 9}, bundle.DeltaMap[BundleTestLocations[0].RelationFileNode].ToArray())
 	assert.Equal(t, []uint32{8}, bundle.DeltaMap[BundleTestLocations[1].RelationFileNode].ToArray())
 }
+
+func TestLoadDeltaMap_LocalWalTail(t *testing.T) {
+	folder, bundle, err := setupFolderAndBundle()
+	assert.NoError(t, err)
+	// the tail segment is not archived yet
+	assert.NoError(t, folder.DeleteObjects([]string{"000000010000000000000090.lz4"}))
+	localWalDirectory, err := ioutil.TempDir("", "pg_wal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(localWalDirectory)
+	err = ioutil.WriteFile(filepath.Join(localWalDirectory, "000000010000000000000090"),
+		testtools.CreateWalPageWithContinuation(), 0600)
+	assert.NoError(t, err)
+
+	backupNextWalFilename := "000000010000000000000091"
+	_, curLogSegNo, _ := postgres.ParseWALFilename(backupNextWalFilename)
+
+	err = bundle.DownloadDeltaMap(folder, curLogSegNo*postgres.WalSegmentSize)
+	assert.Error(t, err)
+
+	err = bundle.DownloadDeltaMapWithLocalWals(folder, localWalDirectory, curLogSegNo*postgres.WalSegmentSize)
+	assert.NoError(t, err)
+	assert.NotNil(t, bundle.DeltaMap)
+	assert.Equal(t, []uint32{4, 9}, bundle.DeltaMap[BundleTestLocations[0].RelationFileNode].ToArray())
+	assert.Equal(t, []uint32{8}, bundle.DeltaMap[BundleTestLocations[1].RelationFileNode].ToArray())
+}
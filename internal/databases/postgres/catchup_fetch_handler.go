@@ -25,7 +25,7 @@ func HandleCatchupFetch(folder storage.Folder, dbDirectory, backupName string, u
 	if useNewUnwrap {
 		_, err = pgBackup.unwrapNew(dbDirectory, sentinelDto, filesToUnwrap, true, false)
 	} else {
-		err = pgBackup.unwrapOld(dbDirectory, sentinelDto, filesToUnwrap, true)
+		err = pgBackup.unwrapOld(dbDirectory, sentinelDto, filesToUnwrap, true, nil)
 	}
 
 	tracelog.ErrorLogger.FatalfOnError("Failed unwrap backup: %v", err)
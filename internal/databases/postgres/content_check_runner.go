@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/semaphore"
+)
+
+type WalSegmentContentError struct {
+	error
+}
+
+func newWalSegmentContentError(format string, args ...interface{}) WalSegmentContentError {
+	return WalSegmentContentError{errors.Errorf(format, args...)}
+}
+
+func (err WalSegmentContentError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type ContentCheckDetails struct {
+	CheckedSegmentsCount int                  `json:"checked_segments_count"`
+	CorruptSegments      []*CorruptWalSegment `json:"corrupt_segments"`
+}
+
+// CorruptWalSegment is the WAL segment which content failed the check
+type CorruptWalSegment struct {
+	SegmentName string `json:"segment_name"`
+	Error       string `json:"error"`
+}
+
+func (details ContentCheckDetails) NewPlainTextReader() (io.Reader, error) {
+	var outputBuffer bytes.Buffer
+
+	outputBuffer.WriteString(fmt.Sprintf("Checked WAL segments: %d\n", details.CheckedSegmentsCount))
+	if len(details.CorruptSegments) == 0 {
+		return &outputBuffer, nil
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(&outputBuffer)
+	defer tableWriter.Render()
+
+	tableWriter.AppendHeader(table.Row{"Segment", "Error"})
+	for _, segment := range details.CorruptSegments {
+		tableWriter.AppendRow(table.Row{segment.SegmentName, segment.Error})
+	}
+
+	return &outputBuffer, nil
+}
+
+// ContentCheckRunner downloads the WAL segments in storage starting from the earliest backup
+// and parses them to find the segments with the invalid page headers or the records with the wrong CRC
+type ContentCheckRunner struct {
+	walFolder           storage.Folder
+	currentWalSegment   WalSegmentDescription
+	stopWalSegmentNo    WalSegmentNo
+	downloadConcurrency int
+	walFolderFilenames  []string
+}
+
+func NewContentCheckRunner(
+	rootFolder storage.Folder,
+	walFolderFilenames []string,
+	currentWalSegment WalSegmentDescription,
+) (ContentCheckRunner, error) {
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+
+	timelineSwitchMap, err := createTimelineSwitchMap(currentWalSegment.Timeline, walFolder)
+	if err != nil {
+		return ContentCheckRunner{}, errors.Wrap(err, "Failed to initialize timeline history map")
+	}
+
+	stopWalSegmentNo, err := getEarliestBackupStartSegmentNo(timelineSwitchMap, currentWalSegment.Timeline, rootFolder)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to detect earliest backup WAL segment no: '%v',"+
+			"will check all WAL segments in storage.\n", err)
+		stopWalSegmentNo = 1
+	}
+
+	downloadConcurrency, err := internal.GetMaxDownloadConcurrency()
+	if err != nil {
+		return ContentCheckRunner{}, errors.Wrap(err, "Failed to resolve MaxDownloadConcurrency")
+	}
+
+	return ContentCheckRunner{
+		walFolder:           walFolder,
+		currentWalSegment:   currentWalSegment,
+		stopWalSegmentNo:    stopWalSegmentNo,
+		downloadConcurrency: downloadConcurrency,
+		walFolderFilenames:  walFolderFilenames,
+	}, nil
+}
+
+func (check ContentCheckRunner) Run() (WalVerifyCheckResult, error) {
+	segments := check.getSegmentsToCheck()
+	details := ContentCheckDetails{CorruptSegments: make([]*CorruptWalSegment, 0)}
+	var mutex sync.Mutex
+	var firstErr error
+
+	downloadingContext := context.TODO()
+	downloadingSemaphore := semaphore.NewWeighted(int64(check.downloadConcurrency))
+	for _, segment := range segments {
+		_ = downloadingSemaphore.Acquire(downloadingContext, 1)
+		go func(segment WalSegmentDescription) {
+			defer downloadingSemaphore.Release(1)
+			isChecked, contentErr, err := check.checkSegment(segment)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if isChecked {
+				details.CheckedSegmentsCount++
+			}
+			if contentErr != nil {
+				details.CorruptSegments = append(details.CorruptSegments, &CorruptWalSegment{
+					SegmentName: segment.Number.getFilename(segment.Timeline),
+					Error:       contentErr.Error(),
+				})
+			}
+		}(segment)
+	}
+	_ = downloadingSemaphore.Acquire(downloadingContext, int64(check.downloadConcurrency))
+	if firstErr != nil {
+		return WalVerifyCheckResult{}, firstErr
+	}
+
+	sort.Slice(details.CorruptSegments, func(i, j int) bool {
+		return details.CorruptSegments[i].SegmentName < details.CorruptSegments[j].SegmentName
+	})
+	return newContentCheckResult(details), nil
+}
+
+func (check ContentCheckRunner) Type() WalVerifyCheckType {
+	return WalVerifyContentCheck
+}
+
+// getSegmentsToCheck returns the segments in storage from the earliest backup up to the current segment
+func (check ContentCheckRunner) getSegmentsToCheck() []WalSegmentDescription {
+	segments := make([]WalSegmentDescription, 0)
+	for segment := range getSegmentsFromFiles(check.walFolderFilenames) {
+		if segment.Number < check.stopWalSegmentNo || segment.Number > check.currentWalSegment.Number ||
+			segment.Timeline > check.currentWalSegment.Timeline {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].Number != segments[j].Number {
+			return segments[i].Number < segments[j].Number
+		}
+		return segments[i].Timeline < segments[j].Timeline
+	})
+	return segments
+}
+
+// checkSegment downloads the segment and verifies its content. The storage errors fail the check,
+// while the reading errors are reported as the segment content errors, since they mean the broken compressed
+// or encrypted data as well.
+func (check ContentCheckRunner) checkSegment(segment WalSegmentDescription) (isChecked bool, contentErr, err error) {
+	segmentName := segment.Number.getFilename(segment.Timeline)
+	tracelog.DebugLogger.Printf("Checking the content of WAL segment %s\n", segmentName)
+	reader, err := internal.DownloadAndDecompressStorageFile(check.walFolder, segmentName)
+	if _, ok := err.(internal.ArchiveNonExistenceError); ok {
+		tracelog.WarningLogger.Printf("WAL segment %s is not found in storage, skipping it\n", segmentName)
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "failed to download WAL segment %s", segmentName)
+	}
+	defer utility.LoggedClose(reader, "")
+
+	contentErr = verifyWalSegmentContent(reader, segment)
+	if contentErr != nil {
+		tracelog.WarningLogger.Printf("WAL segment %s is corrupt: %v\n", segmentName, contentErr)
+	}
+	return true, contentErr, nil
+}
+
+// verifyWalSegmentContent reads the segment page by page and checks that:
+// 1. the first page has the long header and the other pages have the same magic
+// 2. the page addresses are continuous and match the segment number
+// 3. the records parsed by walparser have the valid CRC
+// 4. only the zero pages follow the first zero page
+func verifyWalSegmentContent(reader io.Reader, segment WalSegmentDescription) error {
+	pageReader := walparser.NewWalPageReader(reader)
+	parser := walparser.NewWalParserWithCrcCheck()
+	var magic uint16
+	hasZeroPage := false
+	for pageOffset := uint64(0); pageOffset < WalSegmentSize; pageOffset += uint64(walparser.WalPageSize) {
+		page, err := pageReader.ReadPageData()
+		if err == io.EOF || errors.Cause(err) == io.ErrUnexpectedEOF {
+			return newWalSegmentContentError("the segment is truncated at offset %X", pageOffset)
+		}
+		if err != nil {
+			return err
+		}
+
+		pageHeader, err := walparser.ReadXLogPageHeader(bytes.NewReader(page))
+		if _, ok := err.(walparser.ZeroPageHeaderError); ok && pageOffset > 0 {
+			if bytes.Count(page, []byte{0}) != len(page) {
+				return newWalSegmentContentError("the page at offset %X has the zero header, but is not zero", pageOffset)
+			}
+			hasZeroPage = true
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the page header at offset %X", pageOffset)
+		}
+		if hasZeroPage {
+			return newWalSegmentContentError("the page at offset %X follows the zero page", pageOffset)
+		}
+		err = checkWalPageHeader(pageHeader, segment, pageOffset, magic)
+		if err != nil {
+			return err
+		}
+		magic = pageHeader.Magic
+
+		_, _, err = parser.ParseRecordsFromPage(bytes.NewReader(page))
+		if _, ok := err.(walparser.PartialPageError); ok {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the records on the page at offset %X", pageOffset)
+		}
+	}
+	return nil
+}
+
+func checkWalPageHeader(pageHeader *walparser.XLogPageHeader,
+	segment WalSegmentDescription, pageOffset uint64, magic uint16) error {
+	if pageOffset == 0 && !pageHeader.IsLong() {
+		return newWalSegmentContentError("the first page has no long header")
+	}
+	if pageOffset > 0 && pageHeader.Magic != magic {
+		return newWalSegmentContentError("the page at offset %X has magic %04X, while the first page has %04X",
+			pageOffset, pageHeader.Magic, magic)
+	}
+	expectedPageAddress := walparser.XLogRecordPtr(segment.Number.firstLsn() + pageOffset)
+	if pageHeader.PageAddress != expectedPageAddress {
+		return newWalSegmentContentError("the page at offset %X has address %X, expected %X",
+			pageOffset, pageHeader.PageAddress, expectedPageAddress)
+	}
+	if uint32(pageHeader.TimeLineID) > segment.Timeline {
+		return newWalSegmentContentError("the page at offset %X has timeline %d, which is ahead of the segment",
+			pageOffset, pageHeader.TimeLineID)
+	}
+	return nil
+}
+
+// newContentCheckResult check produces the WalVerifyCheckResult with status:
+// StatusOk if all checked segments are valid
+// StatusWarning if there are no segments to check
+// StatusFailure if some segments are corrupt
+func newContentCheckResult(details ContentCheckDetails) WalVerifyCheckResult {
+	result := WalVerifyCheckResult{
+		Status:  StatusOk,
+		Details: details,
+	}
+	switch {
+	case len(details.CorruptSegments) > 0:
+		result.Status = StatusFailure
+	case details.CheckedSegmentsCount == 0:
+		result.Status = StatusWarning
+	}
+	return result
+}
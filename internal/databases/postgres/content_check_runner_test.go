@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/walparser"
+)
+
+// makeTestWalSegment makes the segment of the pages with no records
+func makeTestWalSegment(segment WalSegmentDescription) []byte {
+	segmentData := make([]byte, WalSegmentSize)
+	for pageOffset := uint64(0); pageOffset < WalSegmentSize; pageOffset += uint64(walparser.WalPageSize) {
+		page := segmentData[pageOffset:]
+		binary.LittleEndian.PutUint16(page[0:], 0xD101)
+		if pageOffset == 0 {
+			binary.LittleEndian.PutUint16(page[2:], walparser.XlpLongHeader)
+		}
+		binary.LittleEndian.PutUint32(page[4:], segment.Timeline)
+		binary.LittleEndian.PutUint64(page[8:], segment.Number.firstLsn()+pageOffset)
+	}
+	return segmentData
+}
+
+func TestVerifyWalSegmentContent(t *testing.T) {
+	segment := WalSegmentDescription{Timeline: 2, Number: 0x12}
+	segmentData := makeTestWalSegment(segment)
+	assert.NoError(t, verifyWalSegmentContent(bytes.NewReader(segmentData), segment))
+
+	err := verifyWalSegmentContent(bytes.NewReader(segmentData), WalSegmentDescription{Timeline: 2, Number: 0x13})
+	assert.IsType(t, WalSegmentContentError{}, err)
+	err = verifyWalSegmentContent(bytes.NewReader(segmentData), WalSegmentDescription{Timeline: 1, Number: 0x12})
+	assert.IsType(t, WalSegmentContentError{}, err)
+	err = verifyWalSegmentContent(bytes.NewReader(segmentData[:WalSegmentSize/2]), segment)
+	assert.IsType(t, WalSegmentContentError{}, err)
+}
+
+func TestVerifyWalSegmentContent_ZeroTail(t *testing.T) {
+	segment := WalSegmentDescription{Timeline: 1, Number: 0x12}
+	segmentData := makeTestWalSegment(segment)
+	// the pages following the WAL switch record are zeroed
+	copy(segmentData[WalSegmentSize/2:], make([]byte, WalSegmentSize/2))
+	assert.NoError(t, verifyWalSegmentContent(bytes.NewReader(segmentData), segment))
+
+	segmentData[WalSegmentSize-1] = 1
+	err := verifyWalSegmentContent(bytes.NewReader(segmentData), segment)
+	assert.IsType(t, WalSegmentContentError{}, err)
+}
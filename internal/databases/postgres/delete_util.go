@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"strings"
+
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -44,6 +46,9 @@ func GetPermanentBackupsAndWals(folder storage.Folder) (map[string]bool, map[str
 }
 
 func IsPermanent(objectName string, permanentBackups, permanentWals map[string]bool) bool {
+	if strings.HasPrefix(objectName, utility.WalPath+WalIndexPath) {
+		return isPermanentWalIndexPage(objectName[len(utility.WalPath+WalIndexPath):], permanentWals)
+	}
 	if objectName[:len(utility.WalPath)] == utility.WalPath {
 		wal := objectName[len(utility.WalPath) : len(utility.WalPath)+24]
 		return permanentWals[wal]
@@ -55,3 +60,17 @@ func IsPermanent(objectName string, permanentBackups, permanentWals map[string]b
 	// should not reach here, default to false
 	return false
 }
+
+// isPermanentWalIndexPage keeps the WAL index page if any of its segments is permanent
+func isPermanentWalIndexPage(pageName string, permanentWals map[string]bool) bool {
+	timelineID, lastSegmentNo, err := ParseWALFilename(utility.TrimFileExtension(pageName))
+	if err != nil {
+		return false
+	}
+	for segmentNo := lastSegmentNo + 1 - xLogSegmentsPerXLogID; segmentNo <= lastSegmentNo; segmentNo++ {
+		if permanentWals[WalSegmentNo(segmentNo).getFilename(timelineID)] {
+			return true
+		}
+	}
+	return false
+}
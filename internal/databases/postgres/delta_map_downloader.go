@@ -4,9 +4,15 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/walparser"
 )
 
+// getDeltaMap builds the delta map from the delta files and the WAL segments after the last complete delta file.
+// The WAL segments of the missing delta files are read instead of them. The segments not archived yet
+// are read from the localWalDirectory, if it is set. There is no delta map if a WAL segment is missing everywhere.
 func getDeltaMap(folder storage.Folder,
+	localWalDirectory string,
 	timeline uint32,
 	firstUsedLSN,
 	firstNotUsedLSN uint64) (PagedFileDeltaMap, error) {
@@ -18,29 +24,53 @@ func getDeltaMap(folder storage.Folder,
 		newWalSegmentNo(firstNotUsedLSN).getFilename(timeline))
 	deltaMap := NewPagedFileDeltaMap()
 	firstUsedDeltaNo, firstNotUsedDeltaNo := getDeltaRange(firstUsedLSN, firstNotUsedLSN)
-	// Get locations from [firstUsedDeltaNo, lastUsedDeltaNo). We use lastUsedDeltaNo in next step
-	err := deltaMap.getLocationsFromDeltas(folder, timeline, firstUsedDeltaNo, firstNotUsedDeltaNo.previous())
+	walParser, err := deltaMap.getLocationsFromDeltas(folder, localWalDirectory, timeline,
+		firstUsedDeltaNo, firstNotUsedDeltaNo, firstNotUsedLSN)
 	if err != nil {
 		return deltaMap, errors.Wrapf(err, "Error during fetch locations from delta files.\n")
 	}
 
-	// Handle last delta file separately for fetch locations and walParser from it
-	lastDeltaFile, err := getDeltaFile(folder, firstNotUsedDeltaNo.previous().getFilename(timeline))
-	if err != nil {
-		return deltaMap, errors.Wrapf(err, "Error during downloading last delta file.\n")
-	}
-	deltaMap.AddLocationsToDelta(lastDeltaFile.Locations)
-
 	firstUsedWalSegmentNo, firstNotUsedWalSegmentNo := getWalSegmentRange(firstNotUsedDeltaNo, firstNotUsedLSN)
 	// we handle WAL files from [firstUsedWalSegmentNo, lastUsedWalSegmentNo]
-	err = deltaMap.getLocationsFromWals(folder, timeline, firstUsedWalSegmentNo,
-		firstNotUsedWalSegmentNo, lastDeltaFile.WalParser)
+	err = deltaMap.getLocationsFromWals(folder, localWalDirectory, timeline, firstUsedWalSegmentNo,
+		firstNotUsedWalSegmentNo, firstNotUsedLSN, walParser)
 	if err != nil {
 		return deltaMap, errors.Wrapf(err, "Error during fetch locations from wal segments.\n")
 	}
 	return deltaMap, nil
 }
 
+// getLocationsFromDeltas reads the delta files [first, last) and returns the WAL parser to continue with the segments
+// after them. The WAL segments of a missing delta file are read with the parser of the previous delta file.
+func (deltaMap *PagedFileDeltaMap) getLocationsFromDeltas(folder storage.Folder,
+	localWalDirectory string,
+	timeline uint32,
+	first,
+	last DeltaNo,
+	firstNotUsedLSN uint64) (*walparser.WalParser, error) {
+	walParser := walparser.NewWalParser()
+	for deltaNo := first; deltaNo < last; deltaNo = deltaNo.next() {
+		filename := deltaNo.getFilename(timeline)
+		deltaFile, err := getDeltaFile(folder, filename)
+		if _, ok := errors.Cause(err).(internal.ArchiveNonExistenceError); ok {
+			tracelog.WarningLogger.Printf("Delta file %s is missing, reading its wal segments\n", filename)
+			err = deltaMap.getLocationsFromWals(folder, localWalDirectory, timeline, deltaNo.firstWalSegmentNo(),
+				deltaNo.next().firstWalSegmentNo(), firstNotUsedLSN, walParser)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tracelog.InfoLogger.Printf("Successfully downloaded delta file %s\n", filename)
+		deltaMap.AddLocationsToDelta(deltaFile.Locations)
+		walParser = deltaFile.WalParser
+	}
+	return walParser, nil
+}
+
 func getDeltaRange(firstUsedLsn, firstNotUsedLsn uint64) (DeltaNo, DeltaNo) {
 	firstUsedDeltaNo := newDeltaNoFromLsn(firstUsedLsn)
 	firstNotUsedDeltaNo := newDeltaNoFromLsn(firstNotUsedLsn)
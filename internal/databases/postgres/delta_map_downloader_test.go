@@ -1,7 +1,14 @@
 package postgres
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/walparser"
 )
 
 func TestGetDeltaRange(t *testing.T) {
@@ -133,3 +140,37 @@ func TestGetWalSegmentRange(t *testing.T) {
 		})
 	}
 }
+
+func putCompressedObject(t *testing.T, folder storage.Folder, name string, data []byte) {
+	var compressedData bytes.Buffer
+	writer := lz4.Compressor{}.NewWriter(&compressedData)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, folder.PutObject(name+"."+lz4.FileExtension, &compressedData))
+}
+
+func TestGetDeltaMap_MissingDelta(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	location := *walparser.NewBlockLocation(1, 2, 3, 4)
+	var deltaFileData bytes.Buffer
+	deltaFile := &DeltaFile{Locations: []walparser.BlockLocation{location}, WalParser: walparser.NewWalParser()}
+	assert.NoError(t, deltaFile.Save(&deltaFileData))
+	putCompressedObject(t, folder, "000000010000000000000070_delta", deltaFileData.Bytes())
+	// the delta file 000000010000000000000080_delta is missing, so its WAL segments are read
+	firstNotUsedWalSegmentNo, _ := newWalSegmentNoFromFilename("000000010000000000000090")
+	walSegmentNo, _ := newWalSegmentNoFromFilename("000000010000000000000080")
+	for ; walSegmentNo < firstNotUsedWalSegmentNo; walSegmentNo = walSegmentNo.next() {
+		putCompressedObject(t, folder, walSegmentNo.getFilename(1), make([]byte, walparser.WalPageSize))
+	}
+
+	firstUsedWalSegmentNo, _ := newWalSegmentNoFromFilename("000000010000000000000073")
+	deltaMap, err := getDeltaMap(folder, "", 1, firstUsedWalSegmentNo.firstLsn(), firstNotUsedWalSegmentNo.firstLsn())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{4}, deltaMap[location.RelationFileNode].ToArray())
+
+	// there is no delta map with the gap in the WAL, so all the files are scanned
+	assert.NoError(t, folder.DeleteObjects([]string{"000000010000000000000085." + lz4.FileExtension}))
+	_, err = getDeltaMap(folder, "", 1, firstUsedWalSegmentNo.firstLsn(), firstNotUsedWalSegmentNo.firstLsn())
+	assert.Error(t, err)
+}
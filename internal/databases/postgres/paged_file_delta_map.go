@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strconv"
@@ -13,6 +14,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/internal/walparser"
 )
 
@@ -141,30 +143,37 @@ func GetRelFileNodeFrom(filePath string) (*walparser.RelFileNode, error) {
 	}
 }
 
-func (deltaMap *PagedFileDeltaMap) getLocationsFromDeltas(folder storage.Folder,
-	timeline uint32,
-	first,
-	last DeltaNo) error {
-	for deltaNo := first; deltaNo < last; deltaNo = deltaNo.next() {
-		filename := deltaNo.getFilename(timeline)
-		deltaFile, err := getDeltaFile(folder, filename)
-		if err != nil {
-			return err
-		}
-		tracelog.InfoLogger.Printf("Successfully downloaded delta file %s\n", filename)
-		deltaMap.AddLocationsToDelta(deltaFile.Locations)
-	}
-	return nil
-}
-
+// getLocationsFromWals reads the WAL segments from the storage. If localWalDirectory is set,
+// the segments not archived yet are read from there up to the firstNotUsedLSN.
+// With the WAL index turned on, the stored objects of the indexed segments are downloaded without the lookups.
 func (deltaMap *PagedFileDeltaMap) getLocationsFromWals(folder storage.Folder,
+	localWalDirectory string,
 	timeline uint32,
 	first,
 	last WalSegmentNo,
+	firstNotUsedLSN uint64,
 	walParser *walparser.WalParser) error {
+	indexedObjects := make(map[WalSegmentDescription]indexedWalObject)
+	if isWalIndexEnabled() {
+		var err error
+		indexedObjects, err = readWalIndexRange(folder, timeline, first, last)
+		if err != nil {
+			return errors.Wrap(err, "Error during reading the WAL index")
+		}
+	}
 	for walSegmentNo := first; walSegmentNo < last; walSegmentNo = walSegmentNo.next() {
 		filename := walSegmentNo.getFilename(timeline)
-		err := deltaMap.getLocationsFromWal(folder, filename, walParser)
+		objectName := indexedObjects[WalSegmentDescription{Number: walSegmentNo, Timeline: timeline}].name
+		err := deltaMap.getLocationsFromWal(folder, filename, objectName, walParser)
+		if _, ok := errors.Cause(err).(internal.ArchiveNonExistenceError); ok && localWalDirectory != "" {
+			err = deltaMap.getLocationsFromLocalWal(path.Join(localWalDirectory, filename),
+				getUsedWalSize(walSegmentNo, firstNotUsedLSN), walParser)
+			if err != nil {
+				return err
+			}
+			tracelog.InfoLogger.Printf("Successfully read local wal file %s\n", filename)
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -173,8 +182,47 @@ func (deltaMap *PagedFileDeltaMap) getLocationsFromWals(folder storage.Folder,
 	return nil
 }
 
-func (deltaMap *PagedFileDeltaMap) getLocationsFromWal(folder storage.Folder, filename string, walParser *walparser.WalParser) error {
-	reader, err := internal.DownloadAndDecompressStorageFile(folder, filename)
+// getUsedWalSize returns the size of the segment pages holding the WAL before the firstNotUsedLSN
+func getUsedWalSize(walSegmentNo WalSegmentNo, firstNotUsedLSN uint64) int64 {
+	if walSegmentNo.next().firstLsn() <= firstNotUsedLSN {
+		return int64(WalSegmentSize)
+	}
+	pageSize := uint64(walparser.WalPageSize)
+	return int64((firstNotUsedLSN - walSegmentNo.firstLsn() + pageSize - 1) / pageSize * pageSize)
+}
+
+// getLocationsFromLocalWal reads the segment not archived yet from pg_wal. The segment may still be written,
+// so only the pages with the WAL before the backup start are read, they are flushed by the start checkpoint.
+func (deltaMap *PagedFileDeltaMap) getLocationsFromLocalWal(walFilePath string, size int64,
+	walParser *walparser.WalParser) error {
+	file, err := os.Open(walFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "Error during opening local wal segment '%s'", walFilePath)
+	}
+	reader := &ioextensions.ReadCascadeCloser{Reader: io.LimitReader(file, size), Closer: file}
+	locations, err := walparser.ExtractLocationsFromWalFile(walParser, reader)
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrapf(err, "Error during extracting locations from local wal segment: '%s'", walFilePath)
+	}
+	err = file.Close()
+	if err != nil {
+		return errors.Wrapf(err, "Error during reading local wal segment '%s'", walFilePath)
+	}
+	deltaMap.AddLocationsToDelta(locations)
+	return nil
+}
+
+// getLocationsFromWal reads the WAL segment from the storage, objectName is the indexed object of the segment, if any
+func (deltaMap *PagedFileDeltaMap) getLocationsFromWal(folder storage.Folder, filename, objectName string,
+	walParser *walparser.WalParser) error {
+	var reader io.ReadCloser
+	var err error
+	if objectName != "" {
+		reader, err = internal.DownloadAndDecompressStorageObject(folder, objectName)
+	} else {
+		reader, err = internal.DownloadAndDecompressStorageFile(folder, filename)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "Error during wal segment'%s' downloading.", filename)
 	}
@@ -176,6 +176,16 @@ func isPageCorrupted(path string, blockNo uint32, page *PgDatabasePage) (bool, e
 
 // VerifyPagedFileIncrement verifies pages of an increment
 func VerifyPagedFileIncrement(path string, fileInfo os.FileInfo, increment io.Reader) ([]uint32, error) {
+	if !canVerifyPages(fileInfo, path) {
+		_, err := io.Copy(ioutil.Discard, increment)
+		return nil, err
+	}
+	return verifyIncrementPages(path, increment)
+}
+
+// verifyIncrementPages verifies the pages of the increment of the paged file.
+// The increment header tells which blocks it holds.
+func verifyIncrementPages(path string, increment io.Reader) ([]uint32, error) {
 	_, diffBlockCount, diffMap, err := GetIncrementHeaderFields(increment)
 	if err != nil {
 		return nil, err
@@ -185,27 +195,32 @@ func VerifyPagedFileIncrement(path string, fileInfo os.FileInfo, increment io.Re
 		blockNo := binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
 		blockNumbers = append(blockNumbers, blockNo)
 	}
-	return verifyPageBlocks(path, fileInfo, increment, blockNumbers)
+	return verifyPageBlocks(path, increment, blockNumbers)
 }
 
 // VerifyPagedFileBase verifies pages of a standard paged file
 func VerifyPagedFileBase(path string, fileInfo os.FileInfo, pagedFile io.Reader) ([]uint32, error) {
+	if !canVerifyPages(fileInfo, path) {
+		_, err := io.Copy(ioutil.Discard, pagedFile)
+		return nil, err
+	}
 	size := fileInfo.Size()
 	filePageCount := uint32(size / DatabasePageSize)
 	blockNumbers := make([]uint32, 0, filePageCount)
 	for i := uint32(0); i < filePageCount; i++ {
 		blockNumbers = append(blockNumbers, i)
 	}
-	return verifyPageBlocks(path, fileInfo, pagedFile, blockNumbers)
+	return verifyPageBlocks(path, pagedFile, blockNumbers)
+}
+
+func canVerifyPages(fileInfo os.FileInfo, path string) bool {
+	_, ignored := ignoredFileNames[fileInfo.Name()]
+	return !ignored && isPagedFile(fileInfo, path)
 }
 
 // verifyPageBlocks verifies provided page blocks from the pagedBlocks reader
-func verifyPageBlocks(path string, fileInfo os.FileInfo, pageBlocks io.Reader,
+func verifyPageBlocks(path string, pageBlocks io.Reader,
 	blockNumbers []uint32) (corruptBlockNumbers []uint32, err error) {
-	if _, ignored := ignoredFileNames[fileInfo.Name()]; ignored || !isPagedFile(fileInfo, path) {
-		_, err = io.Copy(ioutil.Discard, pageBlocks)
-		return nil, err
-	}
 	for _, blockNo := range blockNumbers {
 		corrupted, err := verifySinglePage(path, blockNo, pageBlocks)
 		if err == io.EOF {
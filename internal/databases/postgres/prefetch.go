@@ -208,8 +208,13 @@ func prefetchFile(location string, folder storage.Folder, walFileName string, wa
 	err := os.MkdirAll(runningLocation, 0755)
 	tracelog.ErrorLogger.PrintOnError(err)
 
-	err = internal.DownloadFileTo(folder, walFileName, oldPath)
-	tracelog.ErrorLogger.PrintOnError(err)
+	err = internal.DownloadFileToWithChecksum(folder, walFileName, oldPath, fetchWalChecksum(folder, walFileName))
+	if err != nil {
+		// the partial or corrupted file must not be taken by wal-fetch
+		tracelog.ErrorLogger.Println(err)
+		_ = os.Remove(oldPath)
+		return
+	}
 
 	_, errO = os.Stat(oldPath)
 	_, errN = os.Stat(newPath)
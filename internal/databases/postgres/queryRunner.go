@@ -3,7 +3,10 @@ package postgres
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pglogrepl"
 	"github.com/jackc/pgx"
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
@@ -153,7 +156,24 @@ func (queryRunner *PgQueryRunner) buildGetParameter() string {
 // buildGetPhysicalSlotInfo formats a query to get info on a Physical Replication Slot
 // TODO: Unittest
 func (queryRunner *PgQueryRunner) buildGetPhysicalSlotInfo() string {
-	return "select active, restart_lsn from pg_replication_slots where slot_name = $1"
+	return "select slot_type, active, coalesce(restart_lsn, '0/0')::text from pg_replication_slots where slot_name = $1"
+}
+
+// buildCreatePhysicalSlot formats a query to create a Physical Replication Slot reserving the WAL immediately
+func (queryRunner *PgQueryRunner) buildCreatePhysicalSlot() string {
+	return "select (pg_create_physical_replication_slot($1, true)).lsn::text"
+}
+
+// buildAdvancePhysicalSlot formats a query to move the restart LSN of a Physical Replication Slot forward
+func (queryRunner *PgQueryRunner) buildAdvancePhysicalSlot() (string, error) {
+	switch {
+	case queryRunner.Version >= 110000:
+		return "select end_lsn::text from pg_replication_slot_advance($1, $2::pg_lsn)", nil
+	case queryRunner.Version == 0:
+		return "", newNoPostgresVersionError()
+	default:
+		return "", newUnsupportedPostgresVersionError(queryRunner.Version)
+	}
 }
 
 // Retrieve PostgreSQL numeric version
@@ -237,6 +257,37 @@ func (queryRunner *PgQueryRunner) stopBackup() (label string, offsetMap string,
 	return label, offsetMap, lsnStr, nil
 }
 
+// buildGetReplayLag formats a query to get the seconds since the last replayed transaction,
+// which is zero if the standby has replayed all the received WAL
+func (queryRunner *PgQueryRunner) buildGetReplayLag() (string, error) {
+	switch {
+	case queryRunner.Version >= 100000:
+		return "SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 " +
+			"ELSE coalesce(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0) END::float8", nil
+	case queryRunner.Version >= 90100:
+		return "SELECT CASE WHEN pg_last_xlog_receive_location() = pg_last_xlog_replay_location() THEN 0 " +
+			"ELSE coalesce(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0) END::float8", nil
+	case queryRunner.Version == 0:
+		return "", newNoPostgresVersionError()
+	default:
+		return "", newUnsupportedPostgresVersionError(queryRunner.Version)
+	}
+}
+
+// buildGetWalReceiverUpstream formats a query to get the server the standby receives WAL from
+func (queryRunner *PgQueryRunner) buildGetWalReceiverUpstream() (string, error) {
+	switch {
+	case queryRunner.Version >= 110000:
+		return "SELECT coalesce(sender_host, '') || coalesce(':' || sender_port::text, '') FROM pg_stat_wal_receiver", nil
+	case queryRunner.Version >= 90600:
+		return "SELECT coalesce(conninfo, '') FROM pg_stat_wal_receiver", nil
+	case queryRunner.Version == 0:
+		return "", newNoPostgresVersionError()
+	default:
+		return "", newUnsupportedPostgresVersionError(queryRunner.Version)
+	}
+}
+
 // BuildStatisticsQuery formats a query that fetch relations statistics from database
 func (queryRunner *PgQueryRunner) BuildStatisticsQuery() (string, error) {
 	switch {
@@ -380,20 +431,130 @@ func (queryRunner *PgQueryRunner) GetDataDir() (dataDir string, err error) {
 // GetPhysicalSlotInfo reads information on a physical replication slot
 // TODO: Unittest
 func (queryRunner *PgQueryRunner) GetPhysicalSlotInfo(slotName string) (PhysicalSlot, error) {
+	var slotType string
 	var active bool
 	var restartLSN string
 
 	conn := queryRunner.Connection
-	err := conn.QueryRow(queryRunner.buildGetPhysicalSlotInfo(), slotName).Scan(&active, &restartLSN)
+	err := conn.QueryRow(queryRunner.buildGetPhysicalSlotInfo(), slotName).Scan(&slotType, &active, &restartLSN)
 	if err == pgx.ErrNoRows {
 		// slot does not exist.
 		return PhysicalSlot{Name: slotName}, nil
 	} else if err != nil {
 		return PhysicalSlot{Name: slotName}, err
 	}
+	if slotType != "physical" {
+		return PhysicalSlot{Name: slotName}, genericWalReceiveError{
+			errors.Errorf("Slot %s is a %s replication slot, expected a physical one", slotName, slotType)}
+	}
 	return NewPhysicalSlot(slotName, true, active, restartLSN)
 }
 
+// IsInRecovery tells whether the server is a standby
+func (queryRunner *PgQueryRunner) IsInRecovery() (bool, error) {
+	var inRecovery bool
+	conn := queryRunner.Connection
+	err := conn.QueryRow("select pg_is_in_recovery()").Scan(&inRecovery)
+	if err != nil {
+		return false, errors.Wrap(err, "IsInRecovery: checking the recovery state failed")
+	}
+	return inRecovery, nil
+}
+
+// GetReplayLag returns the time since the last transaction replayed by the standby
+// if the standby has not replayed all the received WAL yet
+func (queryRunner *PgQueryRunner) GetReplayLag() (time.Duration, error) {
+	replayLagQuery, err := queryRunner.buildGetReplayLag()
+	if err != nil {
+		return 0, errors.Wrap(err, "GetReplayLag: building replay lag query failed")
+	}
+	var lagSeconds float64
+	conn := queryRunner.Connection
+	err = conn.QueryRow(replayLagQuery).Scan(&lagSeconds)
+	if err != nil {
+		return 0, errors.Wrap(err, "GetReplayLag: getting replay lag failed")
+	}
+	return time.Duration(lagSeconds * float64(time.Second)), nil
+}
+
+// GetWalReceiverUpstream returns the host and port the standby receives WAL from,
+// it is empty if the standby restores WAL from the archive only
+func (queryRunner *PgQueryRunner) GetWalReceiverUpstream() (string, error) {
+	upstreamQuery, err := queryRunner.buildGetWalReceiverUpstream()
+	if err != nil {
+		return "", errors.Wrap(err, "GetWalReceiverUpstream: building WAL receiver query failed")
+	}
+	var upstream string
+	conn := queryRunner.Connection
+	err = conn.QueryRow(upstreamQuery).Scan(&upstream)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "GetWalReceiverUpstream: getting WAL receiver failed")
+	}
+	if queryRunner.Version < 110000 {
+		return parseConninfoUpstream(upstream), nil
+	}
+	return upstream, nil
+}
+
+// parseConninfoUpstream returns the host and port of the WAL receiver connection string
+func parseConninfoUpstream(conninfo string) string {
+	var host, hostAddr, port string
+	for _, field := range strings.Fields(conninfo) {
+		keyValue := strings.SplitN(field, "=", 2)
+		if len(keyValue) < 2 {
+			continue
+		}
+		value := strings.Trim(keyValue[1], "'")
+		switch keyValue[0] {
+		case "host":
+			host = value
+		case "hostaddr":
+			hostAddr = value
+		case "port":
+			port = value
+		}
+	}
+	if host == "" {
+		host = hostAddr
+	}
+	if host == "" || port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// CreatePhysicalSlot creates a physical replication slot, on a standby as well
+func (queryRunner *PgQueryRunner) CreatePhysicalSlot(slotName string) (PhysicalSlot, error) {
+	var restartLSN string
+
+	conn := queryRunner.Connection
+	err := conn.QueryRow(queryRunner.buildCreatePhysicalSlot(), slotName).Scan(&restartLSN)
+	if err != nil {
+		return PhysicalSlot{Name: slotName}, errors.Wrapf(err, "CreatePhysicalSlot: creating slot %s failed", slotName)
+	}
+	return NewPhysicalSlot(slotName, true, false, restartLSN)
+}
+
+// AdvancePhysicalSlot moves the restart LSN of a physical replication slot forward
+// and returns the new one, which is not past the flushed or the replayed WAL of the server
+func (queryRunner *PgQueryRunner) AdvancePhysicalSlot(slotName string, lsn pglogrepl.LSN) (pglogrepl.LSN, error) {
+	advanceQuery, err := queryRunner.buildAdvancePhysicalSlot()
+	if err != nil {
+		return 0, errors.Wrap(err, "AdvancePhysicalSlot: building advance slot query failed")
+	}
+
+	var endLSN string
+	conn := queryRunner.Connection
+	err = conn.QueryRow(advanceQuery, slotName, lsn.String()).Scan(&endLSN)
+	if err != nil {
+		return 0, errors.Wrapf(err, "AdvancePhysicalSlot: advancing slot %s failed", slotName)
+	}
+	return pglogrepl.ParseLSN(endLSN)
+}
+
 // tablespace map does not exist in < 9.6
 // TODO: Unittest
 func (queryRunner *PgQueryRunner) IsTablespaceMapExists() bool {
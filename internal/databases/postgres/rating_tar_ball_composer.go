@@ -32,7 +32,8 @@ func NewRatingTarBallComposerMaker(relFileStats RelFileStatistics,
 
 func (maker *RatingTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer, error) {
 	composeRatingEvaluator := internal.NewDefaultComposeRatingEvaluator(bundle.IncrementFromFiles)
-	filePacker := newTarBallFilePacker(bundle.DeltaMap, bundle.IncrementFromLsn, maker.bundleFiles, maker.filePackerOptions)
+	filePacker := newTarBallFilePacker(bundle.DeltaMap, bundle.deltaMapStats, bundle.IncrementFromLsn,
+		maker.bundleFiles, bundle.pushState, maker.filePackerOptions)
 	return NewRatingTarBallComposer(uint64(bundle.TarSizeThreshold),
 		composeRatingEvaluator,
 		bundle.IncrementFromLsn,
@@ -275,6 +276,10 @@ func (c *RatingTarBallComposer) getExpectedFileSize(cfi *ComposeFileInfo) (uint6
 		// so the expected size in tar is zero
 		return 0, nil
 	}
+	if err != nil && c.deltaMapComplete {
+		// the downloaded delta map does not cover this file, so it is scanned in full by the packer
+		return uint64(cfi.fileInfo.Size()), nil
+	}
 	if err != nil {
 		return 0, errors.Wrapf(err, "getExpectedFileSize: failed to find corresponding bitmap '%s'\n", cfi.path)
 	}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	RecoveryConfFileName = "recovery.conf"
+	AutoConfFileName     = "postgresql.auto.conf"
+	RecoverySignalName   = "recovery.signal"
+	StandbySignalName    = "standby.signal"
+
+	recoveryConfigHeader = "# recovery settings written by wal-g backup-fetch\n"
+)
+
+// RecoveryConfig is the recovery configuration written to the data directory of the fetched backup
+type RecoveryConfig struct {
+	RestoreCommand string
+	Target         *RecoveryTarget
+	TargetTimeline string
+	Standby        bool
+}
+
+// WriteRecoveryConfig writes the recovery configuration in the format of the Postgres version:
+// recovery.conf before Postgres 12, postgresql.auto.conf and the signal file since then
+func WriteRecoveryConfig(dbDataDirectory string, pgVersion int, config RecoveryConfig) error {
+	restoreCommand := config.RestoreCommand
+	if restoreCommand == "" {
+		restoreCommand = DefaultRestoreCommand
+	}
+	settings := NewRecoverySettings(restoreCommand, config.Target, config.TargetTimeline)
+	if pgVersion < 120000 && config.Standby {
+		settings = append(settings, RecoverySetting{"standby_mode", "on"})
+	}
+	lines := make([]string, 0, len(settings))
+	for _, setting := range settings {
+		lines = append(lines, setting.String()+"\n")
+	}
+	content := recoveryConfigHeader + strings.Join(lines, "")
+
+	if pgVersion < 120000 {
+		tracelog.InfoLogger.Printf("Writing %s\n", RecoveryConfFileName)
+		return ioutil.WriteFile(filepath.Join(dbDataDirectory, RecoveryConfFileName), []byte(content), 0600)
+	}
+
+	tracelog.InfoLogger.Printf("Writing recovery settings to %s\n", AutoConfFileName)
+	autoConfPath := filepath.Join(dbDataDirectory, AutoConfFileName)
+	autoConf, err := ioutil.ReadFile(autoConfPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = utility.ReplaceFile(autoConfPath, []byte(removeRecoverySettings(string(autoConf))+content))
+	if err != nil {
+		return err
+	}
+	signalName := RecoverySignalName
+	if config.Standby {
+		signalName = StandbySignalName
+	}
+	tracelog.InfoLogger.Printf("Writing %s\n", signalName)
+	return ioutil.WriteFile(filepath.Join(dbDataDirectory, signalName), nil, 0600)
+}
+
+// removeRecoverySettings drops the recovery settings of an earlier backup-fetch from postgresql.auto.conf,
+// so that the rewritten file has a single restore_command and recovery target
+func removeRecoverySettings(autoConf string) string {
+	var lines []string
+	for _, line := range strings.SplitAfter(autoConf, "\n") {
+		if line == "" || line == recoveryConfigHeader {
+			continue
+		}
+		name := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+		if name == "restore_command" || strings.HasPrefix(name, "recovery_target") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	kept := strings.Join(lines, "")
+	if kept != "" && !strings.HasSuffix(kept, "\n") {
+		kept += "\n"
+	}
+	return kept
+}
+
+// readDataDirectoryVersion reads the Postgres version from the PG_VERSION file, like 90600 for 9.6 or 120000 for 12
+func readDataDirectoryVersion(dbDataDirectory string) (int, error) {
+	versionBytes, err := ioutil.ReadFile(filepath.Join(dbDataDirectory, "PG_VERSION"))
+	if err != nil {
+		return 0, err
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(versionBytes)), ".", 2)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid PG_VERSION '%s'", versionBytes)
+	}
+	if len(parts) == 1 {
+		return major * 10000, nil
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid PG_VERSION '%s'", versionBytes)
+	}
+	return major*10000 + minor*100, nil
+}
+
+// GetPgFetcherWithRecoveryConfig writes the recovery configuration after the backup is fetched.
+// The Postgres version is taken from the sentinel, or from the fetched data directory for the older backups.
+func GetPgFetcherWithRecoveryConfig(fetcher func(folder storage.Folder, backup internal.Backup),
+	dbDataDirectory string, config RecoveryConfig) func(folder storage.Folder, backup internal.Backup) {
+	return func(folder storage.Folder, backup internal.Backup) {
+		fetcher(folder, backup)
+
+		pgBackup := ToPgBackup(backup)
+		sentinelDto, err := pgBackup.GetSentinel()
+		tracelog.ErrorLogger.FatalfOnError("Failed to write the recovery configuration: %v\n", err)
+		dataDirectory := utility.ResolveSymlink(dbDataDirectory)
+		pgVersion := sentinelDto.PgVersion
+		if pgVersion == 0 {
+			pgVersion, err = readDataDirectoryVersion(dataDirectory)
+			tracelog.ErrorLogger.FatalfOnError("Failed to write the recovery configuration: %v\n", err)
+		}
+		err = WriteRecoveryConfig(dataDirectory, pgVersion, config)
+		tracelog.ErrorLogger.FatalfOnError("Failed to write the recovery configuration: %v\n", err)
+	}
+}
@@ -0,0 +1,80 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func readRecoveryConfigFile(t *testing.T, dataDirectory, name string) string {
+	content, err := ioutil.ReadFile(filepath.Join(dataDirectory, name))
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestWriteRecoveryConfig_RecoveryConfBeforePg12(t *testing.T) {
+	dataDirectory, err := ioutil.TempDir("", "recovery_config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDirectory)
+	target, err := postgres.NewRecoveryTarget(postgres.RecoveryTargetLsn, "0/16B3748")
+	assert.NoError(t, err)
+
+	err = postgres.WriteRecoveryConfig(dataDirectory, 110005, postgres.RecoveryConfig{Target: &target, Standby: true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "# recovery settings written by wal-g backup-fetch\n"+
+		"restore_command = 'wal-g wal-fetch \"%f\" \"%p\"'\n"+
+		"recovery_target_lsn = '0/16B3748'\n"+
+		"standby_mode = 'on'\n",
+		readRecoveryConfigFile(t, dataDirectory, postgres.RecoveryConfFileName))
+	_, err = os.Stat(filepath.Join(dataDirectory, postgres.StandbySignalName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWriteRecoveryConfig_SignalFileSincePg12(t *testing.T) {
+	dataDirectory, err := ioutil.TempDir("", "recovery_config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDirectory)
+	err = ioutil.WriteFile(filepath.Join(dataDirectory, postgres.AutoConfFileName), []byte("work_mem = '64MB'\n"), 0600)
+	assert.NoError(t, err)
+
+	err = postgres.WriteRecoveryConfig(dataDirectory, 130002, postgres.RecoveryConfig{
+		RestoreCommand: "cp /archive/%f %p",
+		TargetTimeline: "latest",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "work_mem = '64MB'\n"+
+		"# recovery settings written by wal-g backup-fetch\n"+
+		"restore_command = 'cp /archive/%f %p'\n"+
+		"recovery_target_timeline = 'latest'\n",
+		readRecoveryConfigFile(t, dataDirectory, postgres.AutoConfFileName))
+	assert.Equal(t, "", readRecoveryConfigFile(t, dataDirectory, postgres.RecoverySignalName))
+	_, err = os.Stat(filepath.Join(dataDirectory, postgres.RecoveryConfFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWriteRecoveryConfig_ReplacesEarlierSettings(t *testing.T) {
+	dataDirectory, err := ioutil.TempDir("", "recovery_config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dataDirectory)
+	err = ioutil.WriteFile(filepath.Join(dataDirectory, postgres.AutoConfFileName), []byte("work_mem = '64MB'"), 0600)
+	assert.NoError(t, err)
+	target, err := postgres.NewRecoveryTarget(postgres.RecoveryTargetLsn, "0/16B3748")
+	assert.NoError(t, err)
+
+	err = postgres.WriteRecoveryConfig(dataDirectory, 130002, postgres.RecoveryConfig{Target: &target})
+	assert.NoError(t, err)
+	err = postgres.WriteRecoveryConfig(dataDirectory, 130002, postgres.RecoveryConfig{TargetTimeline: "latest"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "work_mem = '64MB'\n"+
+		"# recovery settings written by wal-g backup-fetch\n"+
+		"restore_command = 'wal-g wal-fetch \"%f\" \"%p\"'\n"+
+		"recovery_target_timeline = 'latest'\n",
+		readRecoveryConfigFile(t, dataDirectory, postgres.AutoConfFileName))
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// DefaultRestoreCommand fetches the WAL segments from the storage during the recovery
+const DefaultRestoreCommand = `wal-g wal-fetch "%f" "%p"`
+
+const recoveryTargetTimeFormat = "2006-01-02 15:04:05.999999-07:00"
+
+type RecoveryTargetType string
+
+const (
+	RecoveryTargetTime RecoveryTargetType = "time"
+	RecoveryTargetLsn  RecoveryTargetType = "lsn"
+	RecoveryTargetXid  RecoveryTargetType = "xid"
+)
+
+type InvalidRecoveryTargetError struct {
+	error
+}
+
+func newInvalidRecoveryTargetError(target string) InvalidRecoveryTargetError {
+	return InvalidRecoveryTargetError{errors.Errorf(
+		"invalid recovery target '%s', expected an RFC 3339 time, an LSN like 0/16B3748 or a transaction ID", target)}
+}
+
+func (err InvalidRecoveryTargetError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RecoveryTarget is the point the Postgres recovery stops at
+type RecoveryTarget struct {
+	Type RecoveryTargetType
+	Time time.Time
+	Lsn  uint64
+	Xid  uint64
+}
+
+// ParseRecoveryTarget detects the type of the target: an LSN contains a slash,
+// a transaction ID is a number and anything else should be an RFC 3339 time
+func ParseRecoveryTarget(target string) (RecoveryTarget, error) {
+	if strings.Contains(target, "/") {
+		return NewRecoveryTarget(RecoveryTargetLsn, target)
+	}
+	if _, err := strconv.ParseUint(target, 10, 64); err == nil {
+		return NewRecoveryTarget(RecoveryTargetXid, target)
+	}
+	return NewRecoveryTarget(RecoveryTargetTime, target)
+}
+
+// NewRecoveryTarget parses the target of the given type
+func NewRecoveryTarget(targetType RecoveryTargetType, target string) (RecoveryTarget, error) {
+	switch targetType {
+	case RecoveryTargetLsn:
+		lsn, err := pglogrepl.ParseLSN(target)
+		if err != nil {
+			return RecoveryTarget{}, newInvalidRecoveryTargetError(target)
+		}
+		return RecoveryTarget{Type: RecoveryTargetLsn, Lsn: uint64(lsn)}, nil
+	case RecoveryTargetXid:
+		xid, err := strconv.ParseUint(target, 10, 64)
+		if err != nil {
+			return RecoveryTarget{}, newInvalidRecoveryTargetError(target)
+		}
+		return RecoveryTarget{Type: RecoveryTargetXid, Xid: xid}, nil
+	default:
+		targetTime, err := time.Parse(time.RFC3339, target)
+		if err != nil {
+			return RecoveryTarget{}, newInvalidRecoveryTargetError(target)
+		}
+		return RecoveryTarget{Type: RecoveryTargetTime, Time: targetTime}, nil
+	}
+}
+
+// Setting returns the recovery_target_* setting of the target
+func (target RecoveryTarget) Setting() RecoverySetting {
+	switch target.Type {
+	case RecoveryTargetLsn:
+		return RecoverySetting{"recovery_target_lsn", pglogrepl.LSN(target.Lsn).String()}
+	case RecoveryTargetXid:
+		return RecoverySetting{"recovery_target_xid", strconv.FormatUint(target.Xid, 10)}
+	default:
+		return RecoverySetting{"recovery_target_time", target.Time.Format(recoveryTargetTimeFormat)}
+	}
+}
+
+// RecoverySetting is a parameter of the Postgres recovery configuration
+type RecoverySetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (setting RecoverySetting) String() string {
+	return fmt.Sprintf("%s = '%s'", setting.Name, strings.ReplaceAll(setting.Value, "'", "''"))
+}
+
+// NewRecoverySettings lists the recovery parameters, the target and the timeline are omitted if not set
+func NewRecoverySettings(restoreCommand string, target *RecoveryTarget, targetTimeline string) []RecoverySetting {
+	settings := []RecoverySetting{{"restore_command", restoreCommand}}
+	if target != nil {
+		settings = append(settings, target.Setting())
+	}
+	if targetTimeline != "" {
+		settings = append(settings, RecoverySetting{"recovery_target_timeline", targetTimeline})
+	}
+	return settings
+}
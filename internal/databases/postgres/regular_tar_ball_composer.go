@@ -49,8 +49,8 @@ func NewRegularTarBallComposerMaker(filePackerOptions TarBallFilePackerOptions)
 
 func (maker *RegularTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer, error) {
 	bundleFiles := &RegularBundleFiles{}
-	tarBallFilePacker := newTarBallFilePacker(bundle.DeltaMap,
-		bundle.IncrementFromLsn, bundleFiles, maker.filePackerOptions)
+	tarBallFilePacker := newTarBallFilePacker(bundle.DeltaMap, bundle.deltaMapStats,
+		bundle.IncrementFromLsn, bundleFiles, bundle.pushState, maker.filePackerOptions)
 	return NewRegularTarBallComposer(bundle.TarBallQueue, tarBallFilePacker, bundleFiles, bundle.Crypter), nil
 }
 
@@ -0,0 +1,326 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type UnreachableRecoveryTargetError struct {
+	error
+}
+
+func newUnreachableRecoveryTargetError(format string, args ...interface{}) UnreachableRecoveryTargetError {
+	return UnreachableRecoveryTargetError{errors.Errorf("can't reach the recovery target: "+format, args...)}
+}
+
+func (err UnreachableRecoveryTargetError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RestorePlan describes the backup and the WAL segments to recover the cluster up to the target
+type RestorePlan struct {
+	BackupName       string            `json:"backup_name"`
+	Timeline         uint32            `json:"timeline"`
+	StartSegment     string            `json:"start_segment"`
+	EndSegment       string            `json:"end_segment"`
+	RecoverySettings []RecoverySetting `json:"recovery_settings"`
+}
+
+// restoreHistory is the WAL storage seen from the target timeline
+type restoreHistory struct {
+	timeline       uint32
+	historyRecords []*TimelineHistoryRecord
+	segments       map[WalSegmentDescription]bool
+	maxSegmentNo   WalSegmentNo
+}
+
+func newRestoreHistory(walFolder storage.Folder, timeline uint32) (*restoreHistory, error) {
+	objects, _, err := walFolder.ListFolder()
+	if err != nil {
+		return nil, err
+	}
+	history := &restoreHistory{
+		timeline: timeline,
+		segments: make(map[WalSegmentDescription]bool),
+	}
+	for _, object := range objects {
+		segment, err := NewWalSegmentDescription(utility.TrimFileExtension(object.GetName()))
+		if err != nil {
+			// non-wal segment file, skip it
+			continue
+		}
+		history.segments[segment] = true
+		if history.timeline < segment.Timeline && timeline == 0 {
+			history.timeline = segment.Timeline
+		}
+	}
+	if len(history.segments) == 0 {
+		return nil, newUnreachableRecoveryTargetError("no WAL segments found in storage")
+	}
+
+	history.historyRecords, err = getTimeLineHistoryRecords(history.timeline, walFolder)
+	if _, ok := err.(HistoryFileNotFoundError); err != nil && !ok {
+		return nil, err
+	}
+	for segment := range history.segments {
+		if history.maxSegmentNo < segment.Number && history.timelineOf(segment.Number) == segment.Timeline {
+			history.maxSegmentNo = segment.Number
+		}
+	}
+	return history, nil
+}
+
+// timelineOf returns the timeline the segment belongs to in the history of the target timeline.
+// The segment with the switch point belongs to the new timeline, like in the WalSegmentRunner.
+func (history *restoreHistory) timelineOf(segmentNo WalSegmentNo) uint32 {
+	for _, record := range history.historyRecords {
+		if segmentNo < newWalSegmentNo(record.lsn) {
+			return record.timeline
+		}
+	}
+	return history.timeline
+}
+
+func (history *restoreHistory) segment(segmentNo WalSegmentNo) WalSegmentDescription {
+	return WalSegmentDescription{Number: segmentNo, Timeline: history.timelineOf(segmentNo)}
+}
+
+func (history *restoreHistory) switchMap() map[WalSegmentNo]*TimelineHistoryRecord {
+	switchMap := make(map[WalSegmentNo]*TimelineHistoryRecord, len(history.historyRecords))
+	for _, record := range history.historyRecords {
+		switchMap[newWalSegmentNo(record.lsn)] = record
+	}
+	return switchMap
+}
+
+// PlanRestore picks the newest backup to recover the cluster from up to the target on the timeline
+// (the highest one in storage if zero) and checks that the WAL segments up to the target are in storage.
+// The time target is reached with the first segment committing a transaction after it, the xid target
+// with the last segment.
+func PlanRestore(rootFolder storage.Folder, target RecoveryTarget, timeline uint32) (*RestorePlan, error) {
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	history, err := newRestoreHistory(walFolder, timeline)
+	if err != nil {
+		return nil, err
+	}
+	backup, backupSegmentNo, err := findRestoreBackup(rootFolder, target, history)
+	if err != nil {
+		return nil, err
+	}
+	targetSegmentNo, err := findTargetSegment(walFolder, target, history, backupSegmentNo)
+	if err != nil {
+		return nil, err
+	}
+	if targetSegmentNo < backupSegmentNo {
+		return nil, newUnreachableRecoveryTargetError("backup %s starts after the target", backup.BackupName)
+	}
+	err = checkRestoreWalContinuity(history, backupSegmentNo, targetSegmentNo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestorePlan{
+		BackupName:   backup.BackupName,
+		Timeline:     history.timeline,
+		StartSegment: history.segment(backupSegmentNo).GetFileName(),
+		EndSegment:   history.segment(targetSegmentNo).GetFileName(),
+		RecoverySettings: NewRecoverySettings(DefaultRestoreCommand, &target,
+			strconv.FormatUint(uint64(history.timeline), 10)),
+	}, nil
+}
+
+// findRestoreBackup returns the newest backup of the target timeline history finished before the target
+func findRestoreBackup(rootFolder storage.Folder,
+	target RecoveryTarget, history *restoreHistory) (*BackupDetail, WalSegmentNo, error) {
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	backups, err := internal.GetBackups(baseBackupFolder)
+	if _, ok := err.(internal.NoBackupsFoundError); ok {
+		return nil, 0, newUnreachableRecoveryTargetError("no backups found in storage")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	backupDetails, err := GetBackupsDetails(baseBackupFolder, backups)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var newestBackup *BackupDetail
+	var newestBackupSegmentNo WalSegmentNo
+	for i := range backupDetails {
+		backup := &backupDetails[i]
+		backupTimeline, backupSegmentNo, err := ParseWALFilename(backup.WalFileName)
+		if err != nil {
+			return nil, 0, err
+		}
+		if history.timelineOf(WalSegmentNo(backupSegmentNo)) != backupTimeline {
+			tracelog.InfoLogger.Printf("Backup %s is not in the history of timeline %d, skipping it\n",
+				backup.BackupName, history.timeline)
+			continue
+		}
+		if !isBackupFinishedBefore(backup, target) {
+			continue
+		}
+		if newestBackup == nil || newestBackupSegmentNo < WalSegmentNo(backupSegmentNo) ||
+			newestBackupSegmentNo == WalSegmentNo(backupSegmentNo) && newestBackup.FinishLsn < backup.FinishLsn {
+			newestBackup = backup
+			newestBackupSegmentNo = WalSegmentNo(backupSegmentNo)
+		}
+	}
+	if newestBackup == nil {
+		return nil, 0, newUnreachableRecoveryTargetError(
+			"no backup of timeline %d history finished before the target", history.timeline)
+	}
+	if target.Type == RecoveryTargetXid {
+		tracelog.WarningLogger.Printf("Can't tell if backup %s finished before transaction %d, "+
+			"the recovery fails if it didn't\n", newestBackup.BackupName, target.Xid)
+	}
+	return newestBackup, newestBackupSegmentNo, nil
+}
+
+func isBackupFinishedBefore(backup *BackupDetail, target RecoveryTarget) bool {
+	switch target.Type {
+	case RecoveryTargetLsn:
+		return backup.FinishLsn != 0 && backup.FinishLsn <= target.Lsn
+	case RecoveryTargetTime:
+		return !backup.FinishTime.IsZero() && !backup.FinishTime.After(target.Time)
+	default:
+		return true
+	}
+}
+
+// findTargetSegment returns the number of the segment the recovery reaches the target in
+func findTargetSegment(walFolder storage.Folder, target RecoveryTarget,
+	history *restoreHistory, backupSegmentNo WalSegmentNo) (WalSegmentNo, error) {
+	switch target.Type {
+	case RecoveryTargetLsn:
+		return newWalSegmentNo(target.Lsn), nil
+	case RecoveryTargetTime:
+		return findTimeTargetSegment(walFolder, target.Time, history, backupSegmentNo)
+	default:
+		return history.maxSegmentNo, nil
+	}
+}
+
+// findTimeTargetSegment parses the WAL from the backup start up to the first commit or abort record
+// after the target time, the recovery stops there
+func findTimeTargetSegment(walFolder storage.Folder, targetTime time.Time,
+	history *restoreHistory, backupSegmentNo WalSegmentNo) (WalSegmentNo, error) {
+	walParser := walparser.NewWalParser()
+	for segmentNo := backupSegmentNo; segmentNo <= history.maxSegmentNo; segmentNo++ {
+		segment := history.segment(segmentNo)
+		if !history.segments[segment] {
+			// the WAL continuity check reports the missing segment
+			return segmentNo, nil
+		}
+		found, err := hasTransactionAfter(walFolder, segment.GetFileName(), walParser, targetTime)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			return segmentNo, nil
+		}
+	}
+	return 0, newUnreachableRecoveryTargetError(
+		"no transaction of timeline %d history committed after %s", history.timeline, targetTime)
+}
+
+// hasTransactionAfter tells if the WAL segment has a commit or abort record after the target time
+func hasTransactionAfter(walFolder storage.Folder, filename string,
+	walParser *walparser.WalParser, targetTime time.Time) (bool, error) {
+	reader, err := internal.DownloadAndDecompressStorageFile(walFolder, filename)
+	if err != nil {
+		return false, err
+	}
+	defer utility.LoggedClose(reader, "")
+	pageReader := walparser.NewWalPageReader(reader)
+	for {
+		data, err := pageReader.ReadPageData()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to read WAL segment %s", filename)
+		}
+		_, records, err := walParser.ParseRecordsFromPage(bytes.NewReader(data))
+		switch err.(type) {
+		case nil:
+		case walparser.PartialPageError:
+		case walparser.ZeroPageError:
+		default:
+			return false, errors.Wrapf(err, "failed to parse WAL segment %s", filename)
+		}
+		for i := range records {
+			if transactionTime, ok := records[i].TransactionTime(); ok && transactionTime.After(targetTime) {
+				return true, nil
+			}
+		}
+	}
+}
+
+// checkRestoreWalContinuity checks that every segment from the backup start up to the target is in storage
+func checkRestoreWalContinuity(history *restoreHistory, backupSegmentNo, targetSegmentNo WalSegmentNo) error {
+	targetSegment := history.segment(targetSegmentNo)
+	missingSegments := make([]string, 0)
+	if !history.segments[targetSegment] {
+		missingSegments = append(missingSegments, targetSegment.GetFileName())
+	}
+
+	walSegmentRunner := NewWalSegmentRunner(targetSegment, history.segments, backupSegmentNo, history.switchMap())
+	walSegmentScanner := NewWalSegmentScanner(walSegmentRunner)
+	err := walSegmentScanner.Scan(SegmentScanConfig{
+		UnlimitedScan:        true,
+		MissingSegmentStatus: Lost,
+	})
+	if err != nil {
+		return err
+	}
+	for _, segment := range walSegmentScanner.GetMissingSegmentsDescriptions() {
+		missingSegments = append(missingSegments, segment.GetFileName())
+	}
+	if len(missingSegments) > 0 {
+		return newUnreachableRecoveryTargetError("missing WAL segments: %s", strings.Join(missingSegments, ", "))
+	}
+	return nil
+}
+
+// HandleRestorePlan shows the backup and the recovery settings to recover the cluster up to the target,
+// it refuses to do so if there is a gap in the WAL
+func HandleRestorePlan(rootFolder storage.Folder, target RecoveryTarget, timeline uint32,
+	output io.Writer, isJSON bool) {
+	plan, err := PlanRestore(rootFolder, target, timeline)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	if isJSON {
+		err = json.NewEncoder(output).Encode(plan)
+	} else {
+		err = writeRestorePlan(plan, output)
+	}
+	tracelog.ErrorLogger.FatalfOnError("Failed to write the restore plan: %v\n", err)
+}
+
+func writeRestorePlan(plan *RestorePlan, output io.Writer) error {
+	_, err := fmt.Fprintf(output, "Backup: %s\nWAL segments: %s - %s\nRecovery settings:\n",
+		plan.BackupName, plan.StartSegment, plan.EndSegment)
+	if err != nil {
+		return err
+	}
+	for _, setting := range plan.RecoverySettings {
+		if _, err = fmt.Fprintln(output, setting.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
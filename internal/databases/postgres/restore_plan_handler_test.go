@@ -0,0 +1,150 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func newMockFinishedBackupMetadata(finishSegmentNo uint64) postgres.ExtendedMetadataDto {
+	meta := newMockExtendedMetadataDto(false)
+	meta.FinishLsn = finishSegmentNo*postgres.WalSegmentSize + 500
+	return meta
+}
+
+// setupRestorePlanStorage fills the storage with the timeline 5 switched to the timeline 6 in the 5th segment
+func setupRestorePlanStorage(t *testing.T, walFilenames []string) storage.Folder {
+	storageFiles := make(map[string]*bytes.Buffer)
+	addMockBackupsStorageFiles(map[string]postgres.ExtendedMetadataDto{
+		"000000050000000000000002": newMockFinishedBackupMetadata(2),
+		"000000060000000000000006": newMockFinishedBackupMetadata(6),
+		// not in the history of the timeline 6
+		"000000050000000000000006": newMockFinishedBackupMetadata(6),
+	}, storageFiles)
+	historyName, historyFile, err := newTimelineHistoryFile(
+		fmt.Sprintf("%d\t0/%X\tsome comment...\n", 5, 5*postgres.WalSegmentSize+100), 6)
+	assert.NoError(t, err)
+	storageFiles[utility.WalPath+historyName] = historyFile
+
+	rootFolder := setupTestStorageFolder()
+	for name, content := range storageFiles {
+		_ = rootFolder.PutObject(name, content)
+	}
+	putWalSegments(walFilenames, rootFolder.GetSubFolder(utility.WalPath))
+	return rootFolder
+}
+
+func TestPlanRestore_PicksNewestBackupOfTimelineHistory(t *testing.T) {
+	rootFolder := setupRestorePlanStorage(t, []string{
+		"000000050000000000000002",
+		"000000050000000000000003",
+		"000000050000000000000004",
+		"000000050000000000000005",
+		"000000050000000000000006",
+		"000000060000000000000005",
+		"000000060000000000000006",
+		"000000060000000000000007",
+	})
+	target, err := postgres.ParseRecoveryTarget(fmt.Sprintf("0/%X", 7*postgres.WalSegmentSize+10))
+	assert.NoError(t, err)
+
+	plan, err := postgres.PlanRestore(rootFolder, target, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &postgres.RestorePlan{
+		BackupName:   "base_000000060000000000000006",
+		Timeline:     6,
+		StartSegment: "000000060000000000000006",
+		EndSegment:   "000000060000000000000007",
+		RecoverySettings: []postgres.RecoverySetting{
+			{Name: "restore_command", Value: postgres.DefaultRestoreCommand},
+			{Name: "recovery_target_lsn", Value: "0/700000A"},
+			{Name: "recovery_target_timeline", Value: "6"},
+		},
+	}, plan)
+}
+
+func TestPlanRestore_RefusesOnGap(t *testing.T) {
+	rootFolder := setupRestorePlanStorage(t, []string{
+		"000000050000000000000002",
+		"000000050000000000000004",
+		"000000060000000000000005",
+		"000000060000000000000006",
+	})
+	target, err := postgres.ParseRecoveryTarget(fmt.Sprintf("0/%X", 4*postgres.WalSegmentSize+10))
+	assert.NoError(t, err)
+
+	_, err = postgres.PlanRestore(rootFolder, target, 0)
+	assert.IsType(t, postgres.UnreachableRecoveryTargetError{}, err)
+	assert.Contains(t, err.Error(), "000000050000000000000003")
+}
+
+// putCommitWalSegment puts the WAL segment with the only commit record of the given time
+func putCommitWalSegment(t *testing.T, walFolder storage.Folder, filename string, commitTime time.Time) {
+	timeline, segmentNo, err := postgres.ParseWALFilename(filename)
+	assert.NoError(t, err)
+	page := make([]byte, walparser.WalPageSize)
+	binary.LittleEndian.PutUint16(page[0:], 0xD101)
+	binary.LittleEndian.PutUint16(page[2:], walparser.XlpLongHeader)
+	binary.LittleEndian.PutUint32(page[4:], timeline)
+	binary.LittleEndian.PutUint64(page[8:], segmentNo*postgres.WalSegmentSize)
+	// the record follows the long page header: the record header, the short main data header and the commit time
+	record := page[40:]
+	binary.LittleEndian.PutUint32(record[0:], 34)
+	record[16] = walparser.XLogXactCommit
+	record[17] = walparser.RmXactID
+	record[24] = walparser.XlrBlockIDDataShort
+	record[25] = 8
+	commitTimestamp := commitTime.Sub(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)).Microseconds()
+	binary.LittleEndian.PutUint64(record[26:], uint64(commitTimestamp))
+
+	var compressedPage bytes.Buffer
+	writer := lz4.Compressor{}.NewWriter(&compressedPage)
+	_, err = writer.Write(page)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, walFolder.PutObject(filename+"."+lz4.FileExtension, &compressedPage))
+}
+
+func TestPlanRestore_TimeTargetFromCommitTimes(t *testing.T) {
+	rootFolder := setupRestorePlanStorage(t, nil)
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	targetTime := time.Now().Add(time.Hour).Truncate(time.Second)
+	putCommitWalSegment(t, walFolder, "000000060000000000000006", targetTime.Add(-2*time.Minute))
+	putCommitWalSegment(t, walFolder, "000000060000000000000007", targetTime.Add(-time.Minute))
+	putCommitWalSegment(t, walFolder, "000000060000000000000008", targetTime.Add(time.Minute))
+	putCommitWalSegment(t, walFolder, "000000060000000000000009", targetTime.Add(2*time.Minute))
+	target, err := postgres.ParseRecoveryTarget(targetTime.Format(time.RFC3339))
+	assert.NoError(t, err)
+
+	plan, err := postgres.PlanRestore(rootFolder, target, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, "base_000000060000000000000006", plan.BackupName)
+	assert.Equal(t, "000000060000000000000008", plan.EndSegment)
+
+	target.Time = targetTime.Add(time.Hour)
+	_, err = postgres.PlanRestore(rootFolder, target, 6)
+	assert.IsType(t, postgres.UnreachableRecoveryTargetError{}, err)
+}
+
+func TestParseRecoveryTarget(t *testing.T) {
+	target, err := postgres.ParseRecoveryTarget("2020-10-01T12:00:00+03:00")
+	assert.NoError(t, err)
+	assert.Equal(t, postgres.RecoveryTargetTime, target.Type)
+	assert.Equal(t, "recovery_target_time = '2020-10-01 12:00:00+03:00'", target.Setting().String())
+
+	target, err = postgres.ParseRecoveryTarget("1234")
+	assert.NoError(t, err)
+	assert.Equal(t, postgres.RecoveryTarget{Type: postgres.RecoveryTargetXid, Xid: 1234}, target)
+
+	_, err = postgres.ParseRecoveryTarget("yesterday")
+	assert.IsType(t, postgres.InvalidRecoveryTargetError{}, err)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sync/atomic"
 
 	"github.com/wal-g/wal-g/internal"
 
@@ -56,20 +57,58 @@ func NewTarBallFilePackerOptions(verifyPageChecksums, storeAllCorruptBlocks bool
 	}
 }
 
+// deltaMapStatistics counts how the incremented files are read with the downloaded delta map
+type deltaMapStatistics struct {
+	skippedFiles int64
+	readFiles    int64
+	scannedFiles int64
+}
+
+func (stats *deltaMapStatistics) countSkippedFile() {
+	if stats != nil {
+		atomic.AddInt64(&stats.skippedFiles, 1)
+	}
+}
+
+// countReadFile counts the file read by the changed blocks of the bitmap, or scanned in full without the bitmap
+func (stats *deltaMapStatistics) countReadFile(bitmap *roaring.Bitmap) {
+	if stats == nil {
+		return
+	}
+	if bitmap != nil {
+		atomic.AddInt64(&stats.readFiles, 1)
+	} else {
+		atomic.AddInt64(&stats.scannedFiles, 1)
+	}
+}
+
+func (stats *deltaMapStatistics) logSummary() {
+	if stats == nil {
+		return
+	}
+	tracelog.InfoLogger.Printf("Delta map: %d files skipped entirely, %d files read by changed blocks, "+
+		"%d files scanned in full\n", atomic.LoadInt64(&stats.skippedFiles), atomic.LoadInt64(&stats.readFiles),
+		atomic.LoadInt64(&stats.scannedFiles))
+}
+
 // TarBallFilePacker is used to pack bundle file into tarball.
 type TarBallFilePacker struct {
 	deltaMap         PagedFileDeltaMap
+	deltaMapStats    *deltaMapStatistics
 	incrementFromLsn *uint64
 	files            BundleFiles
+	pushState        *backupPushState
 	options          TarBallFilePackerOptions
 }
 
-func newTarBallFilePacker(deltaMap PagedFileDeltaMap, incrementFromLsn *uint64, files BundleFiles,
-	options TarBallFilePackerOptions) *TarBallFilePacker {
+func newTarBallFilePacker(deltaMap PagedFileDeltaMap, deltaMapStats *deltaMapStatistics, incrementFromLsn *uint64,
+	files BundleFiles, pushState *backupPushState, options TarBallFilePackerOptions) *TarBallFilePacker {
 	return &TarBallFilePacker{
 		deltaMap:         deltaMap,
+		deltaMapStats:    deltaMapStats,
 		incrementFromLsn: incrementFromLsn,
 		files:            files,
+		pushState:        pushState,
 		options:          options,
 	}
 }
@@ -134,7 +173,11 @@ func (p *TarBallFilePacker) PackFileIntoTar(cfi *ComposeFileInfo, tarBall intern
 		return nil
 	})
 
-	return errorGroup.Wait()
+	if err = errorGroup.Wait(); err != nil {
+		return err
+	}
+	p.pushState.addPackedFile(tarBall.Name(), cfi, !cfi.isIncremented || p.deltaMap == nil)
+	return nil
 }
 
 func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadCloser, error) {
@@ -142,10 +185,14 @@ func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadC
 	if cfi.isIncremented {
 		bitmap, err := p.getDeltaBitmapFor(cfi.path)
 		if _, ok := err.(NoBitmapFoundError); ok { // this file has changed after the start of backup, so just skip it
+			p.deltaMapStats.countSkippedFile()
 			return nil, newSkippedFileError(cfi.path)
 		} else if err != nil {
-			return nil, errors.Wrapf(err, "PackFileIntoTar: failed to find corresponding bitmap '%s'\n", cfi.path)
+			// the delta map does not cover the file, so it is scanned for the changed pages
+			tracelog.WarningLogger.Printf("No delta map coverage for '%s', scanning the whole file: %v\n", cfi.path, err)
+			bitmap = nil
 		}
+		p.deltaMapStats.countReadFile(bitmap)
 		fileReadCloser, cfi.header.Size, err = ReadIncrementalFile(cfi.path, cfi.fileInfo.Size(), *p.incrementFromLsn, bitmap)
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, newFileNotExistError(cfi.path)
@@ -95,7 +95,13 @@ func HandleWALFetch(folder storage.Folder, walFileName string, location string,
 		time.Sleep(2 * time.Millisecond)
 	}
 
-	err := internal.DownloadFileTo(folder, walFileName, location)
+	err := internal.DownloadFileToWithChecksum(folder, walFileName, location, fetchWalChecksum(folder, walFileName))
+	if _, ok := err.(internal.ArchiveNonExistenceError); ok && viper.GetBool(internal.WalFetchPartialSetting) &&
+		isWalFilename(walFileName) {
+		if isFetched, partialErr := fetchPartialWalFile(folder, walFileName, location); isFetched || partialErr != nil {
+			err = partialErr
+		}
+	}
 	tracelog.ErrorLogger.FatalOnError(err)
 }
 
@@ -117,3 +123,17 @@ func checkWALFileMagic(prefetched string) error {
 
 	return nil
 }
+
+// fetchPartialWalFile serves the partial segment uploaded by wal-receive in place of the missing segment,
+// so the recovery replays the WAL received before the promotion. Returns false if there is no partial either.
+func fetchPartialWalFile(folder storage.Folder, walFileName string, location string) (bool, error) {
+	err := internal.DownloadFileTo(folder, walFileName+".partial", location)
+	if _, ok := err.(internal.ArchiveNonExistenceError); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	tracelog.WarningLogger.Printf("WAL file %s is not archived, its partial is fetched instead\n", walFileName)
+	return true, nil
+}
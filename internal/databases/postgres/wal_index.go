@@ -0,0 +1,757 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// WalIndexPath is the folder of the WAL index pages inside the WAL folder
+const WalIndexPath = "index/"
+
+// WalIndexPage lists the WAL segments of the timeline found in storage.
+// A page covers the segments of one xlog id (256 segments of 16 MB) and is named after its last segment,
+// so delete removes it along with its segments.
+type WalIndexPage struct {
+	Timeline    uint32          `json:"timeline"`
+	Ranges      []WalIndexRange `json:"ranges"`
+	Files       []WalIndexFile  `json:"files,omitempty"`
+	UpdatedTime time.Time       `json:"updated_time"`
+}
+
+// WalIndexRange is a run of consecutive segments stored with the same extension
+type WalIndexRange struct {
+	StartSegment string           `json:"start_segment"`
+	EndSegment   string           `json:"end_segment"`
+	Extension    string           `json:"extension"`
+	Objects      []WalIndexObject `json:"objects"`
+}
+
+// WalIndexObject describes the stored object of the segment.
+// The size and the modification time of the segments found by the lookups are unknown.
+type WalIndexObject struct {
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	// Metadata is the name of the WAL metadata object describing the segment, if the metadata is uploaded
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// WalIndexFile is the WAL folder object which is not a segment: the timeline history file, the backup history file
+// or the partial segment. It is listed in the page of its segment, the history file in the page of the timeline switch.
+type WalIndexFile struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// walIndexEntry is the segment listed in the index page
+type walIndexEntry struct {
+	extension string
+	WalIndexObject
+}
+
+// indexedWalObject is the segment object listed in the index or found by the lookup
+type indexedWalObject struct {
+	name string
+	WalIndexObject
+}
+
+func (object indexedWalObject) storageObject() storage.Object {
+	return storage.NewLocalObject(object.name, object.LastModified, object.Size)
+}
+
+func newWalIndexPage(timeline uint32) *WalIndexPage {
+	return &WalIndexPage{
+		Timeline:    timeline,
+		Ranges:      make([]WalIndexRange, 0),
+		UpdatedTime: utility.TimeNowCrossPlatformUTC(),
+	}
+}
+
+// add appends the segment to the page, the segments are added in order
+func (page *WalIndexPage) add(segmentNo WalSegmentNo, extension string, object WalIndexObject) {
+	if len(page.Ranges) > 0 {
+		lastRange := &page.Ranges[len(page.Ranges)-1]
+		_, endSegmentNo, err := ParseWALFilename(lastRange.EndSegment)
+		if err == nil && WalSegmentNo(endSegmentNo).next() == segmentNo && lastRange.Extension == extension {
+			lastRange.EndSegment = segmentNo.getFilename(page.Timeline)
+			lastRange.Objects = append(lastRange.Objects, object)
+			return
+		}
+	}
+	segmentName := segmentNo.getFilename(page.Timeline)
+	page.Ranges = append(page.Ranges, WalIndexRange{segmentName, segmentName, extension, []WalIndexObject{object}})
+}
+
+// entries returns the segments listed in the page
+func (page *WalIndexPage) entries() (map[WalSegmentNo]walIndexEntry, error) {
+	entries := make(map[WalSegmentNo]walIndexEntry)
+	for _, segmentRange := range page.Ranges {
+		_, startSegmentNo, err := ParseWALFilename(segmentRange.StartSegment)
+		if err != nil {
+			return nil, err
+		}
+		_, endSegmentNo, err := ParseWALFilename(segmentRange.EndSegment)
+		if err != nil {
+			return nil, err
+		}
+		for segmentNo := WalSegmentNo(startSegmentNo); segmentNo <= WalSegmentNo(endSegmentNo); segmentNo++ {
+			entry := walIndexEntry{extension: segmentRange.Extension}
+			if i := int(segmentNo - WalSegmentNo(startSegmentNo)); i < len(segmentRange.Objects) {
+				entry.WalIndexObject = segmentRange.Objects[i]
+			}
+			entries[segmentNo] = entry
+		}
+	}
+	return entries, nil
+}
+
+// setEntries replaces the segments listed in the page
+func (page *WalIndexPage) setEntries(entries map[WalSegmentNo]walIndexEntry) {
+	numbers := make([]WalSegmentNo, 0, len(entries))
+	for segmentNo := range entries {
+		numbers = append(numbers, segmentNo)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	page.Ranges = make([]WalIndexRange, 0)
+	for _, segmentNo := range numbers {
+		page.add(segmentNo, entries[segmentNo].extension, entries[segmentNo].WalIndexObject)
+	}
+	page.UpdatedTime = utility.TimeNowCrossPlatformUTC()
+}
+
+// addFile adds the file to the page, replacing the earlier upload of it
+func (page *WalIndexPage) addFile(file WalIndexFile) {
+	page.removeFiles(file.Name)
+	page.Files = append(page.Files, file)
+	page.UpdatedTime = utility.TimeNowCrossPlatformUTC()
+}
+
+// removeFiles removes the files with the name prefix from the page
+func (page *WalIndexPage) removeFiles(prefix string) {
+	files := make([]WalIndexFile, 0, len(page.Files))
+	for _, file := range page.Files {
+		if !strings.HasPrefix(file.Name, prefix) {
+			files = append(files, file)
+		}
+	}
+	page.Files = files
+}
+
+func (page *WalIndexPage) fileObjects() []storage.Object {
+	objects := make([]storage.Object, 0, len(page.Files))
+	for _, file := range page.Files {
+		objects = append(objects, storage.NewLocalObject(file.Name, file.LastModified, file.Size))
+	}
+	return objects
+}
+
+// objects returns the segment objects listed in the page
+func (page *WalIndexPage) objects() (map[WalSegmentDescription]indexedWalObject, error) {
+	entries, err := page.entries()
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[WalSegmentDescription]indexedWalObject, len(entries))
+	for segmentNo, entry := range entries {
+		segment := WalSegmentDescription{Number: segmentNo, Timeline: page.Timeline}
+		objects[segment] = indexedWalObject{segment.GetFileName() + "." + entry.extension, entry.WalIndexObject}
+	}
+	return objects, nil
+}
+
+func isWalIndexEnabled() bool {
+	return viper.GetBool(internal.UseWalIndexSetting)
+}
+
+func walIndexPageName(timeline uint32, segmentNo WalSegmentNo) string {
+	lastSegmentNo := WalSegmentNo((uint64(segmentNo)/xLogSegmentsPerXLogID+1)*xLogSegmentsPerXLogID - 1)
+	return lastSegmentNo.getFilename(timeline) + ".json"
+}
+
+// walIndexFileSegment returns the segment of the index page listing the file: the segment of the partial
+// or of the backup history file, the segment of the timeline switch for the timeline history file
+func walIndexFileSegment(walFolder storage.Folder, fileName string) (WalSegmentDescription, bool, error) {
+	if len(fileName) > 24 {
+		if timeline, segmentNo, err := ParseWALFilename(fileName[:24]); err == nil {
+			return WalSegmentDescription{Number: WalSegmentNo(segmentNo), Timeline: timeline}, true, nil
+		}
+	}
+	match := timelineHistoryFileRegexp.FindStringSubmatch(fileName)
+	if match == nil {
+		return WalSegmentDescription{}, false, nil
+	}
+	timeline, err := strconv.ParseUint(match[1], 0x10, sizeofInt32bits)
+	if err != nil {
+		return WalSegmentDescription{}, false, nil
+	}
+	historyRecords, err := getTimeLineHistoryRecords(uint32(timeline), walFolder)
+	if err != nil {
+		return WalSegmentDescription{}, false, err
+	}
+	segment := WalSegmentDescription{Timeline: uint32(timeline)}
+	if len(historyRecords) > 0 {
+		segment.Number = newWalSegmentNo(historyRecords[len(historyRecords)-1].lsn)
+	}
+	return segment, true, nil
+}
+
+func isLastPageSegment(segmentNo WalSegmentNo) bool {
+	return (uint64(segmentNo)+1)%xLogSegmentsPerXLogID == 0
+}
+
+// findWalObject returns the name of the stored object of the WAL file, trying the extensions in order
+func findWalObject(walFolder storage.Folder, walFileName string, extensions []string) (string, bool, error) {
+	for _, extension := range extensions {
+		objectName := walFileName + "." + extension
+		exists, err := walFolder.Exists(objectName)
+		if err != nil || exists {
+			return objectName, exists, err
+		}
+	}
+	return "", false, nil
+}
+
+// walObjectExtensions returns the extensions of all the compressors, the preferred ones go first
+func walObjectExtensions(preferred ...string) []string {
+	extensions := append([]string{}, preferred...)
+	for _, decompressor := range compression.Decompressors {
+		extensions = append(extensions, decompressor.FileExtension())
+	}
+	return uniqueExtensions(extensions)
+}
+
+func uniqueExtensions(extensions []string) []string {
+	unique := make([]string, 0, len(extensions))
+	isAdded := make(map[string]bool)
+	for _, extension := range extensions {
+		if !isAdded[extension] {
+			unique = append(unique, extension)
+			isAdded[extension] = true
+		}
+	}
+	return unique
+}
+
+func putWalIndexPage(walFolder storage.Folder, pageName string, page *WalIndexPage) error {
+	pageBody, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	return walFolder.GetSubFolder(WalIndexPath).PutObject(pageName, bytes.NewReader(pageBody))
+}
+
+// walIndexMutex serializes the updates of the index pages by the concurrent uploads of the process.
+// The pages are read, modified and written back with no lock in storage, so an update made by another process
+// at the same time, e.g. by wal-push of another host, may be lost. The readers look the segments missing in the pages up,
+// the files missing in the pages are found by listing only.
+var walIndexMutex sync.Mutex
+
+// updateWalIndex adds the uploaded WAL file to its index page. Once the last segment of the page is uploaded,
+// the page is completed with the segments uploaded without the index, e.g. by the other hosts or before it was turned on.
+func updateWalIndex(uploader *internal.Uploader, walFileName string) error {
+	if !isWalIndexEnabled() {
+		return nil
+	}
+	extension := uploader.Compressor.FileExtension()
+	objectName := walFileName + "." + extension
+	size, _ := uploader.TakeObjectSize(objectName)
+	timeline, segmentNo, err := ParseWALFilename(walFileName)
+	if err != nil {
+		return updateWalIndexFile(uploader.UploadingFolder, walFileName,
+			WalIndexFile{Name: objectName, Size: size, LastModified: utility.TimeNowCrossPlatformUTC()})
+	}
+	object := WalIndexObject{
+		Size:         size,
+		LastModified: utility.TimeNowCrossPlatformUTC(),
+		Metadata:     walMetadataObjectName(walFileName),
+	}
+
+	walIndexMutex.Lock()
+	defer walIndexMutex.Unlock()
+	walFolder := uploader.UploadingFolder
+	pageName := walIndexPageName(timeline, WalSegmentNo(segmentNo))
+	page, err := readOrNewWalIndexPage(walFolder, pageName, timeline)
+	if err != nil {
+		return err
+	}
+	entries, err := page.entries()
+	if err != nil {
+		return err
+	}
+	entries[WalSegmentNo(segmentNo)] = walIndexEntry{extension, object}
+	// wal-receive deletes the partial once the segment is uploaded in full
+	page.removeFiles(walFileName + ".partial")
+	if isLastPageSegment(WalSegmentNo(segmentNo)) {
+		tracelog.InfoLogger.Printf("Completing WAL index page %s\n", pageName)
+		err = completeWalIndexPage(walFolder, timeline, WalSegmentNo(segmentNo), entries, walObjectExtensions(extension))
+		if err != nil {
+			return err
+		}
+	}
+	page.setEntries(entries)
+	return putWalIndexPage(walFolder, pageName, page)
+}
+
+// updateWalIndexFile adds the uploaded file which is not a segment to the index page of its segment.
+// The files of unknown names are not indexed.
+func updateWalIndexFile(walFolder storage.Folder, fileName string, file WalIndexFile) error {
+	segment, ok, err := walIndexFileSegment(walFolder, fileName)
+	if err != nil || !ok {
+		return err
+	}
+	walIndexMutex.Lock()
+	defer walIndexMutex.Unlock()
+	pageName := walIndexPageName(segment.Timeline, segment.Number)
+	page, err := readOrNewWalIndexPage(walFolder, pageName, segment.Timeline)
+	if err != nil {
+		return err
+	}
+	page.addFile(file)
+	return putWalIndexPage(walFolder, pageName, page)
+}
+
+func readOrNewWalIndexPage(walFolder storage.Folder, pageName string, timeline uint32) (*WalIndexPage, error) {
+	page, err := readWalIndexPage(walFolder.GetSubFolder(WalIndexPath), pageName)
+	if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
+		return newWalIndexPage(timeline), nil
+	}
+	return page, err
+}
+
+// completeWalIndexPage looks up the segments of the page missing in the index
+func completeWalIndexPage(walFolder storage.Folder, timeline uint32, lastSegmentNo WalSegmentNo,
+	entries map[WalSegmentNo]walIndexEntry, extensions []string) error {
+	for segmentNo := lastSegmentNo + 1 - WalSegmentNo(xLogSegmentsPerXLogID); segmentNo < lastSegmentNo; segmentNo++ {
+		if _, ok := entries[segmentNo]; ok {
+			continue
+		}
+		objectName, exists, err := findWalObject(walFolder, segmentNo.getFilename(timeline), extensions)
+		if err != nil {
+			return err
+		}
+		if exists {
+			entries[segmentNo] = walIndexEntry{extension: utility.GetFileExtension(objectName)}
+		}
+	}
+	return nil
+}
+
+// BuildWalIndex writes the index pages of all the WAL segments in storage
+func BuildWalIndex(walFolder storage.Folder) error {
+	objects, _, err := walFolder.ListFolder()
+	if err != nil {
+		return err
+	}
+	segments := make([]WalSegmentDescription, 0, len(objects))
+	segmentObjects := make(map[WalSegmentDescription]storage.Object, len(objects))
+	objectNames := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		objectNames[object.GetName()] = true
+	}
+	pages := make(map[string]*WalIndexPage)
+	for _, object := range objects {
+		if utility.GetFileExtension(object.GetName()) == "json" {
+			// the WAL metadata
+			continue
+		}
+		fileName := utility.TrimFileExtension(object.GetName())
+		segment, err := NewWalSegmentDescription(fileName)
+		if err == nil {
+			segments = append(segments, segment)
+			segmentObjects[segment] = object
+			continue
+		}
+		segment, ok, err := walIndexFileSegment(walFolder, fileName)
+		if err != nil {
+			return err
+		}
+		if ok {
+			pageName := walIndexPageName(segment.Timeline, segment.Number)
+			if _, ok := pages[pageName]; !ok {
+				pages[pageName] = newWalIndexPage(segment.Timeline)
+			}
+			pages[pageName].addFile(WalIndexFile{object.GetName(), object.GetSize(), object.GetLastModified()})
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Timeline < segments[j].Timeline ||
+			segments[i].Timeline == segments[j].Timeline && segments[i].Number < segments[j].Number
+	})
+
+	for _, segment := range segments {
+		pageName := walIndexPageName(segment.Timeline, segment.Number)
+		if _, ok := pages[pageName]; !ok {
+			pages[pageName] = newWalIndexPage(segment.Timeline)
+		}
+		object := segmentObjects[segment]
+		indexObject := WalIndexObject{Size: object.GetSize(), LastModified: object.GetLastModified()}
+		// the metadata of the segment is uploaded either individually or in bulk
+		fileName := segment.GetFileName()
+		for _, metadataName := range []string{fileName + ".json", fileName[:len(fileName)-1] + ".json"} {
+			if objectNames[metadataName] {
+				indexObject.Metadata = metadataName
+			}
+		}
+		pages[pageName].add(segment.Number, utility.GetFileExtension(object.GetName()), indexObject)
+	}
+	for pageName, page := range pages {
+		if err = putWalIndexPage(walFolder, pageName, page); err != nil {
+			return err
+		}
+	}
+	tracelog.InfoLogger.Printf("Written %d WAL index pages for %d segments\n", len(pages), len(segments))
+	return nil
+}
+
+// HandleWalIndexBuild writes the WAL index pages of the segments uploaded before the index was turned on
+func HandleWalIndexBuild(rootFolder storage.Folder) {
+	err := BuildWalIndex(rootFolder.GetSubFolder(utility.WalPath))
+	tracelog.ErrorLogger.FatalfOnError("Failed to build the WAL index: %v\n", err)
+}
+
+// getWalFolderFilenames returns the names of the WAL folder objects
+func getWalFolderFilenames(walFolder storage.Folder) ([]string, error) {
+	if !isWalIndexEnabled() {
+		return getFolderFilenames(walFolder)
+	}
+	objects, err := getWalFolderObjects(walFolder)
+	if err != nil {
+		return nil, err
+	}
+	filenames := make([]string, 0, len(objects))
+	for _, object := range objects {
+		filenames = append(filenames, object.GetName())
+	}
+	return filenames, nil
+}
+
+// getWalFolderObjects returns the WAL folder objects. With the WAL index turned on,
+// the segments and their metadata are taken from the index pages and only the segments not in the pages are looked up:
+// the gaps in the pages, the tail after the last page of each timeline and the timelines started after them.
+func getWalFolderObjects(walFolder storage.Folder) ([]storage.Object, error) {
+	if !isWalIndexEnabled() {
+		objects, _, err := walFolder.ListFolder()
+		return objects, err
+	}
+	indexedObjects, indexedFiles, err := readWalIndexPages(walFolder)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexedObjects) == 0 && len(indexedFiles) == 0 {
+		tracelog.InfoLogger.Println("WAL index is empty, listing the WAL folder")
+		objects, _, err := walFolder.ListFolder()
+		return objects, err
+	}
+	tailLookupLimit, err := internal.GetMaxUploadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	lookup := &walIndexLookup{
+		walFolder:       walFolder,
+		objects:         indexedObjects,
+		files:           indexedFiles,
+		tailLookupLimit: tailLookupLimit,
+	}
+	return lookup.storageObjects()
+}
+
+// ListObjectsWithWalIndex lists the folder recursively like storage.ListFolderRecursively,
+// but takes the objects of the WAL folder from the WAL index if it is turned on
+func ListObjectsWithWalIndex(folder storage.Folder) ([]storage.Object, error) {
+	if !isWalIndexEnabled() {
+		return storage.ListFolderRecursively(folder)
+	}
+	objects, subFolders, err := folder.ListFolder()
+	if err != nil {
+		return nil, err
+	}
+	for _, subFolder := range subFolders {
+		subFolderPath := strings.TrimPrefix(subFolder.GetPath(), folder.GetPath())
+		var subFolderObjects []storage.Object
+		if subFolderPath == utility.WalPath {
+			subFolderObjects, err = listWalFolderWithIndex(subFolder)
+		} else {
+			subFolderObjects, err = storage.ListFolderRecursively(subFolder)
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, prefixObjectNames(subFolderObjects, subFolderPath)...)
+	}
+	return objects, nil
+}
+
+// listWalFolderWithIndex returns the WAL folder objects along with the index pages
+func listWalFolderWithIndex(walFolder storage.Folder) ([]storage.Object, error) {
+	objects, err := getWalFolderObjects(walFolder)
+	if err != nil {
+		return nil, err
+	}
+	pageObjects, err := storage.ListFolderRecursively(walFolder.GetSubFolder(WalIndexPath))
+	if err != nil {
+		return nil, err
+	}
+	return append(objects, prefixObjectNames(pageObjects, WalIndexPath)...), nil
+}
+
+func prefixObjectNames(objects []storage.Object, prefix string) []storage.Object {
+	prefixed := make([]storage.Object, 0, len(objects))
+	for _, object := range objects {
+		prefixed = append(prefixed,
+			storage.NewLocalObject(path.Join(prefix, object.GetName()), object.GetLastModified(), object.GetSize()))
+	}
+	return prefixed
+}
+
+// readWalIndexPages reads the index pages concurrently
+func readWalIndexPages(walFolder storage.Folder) (map[WalSegmentDescription]indexedWalObject, []storage.Object, error) {
+	indexFolder := walFolder.GetSubFolder(WalIndexPath)
+	pageObjects, _, err := indexFolder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	pageNames := make([]string, 0, len(pageObjects))
+	for _, pageObject := range pageObjects {
+		pageNames = append(pageNames, pageObject.GetName())
+	}
+	return readWalIndexPagesByName(indexFolder, pageNames, false)
+}
+
+// readWalIndexRange reads the index pages of the segments [first, last) of the timeline.
+// The missing pages are skipped, the segments not found in the index are looked up by the callers.
+func readWalIndexRange(walFolder storage.Folder, timeline uint32,
+	first, last WalSegmentNo) (map[WalSegmentDescription]indexedWalObject, error) {
+	pageNames := make([]string, 0)
+	for segmentNo := first; segmentNo < last; segmentNo++ {
+		pageName := walIndexPageName(timeline, segmentNo)
+		if len(pageNames) == 0 || pageNames[len(pageNames)-1] != pageName {
+			pageNames = append(pageNames, pageName)
+		}
+	}
+	objects, _, err := readWalIndexPagesByName(walFolder.GetSubFolder(WalIndexPath), pageNames, true)
+	return objects, err
+}
+
+// readWalIndexPagesByName returns the segments and the other files listed in the pages
+func readWalIndexPagesByName(indexFolder storage.Folder, pageNames []string,
+	skipMissing bool) (map[WalSegmentDescription]indexedWalObject, []storage.Object, error) {
+	concurrency, err := internal.GetMaxDownloadConcurrency()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objects := make(map[WalSegmentDescription]indexedWalObject)
+	files := make([]storage.Object, 0)
+	var mutex sync.Mutex
+	var firstErr error
+	pageNamesChannel := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pageName := range pageNamesChannel {
+				page, err := readWalIndexPage(indexFolder, pageName)
+				if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok && skipMissing {
+					continue
+				}
+				var pageObjects map[WalSegmentDescription]indexedWalObject
+				if err == nil {
+					pageObjects, err = page.objects()
+				}
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				for segment, object := range pageObjects {
+					objects[segment] = object
+				}
+				if page != nil {
+					files = append(files, page.fileObjects()...)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, pageName := range pageNames {
+		pageNamesChannel <- pageName
+	}
+	close(pageNamesChannel)
+	wg.Wait()
+	return objects, files, firstErr
+}
+
+func readWalIndexPage(indexFolder storage.Folder, pageName string) (*WalIndexPage, error) {
+	reader, err := indexFolder.ReadObject(pageName)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+	var page WalIndexPage
+	if err = json.NewDecoder(reader).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to parse WAL index page %s: %v", pageName, err)
+	}
+	return &page, nil
+}
+
+// walIndexLookup completes the segments of the index pages with the lookups in storage
+type walIndexLookup struct {
+	walFolder       storage.Folder
+	objects         map[WalSegmentDescription]indexedWalObject
+	files           []storage.Object
+	extensions      []string
+	tailLookupLimit int
+}
+
+// storageObjects returns the segments, their metadata, the indexed files
+// and the history files of the timelines started after the index pages were written
+func (lookup *walIndexLookup) storageObjects() ([]storage.Object, error) {
+	// the segments are looked up with the extensions of the indexed ones and of the configured compression only
+	segmentNumbers := make(map[uint32][]WalSegmentNo)
+	extensions := make([]string, 0)
+	for segment, object := range lookup.objects {
+		segmentNumbers[segment.Timeline] = append(segmentNumbers[segment.Timeline], segment.Number)
+		extensions = append(extensions, utility.GetFileExtension(object.name))
+	}
+	if compressor, err := internal.ConfigureCompressor(); err == nil {
+		extensions = append(extensions, compressor.FileExtension())
+	}
+	lookup.extensions = uniqueExtensions(extensions)
+
+	var highestTimeline uint32
+	for timeline, numbers := range segmentNumbers {
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+		numbers, err := lookup.dropDeletedSegments(timeline, numbers)
+		if err != nil {
+			return nil, err
+		}
+		if len(numbers) == 0 {
+			continue
+		}
+		for segmentNo := numbers[0]; segmentNo < numbers[len(numbers)-1]; segmentNo++ {
+			if _, err := lookup.find(WalSegmentDescription{Number: segmentNo, Timeline: timeline}); err != nil {
+				return nil, err
+			}
+		}
+		if err = lookup.findTail(timeline, numbers[len(numbers)-1].next()); err != nil {
+			return nil, err
+		}
+		if highestTimeline < timeline {
+			highestTimeline = timeline
+		}
+	}
+
+	objects, err := lookup.findNewTimelines(highestTimeline + 1)
+	if err != nil {
+		return nil, err
+	}
+	// the history file of the new timeline may be indexed along with no segment
+	objectNames := make(map[string]bool)
+	for _, object := range objects {
+		objectNames[object.GetName()] = true
+	}
+	for _, file := range lookup.files {
+		if !objectNames[file.GetName()] {
+			objects = append(objects, file)
+		}
+	}
+	metadataNames := make(map[string]bool)
+	for _, object := range lookup.objects {
+		objects = append(objects, object.storageObject())
+		if object.Metadata != "" && !metadataNames[object.Metadata] {
+			metadataNames[object.Metadata] = true
+			objects = append(objects, storage.NewLocalObject(object.Metadata, object.LastModified, 0))
+		}
+	}
+	return objects, nil
+}
+
+// find looks the segment up in storage, unless it is already known
+func (lookup *walIndexLookup) find(segment WalSegmentDescription) (bool, error) {
+	if _, ok := lookup.objects[segment]; ok {
+		return true, nil
+	}
+	objectName, exists, err := findWalObject(lookup.walFolder, segment.GetFileName(), lookup.extensions)
+	if exists {
+		lookup.objects[segment] = indexedWalObject{name: objectName}
+	}
+	return exists, err
+}
+
+// findTail looks the segments up starting with the given one, until the limit of missing segments in a row
+func (lookup *walIndexLookup) findTail(timeline uint32, segmentNo WalSegmentNo) error {
+	for missing := 0; missing < lookup.tailLookupLimit; segmentNo++ {
+		exists, err := lookup.find(WalSegmentDescription{Number: segmentNo, Timeline: timeline})
+		if err != nil {
+			return err
+		}
+		if exists {
+			missing = 0
+		} else {
+			missing++
+		}
+	}
+	return nil
+}
+
+// dropDeletedSegments drops the first segments of the timeline deleted after the page was written.
+// Delete removes the oldest segments, so the first existing one is found with the binary search.
+func (lookup *walIndexLookup) dropDeletedSegments(timeline uint32, numbers []WalSegmentNo) ([]WalSegmentNo, error) {
+	var lookupErr error
+	firstExisting := sort.Search(len(numbers), func(i int) bool {
+		segment := WalSegmentDescription{Number: numbers[i], Timeline: timeline}
+		exists, err := lookup.walFolder.Exists(lookup.objects[segment].name)
+		if err != nil && lookupErr == nil {
+			lookupErr = err
+		}
+		return exists
+	})
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	for _, segmentNo := range numbers[:firstExisting] {
+		delete(lookup.objects, WalSegmentDescription{Number: segmentNo, Timeline: timeline})
+	}
+	return numbers[firstExisting:], nil
+}
+
+// findNewTimelines looks up the timelines started after the index pages were written by their history files
+func (lookup *walIndexLookup) findNewTimelines(timeline uint32) ([]storage.Object, error) {
+	objects := make([]storage.Object, 0)
+	for ; ; timeline++ {
+		historyName, exists, err := findWalObject(lookup.walFolder, fmt.Sprintf("%08X.history", timeline), lookup.extensions)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return objects, nil
+		}
+		objects = append(objects, storage.NewLocalObject(historyName, time.Time{}, 0))
+		historyRecords, err := getTimeLineHistoryRecords(timeline, lookup.walFolder)
+		if err != nil {
+			return nil, err
+		}
+		var switchSegmentNo WalSegmentNo
+		if len(historyRecords) > 0 {
+			switchSegmentNo = newWalSegmentNo(historyRecords[len(historyRecords)-1].lsn)
+		}
+		if err = lookup.findTail(timeline, switchSegmentNo); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func setupWalIndexTest(segmentNames []string) storage.Folder {
+	viper.Set(internal.UseWalIndexSetting, true)
+	walFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.WalPath)
+	for _, name := range segmentNames {
+		_ = walFolder.PutObject(name+".lz4", &bytes.Buffer{})
+	}
+	return walFolder
+}
+
+func segmentRangeNames(timeline uint32, first, last WalSegmentNo) []string {
+	names := make([]string, 0)
+	for segmentNo := first; segmentNo <= last; segmentNo++ {
+		names = append(names, segmentNo.getFilename(timeline)+".lz4")
+	}
+	return names
+}
+
+func uploadIndexedWalFile(t *testing.T, uploader *WalUploader, walFileName string) {
+	err := uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(strings.NewReader(walFileName), walFileName))
+	assert.NoError(t, err)
+	assert.NoError(t, updateWalIndex(uploader.Uploader, walFileName))
+}
+
+func TestUpdateWalIndex_CompletesPageOnLastSegment(t *testing.T) {
+	defer viper.Set(internal.UseWalIndexSetting, false)
+	// uploaded before the index was turned on
+	walFolder := setupWalIndexTest([]string{"000000010000000000000001", "000000010000000000000002"})
+	uploader := NewWalUploader(lz4.Compressor{}, walFolder, nil)
+
+	uploadIndexedWalFile(t, uploader, "000000010000000000000004")
+	page, err := readWalIndexPage(walFolder.GetSubFolder(WalIndexPath), "0000000100000000000000FF.json")
+	assert.NoError(t, err)
+	objects, err := page.objects()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(objects))
+	object := objects[WalSegmentDescription{Number: 4, Timeline: 1}]
+	assert.Equal(t, "000000010000000000000004.lz4", object.name)
+	assert.True(t, object.Size > 0)
+	assert.False(t, object.LastModified.IsZero())
+
+	uploadIndexedWalFile(t, uploader, "0000000100000000000000FF")
+	page, err = readWalIndexPage(walFolder.GetSubFolder(WalIndexPath), "0000000100000000000000FF.json")
+	assert.NoError(t, err)
+	objects, err = page.objects()
+	assert.NoError(t, err)
+	objectNames := make(map[WalSegmentDescription]string)
+	for segment, object := range objects {
+		objectNames[segment] = object.name
+	}
+	assert.Equal(t, map[WalSegmentDescription]string{
+		{Number: 1, Timeline: 1}:    "000000010000000000000001.lz4",
+		{Number: 2, Timeline: 1}:    "000000010000000000000002.lz4",
+		{Number: 4, Timeline: 1}:    "000000010000000000000004.lz4",
+		{Number: 0xFF, Timeline: 1}: "0000000100000000000000FF.lz4",
+	}, objectNames)
+	// the size of the segment uploaded earlier is kept
+	assert.Equal(t, object.Size, objects[WalSegmentDescription{Number: 4, Timeline: 1}].Size)
+}
+
+func TestListObjectsWithWalIndex(t *testing.T) {
+	defer viper.Set(internal.UseWalIndexSetting, false)
+	viper.Set(internal.UseWalIndexSetting, true)
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	_ = walFolder.PutObject("000000010000000000000001.lz4", &bytes.Buffer{})
+	_ = walFolder.PutObject("000000010000000000000002.lz4", &bytes.Buffer{})
+	_ = walFolder.PutObject("000000010000000000000001.json", strings.NewReader("{}"))
+	assert.NoError(t, BuildWalIndex(walFolder))
+	// not uploaded by wal-push, so it is found by listing only
+	_ = walFolder.PutObject("unknown", &bytes.Buffer{})
+	_ = rootFolder.GetSubFolder(utility.BaseBackupPath).PutObject("base_000000010000000000000002_backup_stop_sentinel.json",
+		&bytes.Buffer{})
+
+	objects, err := ListObjectsWithWalIndex(rootFolder)
+	assert.NoError(t, err)
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		utility.BaseBackupPath + "base_000000010000000000000002_backup_stop_sentinel.json",
+		utility.WalPath + "000000010000000000000001.json",
+		utility.WalPath + "000000010000000000000001.lz4",
+		utility.WalPath + "000000010000000000000002.lz4",
+		utility.WalPath + WalIndexPath + "0000000100000000000000FF.json",
+	}, names)
+}
+
+func TestGetWalFolderFilenames_LooksUpSegmentsAfterIndex(t *testing.T) {
+	defer viper.Set(internal.UseWalIndexSetting, false)
+	walFolder := setupWalIndexTest(nil)
+	for _, name := range segmentRangeNames(1, 1, 0x102) {
+		_ = walFolder.PutObject(name, &bytes.Buffer{})
+	}
+	assert.NoError(t, BuildWalIndex(walFolder))
+	// uploaded after the index was built
+	for _, name := range segmentRangeNames(1, 0x103, 0x105) {
+		_ = walFolder.PutObject(name, &bytes.Buffer{})
+	}
+
+	filenames, err := getWalFolderFilenames(walFolder)
+	assert.NoError(t, err)
+	sort.Strings(filenames)
+	assert.Equal(t, segmentRangeNames(1, 1, 0x105), filenames)
+}
+
+func TestGetWalFolderFilenames_DropsDeletedSegments(t *testing.T) {
+	defer viper.Set(internal.UseWalIndexSetting, false)
+	walFolder := setupWalIndexTest(nil)
+	for _, name := range segmentRangeNames(1, 1, 0x20) {
+		_ = walFolder.PutObject(name, &bytes.Buffer{})
+	}
+	assert.NoError(t, BuildWalIndex(walFolder))
+	assert.NoError(t, walFolder.DeleteObjects(segmentRangeNames(1, 1, 0x10)))
+
+	filenames, err := getWalFolderFilenames(walFolder)
+	assert.NoError(t, err)
+	sort.Strings(filenames)
+	assert.Equal(t, segmentRangeNames(1, 0x11, 0x20), filenames)
+}
+
+func TestGetWalFolderFilenames_IndexedFiles(t *testing.T) {
+	defer viper.Set(internal.UseWalIndexSetting, false)
+	walFolder := setupWalIndexTest(nil)
+	uploader := NewWalUploader(lz4.Compressor{}, walFolder, nil)
+	uploadIndexedWalFile(t, uploader, "000000010000000000000001")
+	uploadIndexedWalFile(t, uploader, "000000010000000000000001.00000028.backup")
+	uploadIndexedWalFile(t, uploader, "000000010000000000000002")
+	err := uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(
+		strings.NewReader("1\t0/2000100\tno recovery target specified\n"), "00000002.history"))
+	assert.NoError(t, err)
+	assert.NoError(t, updateWalIndex(uploader.Uploader, "00000002.history"))
+	uploadIndexedWalFile(t, uploader, "000000020000000000000003.partial")
+	// the timeline switch page lists the history file
+	page, err := readWalIndexPage(walFolder.GetSubFolder(WalIndexPath), "0000000200000000000000FF.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "00000002.history.lz4", page.Files[0].Name)
+
+	filenames, err := getWalFolderFilenames(walFolder)
+	assert.NoError(t, err)
+	sort.Strings(filenames)
+	assert.Equal(t, []string{
+		"000000010000000000000001.00000028.backup.lz4",
+		"000000010000000000000001.lz4",
+		"000000010000000000000002.lz4",
+		"00000002.history.lz4",
+		"000000020000000000000003.partial.lz4",
+	}, filenames)
+
+	// the partial is deleted once the segment is received in full
+	uploadIndexedWalFile(t, uploader, "000000020000000000000003")
+	assert.NoError(t, walFolder.DeleteObjects([]string{"000000020000000000000003.partial.lz4"}))
+	filenames, err = getWalFolderFilenames(walFolder)
+	assert.NoError(t, err)
+	assert.NotContains(t, filenames, "000000020000000000000003.partial.lz4")
+	assert.Contains(t, filenames, "000000020000000000000003.lz4")
+
+	// the index is rebuilt with the same files
+	assert.NoError(t, BuildWalIndex(walFolder))
+	rebuiltFilenames, err := getWalFolderFilenames(walFolder)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, filenames, rebuiltFilenames)
+}
+
+func TestGetWalFolderFilenames_ListsFolderWithoutIndex(t *testing.T) {
+	defer viper.Set(internal.UseWalIndexSetting, false)
+	walFolder := setupWalIndexTest([]string{"000000010000000000000001", "000000010000000000000003"})
+
+	filenames, err := getWalFolderFilenames(walFolder)
+	assert.NoError(t, err)
+	sort.Strings(filenames)
+	assert.Equal(t, []string{"000000010000000000000001.lz4", "000000010000000000000003.lz4"}, filenames)
+}
+
+func TestIsPermanent_WalIndexPage(t *testing.T) {
+	permanentWals := map[string]bool{"000000010000000100000010": true}
+
+	assert.True(t, IsPermanent(utility.WalPath+WalIndexPath+"0000000100000001000000FF.json", nil, permanentWals))
+	assert.False(t, IsPermanent(utility.WalPath+WalIndexPath+"0000000100000000000000FF.json", nil, permanentWals))
+}
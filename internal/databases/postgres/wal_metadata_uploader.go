@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/wal-g/wal-g/internal"
@@ -14,7 +15,9 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"github.com/wal-g/storages/fs"
+	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
 )
 
 const (
@@ -25,9 +28,15 @@ const (
 
 var WalMetadataLevels = []string{WalBulkMetadataLevel, WalIndividualMetadataLevel, WalNoMetadataLevel}
 
+// walChecksumSuffix is the suffix of the local files which keep the checksums of the WAL files
+// uploaded in background until wal-push uploads their metadata
+const walChecksumSuffix = ".sha256"
+
 type WalMetadataDescription struct {
 	CreatedTime    time.Time `json:"created_time"`
 	DatetimeFormat string    `json:"date_fmt"`
+	// Sha256 is the checksum of the uploaded WAL object (compressed and encrypted)
+	Sha256 string `json:"sha256,omitempty"`
 }
 
 type WalMetadataUploader struct {
@@ -56,6 +65,7 @@ func (u *WalMetadataUploader) UploadWalMetadata(walFileName string, createdTime
 	walMetadataName := walFileName + ".json"
 	walMetadata.DatetimeFormat = MetadataDatetimeFormat
 	walMetadata.CreatedTime = createdTime
+	walMetadata.Sha256 = takeWalChecksum(walFileName, uploader)
 	walMetadataMap[walFileName] = walMetadata
 
 	dtoBody, err := json.Marshal(walMetadataMap)
@@ -70,6 +80,7 @@ func (u *WalMetadataUploader) UploadWalMetadata(walFileName string, createdTime
 		err = u.uploadBulkMetadataFile(walFileName, uploader)
 	} else {
 		err = uploader.Upload(walMetadataName, bytes.NewReader(dtoBody))
+		uploader.TakeObjectChecksums(walMetadataName)
 	}
 	return errors.Wrapf(err, "upload: could not Upload metadata'%s'\n", walFileName)
 }
@@ -112,7 +123,9 @@ func (u *WalMetadataUploader) uploadBulkMetadataFile(walFileName string, uploade
 	if err != nil {
 		return err
 	}
-	if err = uploader.Upload(walSearchString+".json", bytes.NewReader(dtoBody)); err != nil {
+	err = uploader.Upload(walSearchString+".json", bytes.NewReader(dtoBody))
+	uploader.TakeObjectChecksums(walSearchString + ".json")
+	if err != nil {
 		return err
 	}
 	//Deleting the temporary metadata files created
@@ -124,6 +137,98 @@ func (u *WalMetadataUploader) uploadBulkMetadataFile(walFileName string, uploade
 	return errors.Wrapf(err, "Unable to upload bulk wal metadata %s", walFileName)
 }
 
+// saveWalChecksum keeps the checksum of the WAL file uploaded in background,
+// wal-push of this file finds it uploaded and only uploads the metadata
+func saveWalChecksum(walFileName string, uploader *internal.Uploader) error {
+	if viper.GetString(internal.UploadWalMetadata) == WalNoMetadataLevel {
+		forgetWalChecksum(walFileName, uploader)
+		return nil
+	}
+	objectName := walObjectName(walFileName, uploader)
+	checksum, ok := uploader.TakeObjectChecksums(objectName)[objectName]
+	if !ok {
+		return nil
+	}
+	folder := fs.NewFolder(internal.GetRelativeArchiveDataFolderPath(), "")
+	return folder.PutObject(walFileName+walChecksumSuffix, strings.NewReader(checksum))
+}
+
+// takeWalChecksum returns the checksum of the WAL file uploaded by this or by the background uploader
+func takeWalChecksum(walFileName string, uploader *internal.Uploader) string {
+	objectName := walObjectName(walFileName, uploader)
+	if checksum, ok := uploader.TakeObjectChecksums(objectName)[objectName]; ok {
+		return checksum
+	}
+
+	folder := fs.NewFolder(internal.GetRelativeArchiveDataFolderPath(), "")
+	checksumName := walFileName + walChecksumSuffix
+	reader, err := folder.ReadObject(checksumName)
+	if err != nil {
+		return ""
+	}
+	defer utility.LoggedClose(reader, "")
+	checksum, err := ioutil.ReadAll(reader)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Unable to read checksum of the wal file %s: %v", walFileName, err)
+		return ""
+	}
+	if err = folder.DeleteObjects([]string{checksumName}); err != nil {
+		tracelog.WarningLogger.Printf("Unable to remove checksum file of the wal file %s: %v", walFileName, err)
+	}
+	return string(checksum)
+}
+
+// forgetWalChecksum drops the checksum and the size the uploader keeps for the WAL file,
+// so the long-running wal-push daemon and wal-receive don't accumulate them
+func forgetWalChecksum(walFileName string, uploader *internal.Uploader) {
+	uploader.TakeObjectChecksums(walObjectName(walFileName, uploader))
+}
+
+func walObjectName(walFileName string, uploader *internal.Uploader) string {
+	return walFileName + "." + uploader.Compressor.FileExtension()
+}
+
+// fetchWalChecksum returns the checksum of the WAL object from its metadata, empty if there is none.
+// The metadata is looked up only if the metadata upload is configured.
+func fetchWalChecksum(folder storage.Folder, walFileName string) string {
+	metadataName := walMetadataObjectName(walFileName)
+	if metadataName == "" {
+		return ""
+	}
+
+	reader, exists, err := internal.TryDownloadFile(folder, metadataName)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Unable to fetch metadata of the wal file %s, checksum will not be verified: %v",
+			walFileName, err)
+		return ""
+	}
+	if !exists {
+		tracelog.WarningLogger.Printf("No metadata found for the wal file %s, checksum will not be verified", walFileName)
+		return ""
+	}
+	defer utility.LoggedClose(reader, "")
+	walMetadata := make(map[string]WalMetadataDescription)
+	if err = json.NewDecoder(reader).Decode(&walMetadata); err != nil {
+		tracelog.WarningLogger.Printf("Unable to parse metadata of the wal file %s, checksum will not be verified: %v",
+			walFileName, err)
+		return ""
+	}
+	return walMetadata[walFileName].Sha256
+}
+
+// walMetadataObjectName returns the name of the metadata object describing the WAL file,
+// empty if the metadata upload is not configured
+func walMetadataObjectName(walFileName string) string {
+	switch viper.GetString(internal.UploadWalMetadata) {
+	case WalIndividualMetadataLevel:
+		return walFileName + ".json"
+	case WalBulkMetadataLevel:
+		return walFileName[0:len(walFileName)-1] + ".json"
+	default:
+		return ""
+	}
+}
+
 func checkWalMetadataLevel(walMetadataLevel string) error {
 	isCorrect := false
 	for _, level := range WalMetadataLevels {
@@ -141,6 +246,7 @@ func checkWalMetadataLevel(walMetadataLevel string) error {
 func uploadLocalWalMetadata(walFilePath string, uploader *internal.Uploader) error {
 	walMetadataSetting := viper.GetString(internal.UploadWalMetadata)
 	if walMetadataSetting == WalNoMetadataLevel {
+		forgetWalChecksum(path.Base(walFilePath), uploader)
 		return nil
 	}
 
@@ -162,6 +268,7 @@ func uploadLocalWalMetadata(walFilePath string, uploader *internal.Uploader) err
 func uploadRemoteWalMetadata(walFileName string, uploader *internal.Uploader) error {
 	walMetadataSetting := viper.GetString(internal.UploadWalMetadata)
 	if walMetadataSetting == WalNoMetadataLevel {
+		forgetWalChecksum(walFileName, uploader)
 		return nil
 	}
 
@@ -0,0 +1,327 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/metrics"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/semaphore"
+)
+
+// The wal-push daemon protocol is line based: the client sends "PUSH <absolute WAL file path>",
+// the daemon answers "OK" once the file is in storage or "ERROR <message>" if the upload failed.
+const (
+	walPushDaemonPushRequest   = "PUSH"
+	walPushDaemonOkResponse    = "OK"
+	walPushDaemonErrorResponse = "ERROR"
+	walPushDaemonSocketNetwork = "unix"
+	walPushDaemonSocketMode    = 0600
+)
+
+// walPushDaemonFilenameRegexp matches the files archive_command is called for:
+// the WAL segments, their partials, the timeline history and the backup history files
+var walPushDaemonFilenameRegexp = regexp.MustCompile(
+	`^([0-9A-F]{24}(\.partial|\.[0-9A-F]{8}\.backup)?|[0-9A-F]{8}\.history)$`)
+
+type WalPushDaemonError struct {
+	error
+}
+
+func newWalPushDaemonError(walFilePath, message string) WalPushDaemonError {
+	return WalPushDaemonError{errors.Errorf("wal-push daemon failed to upload '%s': %s", walFilePath, message)}
+}
+
+func (err WalPushDaemonError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// WalPushDaemon uploads the WAL files sent by archive_command over the Unix socket.
+// The storage connections, the compressor and the crypter are configured once for all the files.
+// Along with the requested file, the daemon uploads the following files ready for archiving,
+// so they are already in storage when archive_command asks for them.
+type WalPushDaemon struct {
+	uploader            *WalUploader
+	preventWalOverwrite bool
+	readyRename         bool
+
+	// readAheadSem limits the number of the concurrent uploads of the files not requested yet to readAheadWorkers
+	readAheadSem     *semaphore.Weighted
+	readAheadWorkers int64
+	// readAheadLimit is the number of the files after the requested one looked up for the upload
+	readAheadLimit int
+
+	mutex sync.Mutex
+	// uploads tracks the ongoing uploads and the complete ones not requested yet
+	uploads map[string]*walPushDaemonUpload
+}
+
+type walPushDaemonUpload struct {
+	done chan struct{}
+	err  error
+}
+
+func (upload *walPushDaemonUpload) isFailed() bool {
+	select {
+	case <-upload.done:
+		return upload.err != nil
+	default:
+		return false
+	}
+}
+
+// NewWalPushDaemon creates the daemon uploading to the WAL folder of the uploader
+func NewWalPushDaemon(uploader *WalUploader) (*WalPushDaemon, error) {
+	concurrency, err := internal.GetMaxUploadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	readAheadWorkers := int64(concurrency - 1)
+	return &WalPushDaemon{
+		uploader:            uploader,
+		preventWalOverwrite: viper.GetBool(internal.PreventWalOverwriteSetting),
+		readyRename:         viper.GetBool(internal.PgReadyRename),
+		readAheadSem:        semaphore.NewWeighted(readAheadWorkers),
+		readAheadWorkers:    readAheadWorkers,
+		readAheadLimit:      viper.GetInt(internal.TotalBgUploadedLimit) - 1,
+		uploads:             make(map[string]*walPushDaemonUpload),
+	}, nil
+}
+
+// HandleWalPushDaemon serves the wal-push requests on the socket until the context is done
+func HandleWalPushDaemon(ctx context.Context, uploader *WalUploader, socketPath string) {
+	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.WalPath)
+	daemon, err := NewWalPushDaemon(uploader)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	// the socket of the daemon stopped without the cleanup
+	if stat, err := os.Stat(socketPath); err == nil && stat.Mode()&os.ModeSocket != 0 {
+		tracelog.WarningLogger.Printf("Removing the stale socket %s\n", socketPath)
+		tracelog.ErrorLogger.FatalOnError(os.Remove(socketPath))
+	}
+	listener, err := net.Listen(walPushDaemonSocketNetwork, socketPath)
+	tracelog.ErrorLogger.FatalfOnError("Failed to listen on the wal-push daemon socket: %v\n", err)
+	// only the user running the daemon (and Postgres) may ask it to upload the files
+	err = os.Chmod(socketPath, walPushDaemonSocketMode)
+	tracelog.ErrorLogger.FatalfOnError("Failed to restrict the access to the wal-push daemon socket: %v\n", err)
+	go func() {
+		<-ctx.Done()
+		utility.LoggedClose(listener, "Failed to close the wal-push daemon socket")
+	}()
+
+	tracelog.InfoLogger.Printf("Waiting for the WAL files to push on %s\n", socketPath)
+	err = daemon.Serve(listener)
+	if ctx.Err() == nil {
+		tracelog.ErrorLogger.FatalfOnError("Failed to accept the wal-push connection: %v\n", err)
+	}
+
+	err = daemon.Stop()
+	tracelog.ErrorLogger.FatalOnError(err)
+	if uploader.getUseWalDelta() {
+		uploader.FlushFiles()
+	}
+	uploader.Finish()
+}
+
+// Serve handles the connections until the listener is closed
+func (daemon *WalPushDaemon) Serve(listener net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			daemon.handleConnection(conn)
+		}()
+	}
+}
+
+// Stop waits for the uploads of the files not requested yet, no new ones are started after that
+func (daemon *WalPushDaemon) Stop() error {
+	return daemon.readAheadSem.Acquire(context.Background(), daemon.readAheadWorkers)
+}
+
+func (daemon *WalPushDaemon) handleConnection(conn net.Conn) {
+	defer utility.LoggedClose(conn, "Failed to close the wal-push connection")
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		response := walPushDaemonOkResponse
+		if err := daemon.handleRequest(scanner.Text()); err != nil {
+			tracelog.ErrorLogger.PrintError(err)
+			response = walPushDaemonErrorResponse + " " + strings.ReplaceAll(err.Error(), "\n", " ")
+		}
+		if _, err := io.WriteString(conn, response+"\n"); err != nil {
+			tracelog.ErrorLogger.Printf("Failed to respond to the wal-push request: %v\n", err)
+			return
+		}
+	}
+}
+
+func (daemon *WalPushDaemon) handleRequest(request string) error {
+	fields := strings.SplitN(request, " ", 2)
+	if len(fields) != 2 || fields[0] != walPushDaemonPushRequest {
+		return errors.Errorf("unexpected wal-push request '%s'", request)
+	}
+	walFilePath := fields[1]
+	if err := checkWalPushDaemonPath(walFilePath); err != nil {
+		return err
+	}
+	return daemon.Push(walFilePath)
+}
+
+// checkWalPushDaemonPath allows only the regular files in pg_wal with the names of the files to archive,
+// so the daemon can not be used to copy the other files readable by Postgres to the storage
+func checkWalPushDaemonPath(walFilePath string) error {
+	if !filepath.IsAbs(walFilePath) {
+		return errors.Errorf("WAL file path '%s' is not absolute", walFilePath)
+	}
+	if !walPushDaemonFilenameRegexp.MatchString(filepath.Base(walFilePath)) {
+		return errors.Errorf("'%s' is not a WAL file", walFilePath)
+	}
+	walDirName := filepath.Base(filepath.Dir(walFilePath))
+	if walDirName != "pg_wal" && walDirName != "pg_xlog" {
+		return errors.Errorf("WAL file '%s' is not in pg_wal", walFilePath)
+	}
+	stat, err := os.Lstat(walFilePath)
+	if err != nil {
+		return err
+	}
+	if !stat.Mode().IsRegular() {
+		return errors.Errorf("WAL file '%s' is not a regular file", walFilePath)
+	}
+	return nil
+}
+
+// Push uploads the WAL file unless it is already uploaded and starts the uploads of the following files
+func (daemon *WalPushDaemon) Push(walFilePath string) error {
+	metrics.WalPushSegment.Set(filepath.Base(walFilePath))
+	upload := daemon.startUpload(walFilePath, false)
+	daemon.readAhead(walFilePath)
+	<-upload.done
+
+	// archive_command does not ask for the file again once it is archived
+	daemon.mutex.Lock()
+	if daemon.uploads[filepath.Base(walFilePath)] == upload {
+		delete(daemon.uploads, filepath.Base(walFilePath))
+	}
+	daemon.mutex.Unlock()
+	return upload.err
+}
+
+// startUpload returns the ongoing or the successful upload of the file, or starts a new one.
+// The upload of the file not requested yet is not started if there are too many of them, nil is returned then.
+func (daemon *WalPushDaemon) startUpload(walFilePath string, isReadAhead bool) *walPushDaemonUpload {
+	walFileName := filepath.Base(walFilePath)
+	daemon.mutex.Lock()
+	defer daemon.mutex.Unlock()
+	if upload, ok := daemon.uploads[walFileName]; ok && !upload.isFailed() {
+		return upload
+	}
+	if isReadAhead && !daemon.readAheadSem.TryAcquire(1) {
+		return nil
+	}
+
+	upload := &walPushDaemonUpload{done: make(chan struct{})}
+	daemon.uploads[walFileName] = upload
+	go func() {
+		upload.err = daemon.upload(walFilePath, isReadAhead)
+		close(upload.done)
+		if isReadAhead {
+			daemon.readAheadSem.Release(1)
+		}
+	}()
+	return upload
+}
+
+// readAhead starts the uploads of the files following the pushed one which are ready for archiving
+func (daemon *WalPushDaemon) readAhead(walFilePath string) {
+	dir := filepath.Dir(walFilePath)
+	walFileName := filepath.Base(walFilePath)
+	for i := 0; i < daemon.readAheadLimit; i++ {
+		var err error
+		walFileName, err = GetNextWalFilename(walFileName)
+		if err != nil {
+			return
+		}
+		if _, err = os.Stat(filepath.Join(dir, archiveStatusDir, walFileName+readySuffix)); err != nil {
+			return
+		}
+		if daemon.startUpload(filepath.Join(dir, walFileName), true) == nil {
+			return
+		}
+	}
+}
+
+func (daemon *WalPushDaemon) upload(walFilePath string, isReadAhead bool) error {
+	uploader := daemon.uploader.clone()
+	if uploader.ArchiveStatusManager.IsWalAlreadyUploaded(walFilePath) {
+		err := uploader.ArchiveStatusManager.UnmarkWalFile(walFilePath)
+		if err != nil {
+			tracelog.ErrorLogger.Printf("unmark wal-g status for %s file failed due following error %+v", walFilePath, err)
+		}
+		return uploadLocalWalMetadata(walFilePath, uploader.Uploader)
+	}
+
+	err := uploadWALFile(uploader, walFilePath, daemon.preventWalOverwrite)
+	if err != nil {
+		return err
+	}
+	metrics.UploadedWalSegments.Inc()
+	err = uploadLocalWalMetadata(walFilePath, uploader.Uploader)
+	if err != nil || !isReadAhead || !daemon.readyRename {
+		return err
+	}
+
+	// archive_command is not called for the file renamed to ".done", so the upload is not tracked anymore
+	walFileName := filepath.Base(walFilePath)
+	if err = uploader.PGArchiveStatusManager.RenameReady(walFileName); err != nil {
+		tracelog.ErrorLogger.PrintOnError(err)
+		return nil
+	}
+	daemon.mutex.Lock()
+	delete(daemon.uploads, walFileName)
+	daemon.mutex.Unlock()
+	return nil
+}
+
+// PushToWalPushDaemon asks the daemon listening on the socket to push the WAL file and waits for the upload
+func PushToWalPushDaemon(socketPath, walFilePath string) error {
+	walFilePath, err := filepath.Abs(walFilePath)
+	if err != nil {
+		return err
+	}
+	conn, err := net.Dial(walPushDaemonSocketNetwork, socketPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the wal-push daemon")
+	}
+	defer utility.LoggedClose(conn, "")
+
+	if _, err = io.WriteString(conn, walPushDaemonPushRequest+" "+walFilePath+"\n"); err != nil {
+		return errors.Wrap(err, "failed to send the wal-push request")
+	}
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return errors.Wrap(err, "failed to read the wal-push daemon response")
+	}
+	response = strings.TrimSuffix(response, "\n")
+	if response != walPushDaemonOkResponse {
+		return newWalPushDaemonError(walFilePath, strings.TrimPrefix(response, walPushDaemonErrorResponse+" "))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/asm"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func startWalPushDaemon(t *testing.T) (*postgres.WalUploader, string, func()) {
+	socketDir, err := ioutil.TempDir("", "wal_push_daemon")
+	assert.NoError(t, err)
+	socketPath := filepath.Join(socketDir, "wal-push.sock")
+	listener, err := net.Listen("unix", socketPath)
+	assert.NoError(t, err)
+
+	uploader := testtools.NewMockWalDirUploader(false, false)
+	uploader.ArchiveStatusManager = asm.NewNopASM()
+	uploader.PGArchiveStatusManager = asm.NewNopASM()
+	daemon, err := postgres.NewWalPushDaemon(uploader)
+	assert.NoError(t, err)
+	served := make(chan struct{})
+	go func() {
+		_ = daemon.Serve(listener)
+		close(served)
+	}()
+	return uploader, socketPath, func() {
+		_ = listener.Close()
+		<-served
+		_ = os.RemoveAll(socketDir)
+	}
+}
+
+func TestWalPushDaemon_UploadsRequestedFile(t *testing.T) {
+	dir, _ := setupArchiveStatus(t, "")
+	defer testtools.Cleanup(t, dir)
+	walDir := filepath.Join(dir, "pg_wal")
+	addTestDataFile(t, walDir, "1")
+	addTestDataFile(t, walDir, "2")
+	uploader, socketPath, stop := startWalPushDaemon(t)
+	defer stop()
+
+	for _, i := range []string{"1", "2"} {
+		err := postgres.PushToWalPushDaemon(socketPath, filepath.Join(walDir, testFilename(i)))
+		assert.NoError(t, err)
+		// ".mock" suffix is the MockCompressor file extension
+		exists, err := uploader.UploadingFolder.Exists(testFilename(i) + ".mock")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	}
+}
+
+func TestWalPushDaemon_ReportsFailedUpload(t *testing.T) {
+	dir, _ := setupArchiveStatus(t, "")
+	defer testtools.Cleanup(t, dir)
+	_, socketPath, stop := startWalPushDaemon(t)
+	defer stop()
+
+	err := postgres.PushToWalPushDaemon(socketPath, filepath.Join(dir, "pg_wal", testFilename("1")))
+	assert.IsType(t, postgres.WalPushDaemonError{}, err)
+}
+
+func TestWalPushDaemon_RejectsNotWalFiles(t *testing.T) {
+	dir, _ := setupArchiveStatus(t, "")
+	defer testtools.Cleanup(t, dir)
+	walDir := filepath.Join(dir, "pg_wal")
+	addTestDataFile(t, dir, "2")
+	addTestDataFile(t, walDir, "postgresql.conf")
+	assert.NoError(t, os.Symlink(filepath.Join(dir, testFilename("2")), filepath.Join(walDir, testFilename("3"))))
+	uploader, socketPath, stop := startWalPushDaemon(t)
+	defer stop()
+
+	for _, walFilePath := range []string{
+		filepath.Join(dir, testFilename("2")),
+		filepath.Join(walDir, testFilename("postgresql.conf")),
+		filepath.Join(walDir, testFilename("3")),
+	} {
+		err := postgres.PushToWalPushDaemon(socketPath, walFilePath)
+		assert.IsType(t, postgres.WalPushDaemonError{}, err)
+	}
+	objects, _, err := uploader.UploadingFolder.ListFolder()
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+}
@@ -6,8 +6,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/metrics"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -32,6 +34,7 @@ func (err CantOverwriteWalFileError) Error() string {
 // TODO : unit tests
 // HandleWALPush is invoked to perform wal-g wal-push
 func HandleWALPush(uploader *WalUploader, walFilePath string) {
+	metrics.WalPushSegment.Set(filepath.Base(walFilePath))
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.WalPath)
 	if uploader.ArchiveStatusManager.IsWalAlreadyUploaded(walFilePath) {
 		err := uploader.ArchiveStatusManager.UnmarkWalFile(walFilePath)
@@ -57,6 +60,7 @@ func HandleWALPush(uploader *WalUploader, walFilePath string) {
 
 	err = uploadWALFile(uploader, walFilePath, bgUploader.preventWalOverwrite)
 	tracelog.ErrorLogger.FatalOnError(err)
+	metrics.UploadedWalSegments.Inc()
 	err = uploadLocalWalMetadata(walFilePath, uploader.Uploader)
 	tracelog.ErrorLogger.FatalOnError(err)
 
@@ -66,11 +70,13 @@ func HandleWALPush(uploader *WalUploader, walFilePath string) {
 	if uploader.getUseWalDelta() {
 		uploader.FlushFiles()
 	}
+	uploader.Finish()
 }
 
 // TODO : unit tests
 // uploadWALFile from FS to the cloud
 func uploadWALFile(uploader *WalUploader, walFilePath string, preventWalOverwrite bool) error {
+	defer metrics.WalPushDuration.ObserveDuration(time.Now())
 	if preventWalOverwrite {
 		overwriteAttempt, err := checkWALOverwrite(uploader, walFilePath)
 		if overwriteAttempt {
@@ -84,7 +90,15 @@ func uploadWALFile(uploader *WalUploader, walFilePath string, preventWalOverwrit
 		return errors.Wrapf(err, "upload: could not open '%s'\n", walFilePath)
 	}
 	err = uploader.UploadWalFile(walFile)
-	return errors.Wrapf(err, "upload: could not Upload '%s'\n", walFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "upload: could not Upload '%s'\n", walFilePath)
+	}
+	err = updateWalIndex(uploader.Uploader, filepath.Base(walFilePath))
+	if err != nil {
+		// the readers look up the segments missing in the index, so the archiving goes on
+		tracelog.WarningLogger.Printf("Failed to write the WAL index page of '%s': %v\n", walFilePath, err)
+	}
+	return nil
 }
 
 // TODO : unit tests
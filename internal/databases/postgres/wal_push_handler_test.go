@@ -47,6 +47,7 @@ func TestWalPush_IndividualMetadataUploader(t *testing.T) {
 	defer testtools.Cleanup(t, dir)
 	_, err := uploader.UploadingFolder.ReadObject(testFileName + ".json")
 	assert.NoError(t, err)
+	assert.Empty(t, uploader.TakeObjectChecksums(""))
 }
 
 func TestWalPush_BulkMetadataUploader(t *testing.T) {
@@ -63,6 +64,7 @@ func TestWalPush_NoMetataNoUploader(t *testing.T) {
 	defer testtools.Cleanup(t, dir)
 	_, err := uploader.UploadingFolder.ReadObject(testFileName + ".json")
 	assert.Error(t, err)
+	assert.Empty(t, uploader.TakeObjectChecksums(""))
 }
 
 func TestWalPush_BulkMetadataUploaderWithUploadConcurrency(t *testing.T) {
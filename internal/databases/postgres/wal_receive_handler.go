@@ -22,21 +22,15 @@ const (
 
 /*
 NOTE: Preventing a WAL gap is a complex one (also not 100% fixed with arch_command).
-* Using replication slot helps, but that should be created and maintained
-  by wal-g on standby's too (making sure unconsumed wals are preserved on
-  potential new masters too)
-* Using sync replication is another option, but non-promotable, and we
-  should locally cache to disconnect S3 performance from database performance
-* Making something that checks 'what is in wal-g s repo' vs 'where postgres is
-  is another option, but when wal-g is no longer running there would be nothing
-  preventing postgres from advancing and cleaning, which is what slots are for.
-Cleanest would probably be to create the slot on all postgres instances and advance all of them.
-Can be done, but first, lets focus on creating wal files from repl msg...
+* The replication slot keeps the WAL on the primary until it is in storage:
+  Postgres is told the WAL is flushed only after the segment is uploaded.
+* The slot is created on the standbys of WALG_WAL_RECEIVE_STANDBYS too and advanced
+  along with the archived WAL, so the unconsumed WAL is preserved on the potential new primaries.
+* wal-receive resumes from the last segment in storage, so a restart does not upload the archived WAL again.
 
 Things to do (future):
 * unittests for queryrunner code
 * upgrade to pgx/v4
-* we might want to add a feature to have wal-g advance multiple slots to support HA setups natively
 * Test with different wal size (>=pg11)
 */
 
@@ -59,6 +53,15 @@ func HandleWALReceive(uploader *WalUploader) {
 	slot, walSegmentBytes, err := getCurrentWalInfo()
 	tracelog.ErrorLogger.FatalOnError(err)
 	tracelog.DebugLogger.Printf("WAL segment bytes: %d", walSegmentBytes)
+	err = verifyPrimarySlot(slot)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	standbySlots, err := connectStandbySlots(slot.Name)
+	tracelog.ErrorLogger.FatalOnError(err)
+	defer closeStandbySlots(standbySlots)
+
+	partialUploader, err := newPartialSegmentUploader(uploader)
+	tracelog.ErrorLogger.FatalOnError(err)
 
 	conn, err := pgconn.Connect(context.Background(), "replication=yes")
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -76,6 +79,9 @@ func HandleWALReceive(uploader *WalUploader) {
 		tracelog.ErrorLogger.FatalOnError(err)
 		XLogPos = sysident.XLogPos
 	}
+	archivedLSN, err := getArchivedLSN(uploader.UploadingFolder, uint32(sysident.Timeline), walSegmentBytes)
+	tracelog.ErrorLogger.FatalOnError(err)
+	XLogPos = getResumeLSN(XLogPos, archivedLSN, sysident.XLogPos, walSegmentBytes)
 
 	// Get timeline for XLogPos from historyfile with helper function
 	timeline, err := getStartTimeline(conn, uploader, uint32(sysident.Timeline), XLogPos)
@@ -84,23 +90,28 @@ func HandleWALReceive(uploader *WalUploader) {
 	segment = NewWalSegment(timeline, XLogPos, walSegmentBytes)
 	startReplication(conn, segment, slot.Name)
 	for {
-		streamResult, err := segment.Stream(conn, StandbyMessageTimeout)
+		streamResult, err := segment.Stream(conn, StandbyMessageTimeout, partialUploader)
 		tracelog.ErrorLogger.FatalOnError(err)
 		tracelog.DebugLogger.Printf("Successfully received wal segment %s: ", segment.Name())
 
 		switch streamResult {
 		case ProcessMessageOK:
 			// segment is a regular segemnt. Write, and create a new for this timeline.
-			err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(segment, segment.Name()))
+			err = uploadReceivedWalFile(uploader, ioextensions.NewNamedReaderImpl(segment, segment.Name()))
 			tracelog.ErrorLogger.FatalOnError(err)
 			err = uploadRemoteWalMetadata(segment.Name(), uploader.Uploader)
 			tracelog.ErrorLogger.FatalOnError(err)
+			err = partialUploader.deletePartial(segment)
+			if err != nil {
+				tracelog.WarningLogger.Printf("Failed to delete the partial of '%s': %v\n", segment.Name(), err)
+			}
+			advanceStandbySlots(standbySlots, segment.endLSN)
 			XLogPos = segment.endLSN
 			segment, err = segment.NextWalSegment()
 			tracelog.ErrorLogger.FatalOnError(err)
 		case ProcessMessageCopyDone:
 			// segment is a partial. Write, and create a new for the next timeline.
-			err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(segment, segment.Name()))
+			err = uploadReceivedWalFile(uploader, ioextensions.NewNamedReaderImpl(segment, segment.Name()))
 			tracelog.ErrorLogger.FatalOnError(err)
 			err = uploadRemoteWalMetadata(segment.Name(), uploader.Uploader)
 			tracelog.ErrorLogger.FatalOnError(err)
@@ -109,7 +120,7 @@ func HandleWALReceive(uploader *WalUploader) {
 			tracelog.ErrorLogger.FatalOnError(err)
 			tlh, err := NewTimeLineHistFile(timeline, timelinehistfile.FileName, timelinehistfile.Content)
 			tracelog.ErrorLogger.FatalOnError(err)
-			err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(tlh, tlh.Name()))
+			err = uploadReceivedWalFile(uploader, ioextensions.NewNamedReaderImpl(tlh, tlh.Name()))
 			tracelog.ErrorLogger.FatalOnError(err)
 			err = uploadRemoteWalMetadata(tlh.Name(), uploader.Uploader)
 			tracelog.ErrorLogger.FatalOnError(err)
@@ -121,6 +132,37 @@ func HandleWALReceive(uploader *WalUploader) {
 	}
 }
 
+// getResumeLSN resumes from the last archived segment unless the slot is behind it. The storage ahead of the server
+// is not trusted, it may belong to another cluster. The WAL between the archived segment and the slot is lost.
+func getResumeLSN(slotLSN, archivedLSN, serverLSN pglogrepl.LSN, walSegmentBytes uint64) pglogrepl.LSN {
+	if archivedLSN == 0 || archivedLSN > serverLSN {
+		return slotLSN
+	}
+	if archivedLSN >= slotLSN {
+		tracelog.InfoLogger.Printf("Resuming from the last archived LSN %s\n", archivedLSN)
+		return archivedLSN
+	}
+	if uint64(archivedLSN)/walSegmentBytes < uint64(slotLSN)/walSegmentBytes {
+		tracelog.WarningLogger.Printf("The WAL from %s to %s is not archived and not kept by the replication slot, "+
+			"a new backup is needed for the point-in-time recovery past it\n", archivedLSN, slotLSN)
+	}
+	return slotLSN
+}
+
+// uploadReceivedWalFile uploads the received WAL file and adds it to the WAL index
+func uploadReceivedWalFile(uploader *WalUploader, file ioextensions.NamedReader) error {
+	err := uploader.UploadWalFile(file)
+	if err != nil {
+		return err
+	}
+	err = updateWalIndex(uploader.Uploader, file.Name())
+	if err != nil {
+		// the readers look up the segments missing in the index, so the receiving goes on
+		tracelog.WarningLogger.Printf("Failed to write the WAL index page of '%s': %v\n", file.Name(), err)
+	}
+	return nil
+}
+
 func getStartTimeline(conn *pgconn.PgConn,
 	uploader *WalUploader,
 	systemTimeline uint32,
@@ -132,7 +174,7 @@ func getStartTimeline(conn *pgconn.PgConn,
 	if err == nil {
 		tlh, err := NewTimeLineHistFile(systemTimeline, timelinehistfile.FileName, timelinehistfile.Content)
 		tracelog.ErrorLogger.FatalOnError(err)
-		err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(tlh, tlh.Name()))
+		err = uploadReceivedWalFile(uploader, ioextensions.NewNamedReaderImpl(tlh, tlh.Name()))
 		tracelog.ErrorLogger.FatalOnError(err)
 		return tlh.LSNToTimeLine(xLogPos)
 	}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+)
+
+const testWalSegmentBytes = 16 * 1024 * 1024
+
+func TestGetResumeLSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		slotLSN     pglogrepl.LSN
+		archivedLSN pglogrepl.LSN
+		serverLSN   pglogrepl.LSN
+		expected    pglogrepl.LSN
+	}{
+		{"nothing archived", 0x2A000100, 0, 0x30000000, 0x2A000100},
+		{"slot behind the archive", 0x2A000100, 0x2C000000, 0x30000000, 0x2C000000},
+		{"slot in the last archived segment", 0x2C000100, 0x2C000000, 0x30000000, 0x2C000100},
+		{"WAL not archived before the slot", 0x2E000100, 0x2C000000, 0x30000000, 0x2E000100},
+		{"archive ahead of the server", 0x2A000100, 0x40000000, 0x30000000, 0x2A000100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, getResumeLSN(tt.slotLSN, tt.archivedLSN, tt.serverLSN, testWalSegmentBytes))
+		})
+	}
+}
+
+func TestGetArchivedLSN_ForkedTimeline(t *testing.T) {
+	walFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	// the old primary archived the timeline 1 past the switch to the timeline 2 at 0/5000100
+	for segmentNo := WalSegmentNo(1); segmentNo <= 8; segmentNo++ {
+		assert.NoError(t, walFolder.PutObject(segmentNo.getFilename(1)+".lz4", new(bytes.Buffer)))
+	}
+	putCompressedObject(t, walFolder, "00000002.history", []byte("1\t0/5000100\tno recovery target specified\n"))
+
+	archivedLSN, err := getArchivedLSN(walFolder, 2, testWalSegmentBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, pglogrepl.LSN(0x5000100), archivedLSN)
+
+	for segmentNo := WalSegmentNo(5); segmentNo <= 6; segmentNo++ {
+		assert.NoError(t, walFolder.PutObject(segmentNo.getFilename(2)+".lz4", new(bytes.Buffer)))
+	}
+	archivedLSN, err = getArchivedLSN(walFolder, 2, testWalSegmentBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, pglogrepl.LSN(0x7000000), archivedLSN)
+
+	// the timeline 1 has no history file, so all of its segments count
+	archivedLSN, err = getArchivedLSN(walFolder, 1, testWalSegmentBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, pglogrepl.LSN(0x9000000), archivedLSN)
+}
+
+func TestSetStandbyHost(t *testing.T) {
+	config := pgx.ConnConfig{Host: "primary", Port: 5432}
+	assert.NoError(t, setStandbyHost(&config, "standby1"))
+	assert.Equal(t, "standby1", config.Host)
+	assert.Equal(t, uint16(5432), config.Port)
+
+	assert.NoError(t, setStandbyHost(&config, "standby2:6432"))
+	assert.Equal(t, "standby2", config.Host)
+	assert.Equal(t, uint16(6432), config.Port)
+
+	assert.Error(t, setStandbyHost(&config, "standby3:port"))
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+)
+
+// partialSegmentUploader uploads the segment being received as <name>.partial every interval or every number
+// of bytes received, replacing the previous partial. The partial is zero-padded to the segment size
+// like the one of pg_receivewal, so Postgres replays it up to the received WAL.
+type partialSegmentUploader struct {
+	uploader *WalUploader
+	interval time.Duration
+	bytes    int
+
+	// segmentName is the partial the last upload is counted for
+	segmentName    string
+	lastUploadTime time.Time
+	lastUploadSize int
+	isUploaded     bool
+}
+
+// newPartialSegmentUploader returns nil if neither the interval nor the number of bytes is set
+func newPartialSegmentUploader(uploader *WalUploader) (*partialSegmentUploader, error) {
+	var interval time.Duration
+	if viper.IsSet(internal.WalReceivePartialInterval) {
+		var err error
+		interval, err = internal.GetDurationSetting(internal.WalReceivePartialInterval)
+		if err != nil {
+			return nil, err
+		}
+	}
+	partialBytes := viper.GetInt(internal.WalReceivePartialBytes)
+	if interval <= 0 && partialBytes <= 0 {
+		return nil, nil
+	}
+	return &partialSegmentUploader{uploader: uploader, interval: interval, bytes: partialBytes}, nil
+}
+
+// isDue tells whether the received WAL of the segment should be uploaded now
+func (partialUploader *partialSegmentUploader) isDue(seg *WalSegment) bool {
+	if partialUploader == nil {
+		return false
+	}
+	if partialUploader.segmentName != seg.partialName() {
+		partialUploader.segmentName = seg.partialName()
+		partialUploader.lastUploadTime = time.Now()
+		partialUploader.lastUploadSize = 0
+		partialUploader.isUploaded = false
+	}
+	receivedBytes := seg.writeIndex - partialUploader.lastUploadSize
+	if receivedBytes <= 0 {
+		return false
+	}
+	return partialUploader.bytes > 0 && receivedBytes >= partialUploader.bytes ||
+		partialUploader.interval > 0 && time.Since(partialUploader.lastUploadTime) >= partialUploader.interval
+}
+
+// nextDeadline returns the time of the next upload by the interval if it comes before the deadline
+func (partialUploader *partialSegmentUploader) nextDeadline(deadline time.Time) time.Time {
+	if partialUploader == nil || partialUploader.interval <= 0 {
+		return deadline
+	}
+	uploadTime := partialUploader.lastUploadTime.Add(partialUploader.interval)
+	if uploadTime.Before(deadline) {
+		return uploadTime
+	}
+	return deadline
+}
+
+// upload uploads the segment as partial, the received WAL is reported as flushed after that
+func (partialUploader *partialSegmentUploader) upload(seg *WalSegment) error {
+	name := seg.partialName()
+	reader := ioextensions.NewNamedReaderImpl(bytes.NewReader(seg.data), name)
+	err := uploadReceivedWalFile(partialUploader.uploader, reader)
+	if err != nil {
+		return err
+	}
+	// the partials have no metadata
+	forgetWalChecksum(name, partialUploader.uploader.Uploader)
+	tracelog.DebugLogger.Printf("Uploaded %d bytes of %s\n", seg.writeIndex, name)
+	partialUploader.lastUploadTime = time.Now()
+	partialUploader.lastUploadSize = seg.writeIndex
+	partialUploader.isUploaded = true
+	seg.flushLSN = seg.StartLSN + pglogrepl.LSN(seg.writeIndex)
+	return nil
+}
+
+// deletePartial deletes the partial of the segment uploaded in full
+func (partialUploader *partialSegmentUploader) deletePartial(seg *WalSegment) error {
+	if partialUploader == nil || partialUploader.segmentName != seg.partialName() || !partialUploader.isUploaded {
+		return nil
+	}
+	partialUploader.isUploaded = false
+	objectName := seg.partialName() + "." + partialUploader.uploader.Compressor.FileExtension()
+	return partialUploader.uploader.UploadingFolder.DeleteObjects([]string{objectName})
+}
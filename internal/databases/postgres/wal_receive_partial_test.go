@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+func TestNewPartialSegmentUploader_DisabledByDefault(t *testing.T) {
+	partialUploader, err := newPartialSegmentUploader(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, partialUploader)
+	assert.False(t, partialUploader.isDue(NewWalSegment(1, 0x2A000000, testWalSegmentBytes)))
+}
+
+func TestPartialSegmentUploader_UploadsEveryBytes(t *testing.T) {
+	viper.Set(internal.WalReceivePartialBytes, 64)
+	defer viper.Set(internal.WalReceivePartialBytes, 0)
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	partialUploader, err := newPartialSegmentUploader(NewWalUploader(lz4.Compressor{}, folder, nil))
+	assert.NoError(t, err)
+
+	segment := NewWalSegment(1, 0x2A000000, testWalSegmentBytes)
+	segment.writeIndex = 100
+	assert.True(t, partialUploader.isDue(segment))
+	assert.NoError(t, partialUploader.upload(segment))
+	assert.Equal(t, segment.StartLSN+100, segment.flushLSN)
+	exists, err := folder.Exists("00000001000000000000002A.partial.lz4")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Empty(t, partialUploader.uploader.TakeObjectChecksums(""))
+
+	segment.writeIndex = 150
+	assert.False(t, partialUploader.isDue(segment))
+	segment.writeIndex = 164
+	assert.True(t, partialUploader.isDue(segment))
+
+	assert.NoError(t, partialUploader.deletePartial(segment))
+	exists, err = folder.Exists("00000001000000000000002A.partial.lz4")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// standbySlot is the copy of the wal-receive slot on a standby. It is advanced along with the archived WAL,
+// so the standby keeps the WAL not archived yet and wal-receive goes on without a gap once it is promoted.
+type standbySlot struct {
+	name        string
+	host        string
+	queryRunner *PgQueryRunner
+	restartLSN  pglogrepl.LSN
+}
+
+// verifyPrimarySlot makes sure the wal-receive slot of the primary is not streamed by another receiver
+func verifyPrimarySlot(slot PhysicalSlot) error {
+	if slot.Exists && slot.Active {
+		return genericWalReceiveError{
+			errors.Errorf("Slot %s is active, is another wal-receive running?", slot.Name)}
+	}
+	return nil
+}
+
+// connectStandbySlots connects to the standbys of WALG_WAL_RECEIVE_STANDBYS and creates the slot there if needed
+func connectStandbySlots(slotName string) ([]*standbySlot, error) {
+	hosts := viper.GetString(internal.WalReceiveStandbysSetting)
+	if hosts == "" {
+		return nil, nil
+	}
+	slots := make([]*standbySlot, 0)
+	for _, host := range strings.Split(hosts, ",") {
+		slot, err := connectStandbySlot(strings.TrimSpace(host), slotName)
+		if err != nil {
+			closeStandbySlots(slots)
+			return nil, err
+		}
+		if slot != nil {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
+
+// connectStandbySlot returns nil for the primary, which is listed among the standbys after a failover
+func connectStandbySlot(host, slotName string) (*standbySlot, error) {
+	// the connection settings are shared with the primary, except for the host. Unlike Connect,
+	// there is no fallback to localhost, which is the primary when wal-receive runs next to it.
+	config, err := pgx.ParseEnvLibpq()
+	if err == nil {
+		err = setStandbyHost(&config, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pgx.Connect(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to the standby %s", host)
+	}
+	queryRunner, err := NewPgQueryRunner(conn)
+	if err == nil {
+		// the slot is advanced with pg_replication_slot_advance() since Postgres 11
+		_, err = queryRunner.buildAdvancePhysicalSlot()
+	}
+	var inRecovery bool
+	if err == nil {
+		inRecovery, err = queryRunner.IsInRecovery()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "failed to query the standby %s", host)
+	}
+	if !inRecovery {
+		tracelog.InfoLogger.Printf("%s is not a standby, its replication slot is not advanced\n", host)
+		return nil, conn.Close()
+	}
+
+	slot, err := queryRunner.GetPhysicalSlotInfo(slotName)
+	if err == nil && !slot.Exists {
+		tracelog.InfoLogger.Printf("Creating the replication slot %s on the standby %s\n", slotName, host)
+		slot, err = queryRunner.CreatePhysicalSlot(slotName)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "failed to set up the replication slot on the standby %s", host)
+	}
+	return &standbySlot{name: slotName, host: host, queryRunner: queryRunner, restartLSN: slot.RestartLSN}, nil
+}
+
+// setStandbyHost sets the host and the optional port of the standby
+func setStandbyHost(config *pgx.ConnConfig, host string) error {
+	hostName, port, err := net.SplitHostPort(host)
+	if err != nil {
+		config.Host = host
+		return nil
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return errors.Wrapf(err, "invalid port of the standby %s", host)
+	}
+	config.Host = hostName
+	config.Port = uint16(portNumber)
+	return nil
+}
+
+// advanceStandbySlots moves the slots of the standbys to the archived LSN. The standby failures do not stop
+// the archiving, the slot of the failed standby is advanced along with the next archived segment.
+func advanceStandbySlots(slots []*standbySlot, archivedLSN pglogrepl.LSN) {
+	for _, slot := range slots {
+		if archivedLSN <= slot.restartLSN {
+			// the slot is created after the WAL was archived and can not be moved back
+			continue
+		}
+		restartLSN, err := slot.queryRunner.AdvancePhysicalSlot(slot.name, archivedLSN)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to advance the replication slot on the standby %s: %v\n", slot.host, err)
+			continue
+		}
+		slot.restartLSN = restartLSN
+	}
+}
+
+func closeStandbySlots(slots []*standbySlot) {
+	for _, slot := range slots {
+		if err := slot.queryRunner.Connection.Close(); err != nil {
+			tracelog.WarningLogger.Printf("Failed to close the connection to the standby %s: %v\n", slot.host, err)
+		}
+	}
+}
+
+// getArchivedLSN returns the end of the last WAL segment archived on the timeline or on the preceding ones.
+// The segments of the preceding timelines count up to the switch point of the timeline history,
+// the old primary may have archived the WAL of the abandoned branch past it.
+func getArchivedLSN(walFolder storage.Folder, timeline uint32, walSegmentBytes uint64) (pglogrepl.LSN, error) {
+	filenames, err := getWalFolderFilenames(walFolder)
+	if err != nil {
+		return 0, err
+	}
+	switchLSNs := make(map[uint32]uint64)
+	historyRecords, err := getTimeLineHistoryRecords(timeline, walFolder)
+	if _, ok := err.(HistoryFileNotFoundError); err != nil && !ok {
+		return 0, err
+	}
+	for _, record := range historyRecords {
+		switchLSNs[record.timeline] = record.lsn
+	}
+	var archivedLSN pglogrepl.LSN
+	for segment := range getSegmentsFromFiles(filenames) {
+		segmentEndLSN := (uint64(segment.Number) + 1) * walSegmentBytes
+		if segment.Timeline != timeline {
+			switchLSN, ok := switchLSNs[segment.Timeline]
+			if !ok {
+				// not in the history of the timeline
+				continue
+			}
+			if switchLSN < segmentEndLSN {
+				segmentEndLSN = switchLSN
+			}
+		}
+		if archivedLSN < pglogrepl.LSN(segmentEndLSN) {
+			archivedLSN = pglogrepl.LSN(segmentEndLSN)
+		}
+	}
+	return archivedLSN, nil
+}
@@ -27,6 +27,7 @@ type WalSegment struct {
 	TimeLine        uint32
 	StartLSN        pglogrepl.LSN
 	endLSN          pglogrepl.LSN
+	flushLSN        pglogrepl.LSN
 	walSegmentBytes uint64
 	data            []byte
 	readIndex       int
@@ -60,6 +61,8 @@ func NewWalSegment(timeline uint32, location pglogrepl.LSN, walSegmentBytes uint
 	segment.StartLSN = pglogrepl.LSN((uint64(location) / walSegmentBytes) * walSegmentBytes)
 	// Calculate end form start and number of bytes in this file
 	segment.endLSN = segment.StartLSN + pglogrepl.LSN(walSegmentBytes)
+	// The WAL before this segment is in storage
+	segment.flushLSN = segment.StartLSN
 	// Allocate data
 	segment.data = make([]byte, walSegmentBytes)
 	return segment
@@ -95,11 +98,15 @@ func (seg *WalSegment) NextWalSegment() (*WalSegment, error) {
 func (seg *WalSegment) Name() string {
 	// Example LSN -> Name:
 	// '0/2A33FE00' -> '00000001000000000000002A'
-	segID := uint64(seg.StartLSN) / seg.walSegmentBytes
 	if seg.isComplete() {
-		return formatWALFileName(seg.TimeLine, segID)
+		return formatWALFileName(seg.TimeLine, uint64(seg.StartLSN)/seg.walSegmentBytes)
 	}
-	return formatWALFileName(seg.TimeLine, segID) + ".partial"
+	return seg.partialName()
+}
+
+// partialName returns the filename of this wal segment uploaded before it is complete.
+func (seg *WalSegment) partialName() string {
+	return formatWALFileName(seg.TimeLine, uint64(seg.StartLSN)/seg.walSegmentBytes) + ".partial"
 }
 
 // processMessage is a method that processes a message from Postgres and copies its data
@@ -159,7 +166,10 @@ func (seg *WalSegment) processMessage(message pgproto3.BackendMessage) (ProcessM
 }
 
 // Stream is a helper function to retrieve messages from Postgres and have them processed by processMessage().
-func (seg *WalSegment) Stream(conn *pgconn.PgConn, standbyMessageTimeout time.Duration) (ProcessMessageResult, error) {
+// The received WAL is uploaded as partial segment if the partialUploader is set.
+func (seg *WalSegment) Stream(conn *pgconn.PgConn,
+	standbyMessageTimeout time.Duration,
+	partialUploader *partialSegmentUploader) (ProcessMessageResult, error) {
 	// Inspired by https://github.com/jackc/pglogrepl/blob/master/example/pglogrepl_demo/main.go
 	// And https://www.postgresql.org/docs/12/protocol-replication.html
 
@@ -167,16 +177,28 @@ func (seg *WalSegment) Stream(conn *pgconn.PgConn, standbyMessageTimeout time.Du
 	var msg pgproto3.BackendMessage
 	nextStandbyMessageDeadline := time.Now()
 	for {
+		if partialUploader.isDue(seg) {
+			if err = partialUploader.upload(seg); err != nil {
+				return ProcessMessageUnknown, err
+			}
+			// report the uploaded WAL right away
+			nextStandbyMessageDeadline = time.Time{}
+		}
 		if time.Now().After(nextStandbyMessageDeadline) {
+			// Only the WAL in storage is reported, so the slot and the synchronous commits wait for the upload.
 			err = pglogrepl.SendStandbyStatusUpdate(context.Background(),
 				conn,
-				pglogrepl.StandbyStatusUpdate{WALWritePosition: seg.StartLSN})
+				pglogrepl.StandbyStatusUpdate{
+					WALWritePosition: seg.flushLSN,
+					WALFlushPosition: seg.flushLSN,
+					WALApplyPosition: seg.flushLSN,
+				})
 			tracelog.ErrorLogger.FatalOnError(err)
 			tracelog.DebugLogger.Println("Sent Standby status message")
 			nextStandbyMessageDeadline = time.Now().Add(standbyMessageTimeout)
 		}
 
-		ctx, cancel := context.WithDeadline(context.Background(), nextStandbyMessageDeadline)
+		ctx, cancel := context.WithDeadline(context.Background(), partialUploader.nextDeadline(nextStandbyMessageDeadline))
 		msg, err = conn.ReceiveMessage(ctx)
 		cancel()
 		if pgconn.Timeout(err) {
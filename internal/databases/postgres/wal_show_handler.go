@@ -12,6 +12,8 @@ import (
 const (
 	TimelineOkStatus          = "OK"
 	TimelineLostSegmentStatus = "LOST_SEGMENTS"
+	// TimelineNoSegmentsStatus is the status of the tree-json parent timelines with no segments in storage
+	TimelineNoSegmentsStatus = "NO_SEGMENTS"
 )
 
 // TimelineInfo contains information about some timeline in storage
@@ -118,7 +120,7 @@ func (seq *WalSegmentsSequence) FindMissingSegments() ([]WalSegmentDescription,
 // groups WAL segments by the timeline and shows detailed info about each timeline stored in storage
 func HandleWalShow(rootFolder storage.Folder, showBackups bool, outputWriter WalShowOutputWriter) {
 	walFolder := rootFolder.GetSubFolder(utility.WalPath)
-	filenames, err := getFolderFilenames(walFolder)
+	filenames, err := getWalFolderFilenames(walFolder)
 	tracelog.ErrorLogger.FatalfOnError("Failed to get the WAL folder filenames %v\n", err)
 
 	walSegments := getSegmentsFromFiles(filenames)
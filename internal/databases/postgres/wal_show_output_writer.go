@@ -2,8 +2,11 @@ package postgres
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"strings"
 
+	"github.com/jackc/pglogrepl"
 	"github.com/jedib0t/go-pretty/table"
 )
 
@@ -12,6 +15,8 @@ type WalShowOutputType int
 const (
 	TableOutput WalShowOutputType = iota + 1
 	JSONOutput
+	DotOutput
+	TreeJSONOutput
 )
 
 // WalShowOutputWriter writes the output of wal-show command execution result
@@ -63,12 +68,113 @@ func (writer *WalShowTableOutputWriter) Write(timelineInfos []*TimelineInfo) err
 	return nil
 }
 
+// TimelineTreeNode is the timeline with the timelines switched from it
+type TimelineTreeNode struct {
+	*TimelineInfo
+	Children []*TimelineTreeNode `json:"children"`
+}
+
+// buildTimelineTree links the timelines to their parents. The parents with no segments in storage
+// are added as the placeholder nodes, like the dashed nodes of the DOT output, so the switch points are kept.
+// Timeline infos are expected to be ordered by ID.
+func buildTimelineTree(timelineInfos []*TimelineInfo) []*TimelineTreeNode {
+	nodes := make(map[uint32]*TimelineTreeNode, len(timelineInfos))
+	for _, info := range timelineInfos {
+		nodes[info.ID] = &TimelineTreeNode{TimelineInfo: info, Children: make([]*TimelineTreeNode, 0)}
+	}
+	roots := make([]*TimelineTreeNode, 0)
+	for _, info := range timelineInfos {
+		if info.ParentID == 0 || info.ParentID == info.ID {
+			roots = append(roots, nodes[info.ID])
+			continue
+		}
+		parent, ok := nodes[info.ParentID]
+		if !ok {
+			parent = &TimelineTreeNode{
+				TimelineInfo: &TimelineInfo{ID: info.ParentID, MissingSegments: []string{}, Status: TimelineNoSegmentsStatus},
+				Children:     make([]*TimelineTreeNode, 0),
+			}
+			nodes[info.ParentID] = parent
+			roots = append(roots, parent)
+		}
+		parent.Children = append(parent.Children, nodes[info.ID])
+	}
+	return roots
+}
+
+// WalShowTreeJSONOutputWriter writes the timelines as JSON tree
+type WalShowTreeJSONOutputWriter struct {
+	output io.Writer
+}
+
+func (writer *WalShowTreeJSONOutputWriter) Write(timelineInfos []*TimelineInfo) error {
+	bytes, err := json.Marshal(buildTimelineTree(timelineInfos))
+	if err != nil {
+		return err
+	}
+	_, err = writer.output.Write(bytes)
+	return err
+}
+
+// WalShowDotOutputWriter writes the timeline graph in Graphviz DOT format
+type WalShowDotOutputWriter struct {
+	output         io.Writer
+	includeBackups bool
+}
+
+func (writer *WalShowDotOutputWriter) Write(timelineInfos []*TimelineInfo) error {
+	var graph strings.Builder
+	graph.WriteString("digraph timelines {\n\trankdir=LR;\n\tnode [shape=box];\n")
+
+	timelines := make(map[uint32]bool, len(timelineInfos))
+	for _, tl := range timelineInfos {
+		timelines[tl.ID] = true
+	}
+	for _, tl := range timelineInfos {
+		label := fmt.Sprintf("timeline %d\n%s - %s\n%d of %d segments, %s",
+			tl.ID, tl.StartSegment, tl.EndSegment, tl.SegmentsCount, tl.SegmentRangeSize, tl.Status)
+		if len(tl.MissingSegments) > 0 {
+			label += fmt.Sprintf("\n%d missing segments", len(tl.MissingSegments))
+		}
+		graph.WriteString(fmt.Sprintf("\ttl%d [label=%q];\n", tl.ID, label))
+	}
+	for _, tl := range timelineInfos {
+		if tl.ParentID == 0 || tl.ParentID == tl.ID {
+			continue
+		}
+		if !timelines[tl.ParentID] {
+			// the parent timeline has no segments in storage
+			graph.WriteString(fmt.Sprintf("\ttl%d [label=%q, style=dashed];\n",
+				tl.ParentID, fmt.Sprintf("timeline %d\nno segments", tl.ParentID)))
+			timelines[tl.ParentID] = true
+		}
+		graph.WriteString(fmt.Sprintf("\ttl%d -> tl%d [label=%q];\n",
+			tl.ParentID, tl.ID, pglogrepl.LSN(tl.SwitchPointLsn).String()))
+	}
+	if writer.includeBackups {
+		for _, tl := range timelineInfos {
+			for _, backup := range tl.Backups {
+				graph.WriteString(fmt.Sprintf("\t%q [shape=ellipse];\n\ttl%d -> %q [style=dashed];\n",
+					backup.BackupName, tl.ID, backup.BackupName))
+			}
+		}
+	}
+	graph.WriteString("}\n")
+
+	_, err := io.WriteString(writer.output, graph.String())
+	return err
+}
+
 func NewWalShowOutputWriter(outputType WalShowOutputType, output io.Writer, includeBackups bool) WalShowOutputWriter {
 	switch outputType {
 	case TableOutput:
 		return &WalShowTableOutputWriter{output: output, includeBackups: includeBackups}
 	case JSONOutput:
 		return &WalShowJSONOutputWriter{output: output}
+	case DotOutput:
+		return &WalShowDotOutputWriter{output: output, includeBackups: includeBackups}
+	case TreeJSONOutput:
+		return &WalShowTreeJSONOutputWriter{output: output}
 	default:
 		return &WalShowTableOutputWriter{output: output, includeBackups: includeBackups}
 	}
@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func getTestTimelineInfos() []*postgres.TimelineInfo {
+	return []*postgres.TimelineInfo{
+		{
+			ID:               1,
+			StartSegment:     "000000010000000000000001",
+			EndSegment:       "000000010000000000000003",
+			SegmentsCount:    3,
+			SegmentRangeSize: 3,
+			MissingSegments:  []string{},
+			Status:           postgres.TimelineOkStatus,
+			Backups: []*postgres.BackupDetail{
+				{BackupTime: internal.BackupTime{BackupName: "base_000000010000000000000002"}},
+			},
+		},
+		{
+			ID:               2,
+			ParentID:         1,
+			SwitchPointLsn:   0x3000000,
+			StartSegment:     "000000020000000000000003",
+			EndSegment:       "000000020000000000000004",
+			SegmentsCount:    2,
+			SegmentRangeSize: 2,
+			MissingSegments:  []string{},
+			Status:           postgres.TimelineOkStatus,
+		},
+		{
+			ID:               4,
+			ParentID:         3,
+			SwitchPointLsn:   0x6000000,
+			StartSegment:     "000000040000000000000006",
+			EndSegment:       "000000040000000000000008",
+			SegmentsCount:    2,
+			SegmentRangeSize: 3,
+			MissingSegments:  []string{"000000040000000000000007"},
+			Status:           postgres.TimelineLostSegmentStatus,
+		},
+	}
+}
+
+func TestWalShowDotOutput(t *testing.T) {
+	const expected = `digraph timelines {
+	rankdir=LR;
+	node [shape=box];
+	tl1 [label="timeline 1\n000000010000000000000001 - 000000010000000000000003\n3 of 3 segments, OK"];
+	tl2 [label="timeline 2\n000000020000000000000003 - 000000020000000000000004\n2 of 2 segments, OK"];
+	tl4 [label="timeline 4\n000000040000000000000006 - 000000040000000000000008\n2 of 3 segments, LOST_SEGMENTS\n1 missing segments"];
+	tl1 -> tl2 [label="0/3000000"];
+	tl3 [label="timeline 3\nno segments", style=dashed];
+	tl3 -> tl4 [label="0/6000000"];
+	"base_000000010000000000000002" [shape=ellipse];
+	tl1 -> "base_000000010000000000000002" [style=dashed];
+}
+`
+
+	var output bytes.Buffer
+	outputWriter := postgres.NewWalShowOutputWriter(postgres.DotOutput, &output, true)
+	assert.NoError(t, outputWriter.Write(getTestTimelineInfos()))
+	assert.Equal(t, expected, output.String())
+}
+
+func TestWalShowTreeJSONOutput(t *testing.T) {
+	var output bytes.Buffer
+	outputWriter := postgres.NewWalShowOutputWriter(postgres.TreeJSONOutput, &output, true)
+	assert.NoError(t, outputWriter.Write(getTestTimelineInfos()))
+
+	var roots []struct {
+		ID       uint32 `json:"id"`
+		Status   string `json:"status"`
+		Children []struct {
+			ID             uint32        `json:"id"`
+			ParentID       uint32        `json:"parent_id"`
+			SwitchPointLsn uint64        `json:"switch_point_lsn"`
+			Children       []interface{} `json:"children"`
+		} `json:"children"`
+	}
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &roots))
+	assert.Len(t, roots, 2)
+	assert.Equal(t, uint32(1), roots[0].ID)
+	assert.Len(t, roots[0].Children, 1)
+	assert.Equal(t, uint32(2), roots[0].Children[0].ID)
+	assert.Equal(t, uint64(0x3000000), roots[0].Children[0].SwitchPointLsn)
+	assert.Empty(t, roots[0].Children[0].Children)
+	// timeline 3 has no segments, it is the placeholder parent of timeline 4
+	assert.Equal(t, uint32(3), roots[1].ID)
+	assert.Equal(t, postgres.TimelineNoSegmentsStatus, roots[1].Status)
+	assert.Len(t, roots[1].Children, 1)
+	assert.Equal(t, uint32(4), roots[1].Children[0].ID)
+	assert.Equal(t, uint32(3), roots[1].Children[0].ParentID)
+	assert.Equal(t, uint64(0x6000000), roots[1].Children[0].SwitchPointLsn)
+}
@@ -45,22 +45,31 @@ func (walUploader *WalUploader) clone() *WalUploader {
 
 // TODO : unit tests
 func (walUploader *WalUploader) UploadWalFile(file ioextensions.NamedReader) error {
-	var walFileReader io.Reader
-
 	filename := path.Base(file.Name())
-	if walUploader.getUseWalDelta() && isWalFilename(filename) {
-		recordingReader, err := NewWalDeltaRecordingReader(file, filename, walUploader.DeltaFileManager)
-		if err != nil {
-			walFileReader = file
-		} else {
-			walFileReader = recordingReader
-			defer utility.LoggedClose(recordingReader, "")
-		}
-	} else {
-		walFileReader = file
+	if !walUploader.getUseWalDelta() || !isWalFilename(filename) {
+		return walUploader.UploadFile(file)
+	}
+	recordingReader, err := NewWalDeltaRecordingReader(file, filename, walUploader.DeltaFileManager)
+	if err != nil {
+		return walUploader.UploadFile(file)
 	}
+	defer utility.LoggedClose(recordingReader, "")
 
-	return walUploader.UploadFile(ioextensions.NewNamedReaderImpl(walFileReader, file.Name()))
+	seeker, isSeekable := file.(io.Seeker)
+	if !isSeekable {
+		return walUploader.UploadFile(ioextensions.NewNamedReaderImpl(recordingReader, file.Name()))
+	}
+	// the deltas are recorded by the first attempt only: the failed attempt still reads the whole file,
+	// so the next attempts upload the file itself read from the start
+	recorded := false
+	return walUploader.UploadReopenableFile(file.Name(), func() (io.Reader, error) {
+		if !recorded {
+			recorded = true
+			return recordingReader, nil
+		}
+		_, err := seeker.Seek(0, io.SeekStart)
+		return file, err
+	})
 }
 
 func (walUploader *WalUploader) FlushFiles() {
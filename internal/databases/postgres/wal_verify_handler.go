@@ -16,10 +16,11 @@ type WalVerifyCheckType int
 const (
 	WalVerifyIntegrityCheck = iota + 1
 	WalVerifyTimelineCheck
+	WalVerifyContentCheck
 )
 
 func (checkType WalVerifyCheckType) String() string {
-	return [...]string{"", "integrity", "timeline"}[checkType]
+	return [...]string{"", "integrity", "timeline", "content"}[checkType]
 }
 
 func (checkType WalVerifyCheckType) MarshalText() (text []byte, err error) {
@@ -119,6 +120,8 @@ func BuildWalVerifyCheckRunner(
 		checkRunner, err = NewTimelineCheckRunner(walFolderFilenames, currentWalSegment)
 	case WalVerifyIntegrityCheck:
 		checkRunner, err = NewIntegrityCheckRunner(rootFolder, walFolderFilenames, currentWalSegment)
+	case WalVerifyContentCheck:
+		checkRunner, err = NewContentCheckRunner(rootFolder, walFolderFilenames, currentWalSegment)
 	default:
 		return nil, NewUnknownWalVerifyCheckError(checkType)
 	}
@@ -140,7 +143,7 @@ func HandleWalVerify(
 	checkResults := make(map[WalVerifyCheckType]WalVerifyCheckResult, len(checkTypes))
 
 	// pre-fetch WAL folder filenames to reduce storage load
-	walFolderFilenames, err := getFolderFilenames(rootFolder.GetSubFolder(utility.WalPath))
+	walFolderFilenames, err := getWalFolderFilenames(rootFolder.GetSubFolder(utility.WalPath))
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch WAL folder filenames: %v", err)
 
 	for _, checkType := range checkTypes {
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"fmt"
+	"io"
 	"sort"
 	"time"
 
@@ -15,6 +16,9 @@ import (
 type PurgeSettings struct {
 	retainCount  *int
 	retainAfter  *time.Time
+	retainPolicy internal.RetainPolicy
+	planOutput   io.Writer
+	plan         *internal.DeletePlan
 	purgeGarbage bool
 	dryRun       bool
 }
@@ -35,6 +39,20 @@ func PurgeRetainCount(retainCount int) PurgeOption {
 	}
 }
 
+// PurgeRetainPolicy ...
+func PurgeRetainPolicy(retainPolicy internal.RetainPolicy) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.retainPolicy = retainPolicy
+	}
+}
+
+// PurgePlanOutput ...
+func PurgePlanOutput(output io.Writer) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.planOutput = output
+	}
+}
+
 // PurgeGarbage ...
 func PurgeGarbage(purgeGarbage bool) PurgeOption {
 	return func(args *PurgeSettings) {
@@ -55,6 +73,9 @@ func HandlePurge(backupsPath string, setters ...PurgeOption) error {
 	for _, setter := range setters {
 		setter(&opts)
 	}
+	if opts.planOutput != nil {
+		opts.plan = internal.NewDeletePlan(!opts.dryRun)
+	}
 
 	folder, err := internal.ConfigureFolder()
 	if err != nil {
@@ -82,6 +103,9 @@ func HandlePurge(backupsPath string, setters ...PurgeOption) error {
 		}
 	}
 
+	if opts.plan != nil {
+		return opts.plan.Write(opts.planOutput)
+	}
 	return nil
 }
 
@@ -102,12 +126,28 @@ func HandleBackupsDelete(backupTimes []internal.BackupTime,
 	timedBackup := archive.RedisModelToTimedBackup(backups)
 
 	internal.SortTimedBackup(timedBackup)
-	purgeBackups, retainBackups, err := internal.SplitPurgingBackups(timedBackup, opts.retainCount, opts.retainAfter)
+	purgeBackups, retainReasons, err := internal.SplitPurgingBackupsWithReasons(timedBackup,
+		opts.retainCount, opts.retainAfter)
 	if err != nil {
 		return nil, nil, err
 	}
+	if !opts.retainPolicy.IsEmpty() {
+		internal.RetainByPolicy(timedBackup, opts.retainPolicy, purgeBackups, retainReasons)
+	}
+	retainBackups := make(map[string]bool, len(retainReasons))
+	for name := range retainReasons {
+		retainBackups[name] = true
+	}
 
 	purge, retain = archive.SplitRedisBackups(backups, purgeBackups, retainBackups)
+	if opts.plan != nil {
+		for _, backup := range purge {
+			opts.plan.AddBackupToDelete(backup.BackupName, backup.StartLocalTime, backup.BackupSize)
+		}
+		for _, backup := range retain {
+			opts.plan.AddRetainedBackup(backup.BackupName, backup.StartLocalTime, retainReasons[backup.BackupName])
+		}
+	}
 
 	purgeFiles := BackupNamesFromBackups(purge)
 
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -35,18 +36,29 @@ const (
 	delete every backup only if there is no permanent backups
   everything FORCE          delete every backup include permanents`
 
+	DeleteRetainPolicyExamples = `  retain-policy --daily 7 --weekly 4 --monthly 12
+	keep the latest backup of each of the last 7 days, 4 weeks and 12 months`
+
 	DeleteTargetExamples = `  target base_0000000100000000000000C4	delete base backup by name
   target --target-user-data "{ \"x\": [3], \"y\": 4 }"	delete backup specified by user data
   target base_0000000100000000000000C9_D_0000000100000000000000C4	delete delta backup and all dependant delta backups 
   target FIND_FULL base_0000000100000000000000C9_D_0000000100000000000000C4	delete delta backup and all delta backups with the same base backup`  //nolint:lll
 
-	DeleteEverythingUsageExample = "everything [FORCE]"
-	DeleteRetainUsageExample     = "retain [FULL|FIND_FULL] backup_count"
-	DeleteBeforeUsageExample     = "before [FIND_FULL] backup_name|timestamp"
-	DeleteTargetUsageExample     = "target [FIND_FULL] backup_name | --target-user-data <data>"
+	DeleteEverythingUsageExample   = "everything [FORCE]"
+	DeleteRetainUsageExample       = "retain [FULL|FIND_FULL] backup_count"
+	DeleteBeforeUsageExample       = "before [FIND_FULL] backup_name|timestamp"
+	DeleteTargetUsageExample       = "target [FIND_FULL] backup_name | --target-user-data <data>"
+	DeleteRetainPolicyUsageExample = "retain-policy [--daily N] [--weekly N] [--monthly N]"
 
 	DeleteTargetUserDataFlag        = "target-user-data"
 	DeleteTargetUserDataDescription = "delete storage backup which has the specified user data"
+
+	DeleteRetainDailyFlag          = "daily"
+	DeleteRetainDailyDescription   = "keep the latest backup of each of the last N days"
+	DeleteRetainWeeklyFlag         = "weekly"
+	DeleteRetainWeeklyDescription  = "keep the latest backup of each of the last N weeks"
+	DeleteRetainMonthlyFlag        = "monthly"
+	DeleteRetainMonthlyDescription = "keep the latest backup of each of the last N months"
 )
 
 var StringModifiers = []string{"FULL", "FIND_FULL"}
@@ -67,6 +79,83 @@ type BackupObject interface {
 	GetIncrementFromName() string
 }
 
+// RetainPolicy is the grandfather-father-son retention policy:
+// the latest backup of each of the last Daily days, Weekly weeks and Monthly months is kept.
+// Periods are calculated in UTC.
+type RetainPolicy struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+func (p RetainPolicy) IsEmpty() bool {
+	return p.Daily <= 0 && p.Weekly <= 0 && p.Monthly <= 0
+}
+
+type retainPeriod struct {
+	name      string
+	count     int
+	periodKey func(t time.Time) string
+}
+
+func (p RetainPolicy) periods() []retainPeriod {
+	return []retainPeriod{
+		{"daily", p.Daily, func(t time.Time) string {
+			return t.UTC().Format("2006-01-02")
+		}},
+		{"weekly", p.Weekly, func(t time.Time) string {
+			year, week := t.UTC().ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{"monthly", p.Monthly, func(t time.Time) string {
+			return t.UTC().Format("2006-01")
+		}},
+	}
+}
+
+// FindBackupsRetainedByPolicy returns the reasons to keep every backup retained by the policy.
+// Permanent backups are always retained.
+func FindBackupsRetainedByPolicy(backups []TimedBackup, policy RetainPolicy) map[string][]string {
+	sortedBackups := append(make([]TimedBackup, 0, len(backups)), backups...)
+	SortTimedBackup(sortedBackups)
+
+	retained := make(map[string][]string)
+	for _, backup := range sortedBackups {
+		if backup.IsPermanent() {
+			retained[backup.Name()] = append(retained[backup.Name()], "permanent")
+		}
+	}
+	for _, period := range policy.periods() {
+		seenPeriods := make(map[string]bool)
+		for _, backup := range sortedBackups {
+			if len(seenPeriods) >= period.count {
+				break
+			}
+			key := period.periodKey(backup.StartTime())
+			if seenPeriods[key] {
+				continue
+			}
+			// backups are sorted from the newest, so the first one met is the latest in the period
+			seenPeriods[key] = true
+			retained[backup.Name()] = append(retained[backup.Name()], period.name+" "+key)
+		}
+	}
+	return retained
+}
+
+// RetainByPolicy moves the backups retained by the policy from purge to retain
+func RetainByPolicy(backups []TimedBackup, policy RetainPolicy, purge map[string]bool, retain map[string][]string) {
+	for name, reasons := range FindBackupsRetainedByPolicy(backups, policy) {
+		tracelog.DebugLogger.Printf("Preserving backup due to retain policy %v: %s", reasons, name)
+		delete(purge, name)
+		for _, reason := range reasons {
+			if !containsString(retain[name], reason) {
+				retain[name] = append(retain[name], reason)
+			}
+		}
+	}
+}
+
 type DeleteHandlerOption func(h *DeleteHandler)
 
 func IsPermanentFunc(isPermanent func(storage.Object) bool) DeleteHandlerOption {
@@ -75,6 +164,22 @@ func IsPermanentFunc(isPermanent func(storage.Object) bool) DeleteHandlerOption
 	}
 }
 
+// ListObjectsFunc makes DeleteHandler list the objects to delete with the function
+// instead of listing the folder recursively, e.g. to take them from an index
+func ListObjectsFunc(listObjects func(folder storage.Folder) ([]storage.Object, error)) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.listObjects = listObjects
+	}
+}
+
+// DeletePlanOutput makes DeleteHandler print the deletion plan as JSON to the output
+func DeletePlanOutput(output io.Writer) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.planOutput = output
+		h.plan = NewDeletePlan(false)
+	}
+}
+
 func NewDeleteHandler(
 	folder storage.Folder,
 	backups []BackupObject,
@@ -89,7 +194,10 @@ func NewDeleteHandler(
 			return less(object2, object1)
 		},
 		// by default, all storage objects are impermanent
-		isPermanent: func(storage.Object) bool { return false },
+		isPermanent:        func(storage.Object) bool { return false },
+		listObjects:        storage.ListFolderRecursively,
+		retainReasons:      make(map[string][]string),
+		deletedBackupBytes: make(map[string]int64),
 	}
 
 	for _, option := range options {
@@ -107,6 +215,13 @@ type DeleteHandler struct {
 	greater func(object1, object2 storage.Object) bool
 
 	isPermanent func(object storage.Object) bool
+
+	listObjects func(folder storage.Folder) ([]storage.Object, error)
+
+	planOutput         io.Writer
+	plan               *DeletePlan
+	retainReasons      map[string][]string
+	deletedBackupBytes map[string]int64
 }
 
 func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
@@ -122,12 +237,14 @@ func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
 
 	tracelog.ErrorLogger.FatalOnError(err)
 	if target == nil {
-		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		h.exitNothingToDelete()
 	}
 
 	err = h.DeleteBeforeTarget(target, confirmed)
 	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.writePlan(confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetain(args []string, confirmed bool) {
@@ -138,11 +255,13 @@ func (h *DeleteHandler) HandleDeleteRetain(args []string, confirmed bool) {
 	target, err := h.FindTargetRetain(retentionCount, modifier)
 	tracelog.ErrorLogger.FatalOnError(err)
 	if target == nil {
-		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		h.exitNothingToDelete()
 	}
 	err = h.DeleteBeforeTarget(target, confirmed)
 	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.writePlan(confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetainAfter(args []string, confirmed bool) {
@@ -160,12 +279,29 @@ func (h *DeleteHandler) HandleDeleteRetainAfter(args []string, confirmed bool) {
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	if target == nil {
-		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		h.exitNothingToDelete()
 	}
 
 	err = h.DeleteBeforeTarget(target, confirmed)
 	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.writePlan(confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+func (h *DeleteHandler) HandleDeleteRetainPolicy(policy RetainPolicy, confirmed bool) {
+	if len(h.backups) == 0 {
+		h.exitNothingToDelete()
+	}
+
+	retained, err := h.FindRetainedByPolicy(policy)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.DeleteNotRetained(retained, confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.writePlan(confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confirmed, findFull bool) {
@@ -181,8 +317,7 @@ func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confir
 	}
 
 	if target == nil {
-		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		h.exitNothingToDelete()
 	}
 
 	var backupsToDelete []BackupObject
@@ -196,6 +331,9 @@ func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confir
 
 	err = h.DeleteTargets(backupsToDelete, confirmed)
 	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.writePlan(confirmed)
+	tracelog.ErrorLogger.FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteEverything(args []string, permanentBackups map[string]bool, confirmed bool) {
@@ -322,9 +460,96 @@ func (h *DeleteHandler) FindTargetRetainAfterTime(retentionCount int, timeLine t
 	return target2, nil
 }
 
+// FindRetainedByPolicy returns the reasons to keep every backup retained by the policy.
+// Increment chains of the retained backups are retained too.
+func (h *DeleteHandler) FindRetainedByPolicy(policy RetainPolicy) (map[string][]string, error) {
+	backupsByName := make(map[string]BackupObject, len(h.backups))
+	timedBackups := make([]TimedBackup, 0, len(h.backups))
+	for _, backup := range h.backups {
+		backupsByName[backup.GetBackupName()] = backup
+		timedBackups = append(timedBackups, timedBackupObject{backup, h.isPermanentBackup(backup)})
+	}
+
+	retained := FindBackupsRetainedByPolicy(timedBackups, policy)
+
+	retainedNames := make([]string, 0, len(retained))
+	for name := range retained {
+		retainedNames = append(retainedNames, name)
+	}
+	for _, name := range retainedNames {
+		backup := backupsByName[name]
+		for !backup.IsFullBackup() {
+			incrementFrom, ok := backupsByName[backup.GetIncrementFromName()]
+			if !ok {
+				return nil, fmt.Errorf("failed to find %s which is the increment base of %s",
+					backup.GetIncrementFromName(), backup.GetBackupName())
+			}
+			retained[incrementFrom.GetBackupName()] = append(retained[incrementFrom.GetBackupName()],
+				"increment base of "+name)
+			backup = incrementFrom
+		}
+	}
+	return retained, nil
+}
+
+// DeleteNotRetained deletes all backups except the retained ones.
+// WALs are deleted only before the oldest retained backup.
+func (h *DeleteHandler) DeleteNotRetained(retained map[string][]string, confirmed bool) error {
+	var retainedBackups, deletedBackups []BackupObject
+	for _, backup := range h.backups {
+		if _, ok := retained[backup.GetBackupName()]; ok {
+			retainedBackups = append(retainedBackups, backup)
+		} else {
+			deletedBackups = append(deletedBackups, backup)
+		}
+	}
+	if len(retainedBackups) == 0 {
+		return utility.NewForbiddenActionError("Retain policy keeps no backups. Check out delete everything")
+	}
+	sort.Slice(retainedBackups, func(i, j int) bool {
+		return h.less(retainedBackups[i], retainedBackups[j])
+	})
+	for _, backup := range retainedBackups {
+		tracelog.InfoLogger.Printf("Retain %s: %s\n", backup.GetBackupName(),
+			strings.Join(retained[backup.GetBackupName()], ", "))
+		h.setRetainReason(backup.GetBackupName(), retained[backup.GetBackupName()]...)
+	}
+
+	// permanent backups are protected from deletion anyway,
+	// so they should not keep the WALs of the newer backups
+	target := retainedBackups[0]
+	for _, backup := range retainedBackups {
+		if !h.isPermanentBackup(backup) {
+			if backup.IsFullBackup() {
+				target = backup
+			}
+			break
+		}
+	}
+	err := h.DeleteBeforeTarget(target, confirmed)
+	if err != nil {
+		return err
+	}
+
+	// backups between the retained ones are deleted without WALs to keep the point-in-time recovery possible
+	var backupsAfterTarget []BackupObject
+	for _, backup := range deletedBackups {
+		if !h.less(backup, target) {
+			backupsAfterTarget = append(backupsAfterTarget, backup)
+		}
+	}
+	if len(backupsAfterTarget) == 0 {
+		return nil
+	}
+	return h.DeleteTargets(backupsAfterTarget, confirmed)
+}
+
 func (h *DeleteHandler) DeleteEverything(confirmed bool) {
 	filter := func(object storage.Object) bool { return true }
-	err := storage.DeleteObjectsWhere(h.Folder, confirmed, filter)
+	err := h.deleteObjectsWhere(h.Folder, "", confirmed, filter)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	err = h.writePlan(confirmed)
 	tracelog.ErrorLogger.FatalOnError(err)
 }
 
@@ -335,7 +560,12 @@ func (h *DeleteHandler) DeleteBeforeTarget(target BackupObject, confirmed bool)
 	}
 	tracelog.InfoLogger.Println("Start delete")
 
-	return storage.DeleteObjectsWhere(h.Folder, confirmed, func(object storage.Object) bool {
+	for _, backup := range h.backups {
+		if !h.less(backup, target) {
+			h.setRetainReason(backup.GetBackupName(), "not older than "+target.GetBackupName())
+		}
+	}
+	return h.deleteObjectsWhere(h.Folder, "", confirmed, func(object storage.Object) bool {
 		return h.less(object, target) && !h.isPermanent(object)
 	})
 }
@@ -348,13 +578,111 @@ func (h *DeleteHandler) DeleteTargets(targets []BackupObject, confirmed bool) er
 		}
 		backupNamesToDelete[target.GetBackupName()] = true
 	}
+	for _, backup := range h.backups {
+		if !backupNamesToDelete[backup.GetBackupName()] {
+			h.setRetainReason(backup.GetBackupName(), "not selected for deletion")
+		}
+	}
 
-	return storage.DeleteObjectsWhere(h.Folder.GetSubFolder(utility.BaseBackupPath),
+	return h.deleteObjectsWhere(h.Folder.GetSubFolder(utility.BaseBackupPath), utility.BaseBackupPath,
 		confirmed, func(object storage.Object) bool {
 			return backupNamesToDelete[utility.StripLeftmostBackupName(object.GetName())] && !h.isPermanent(object)
 		})
 }
 
+// deleteObjectsWhere is storage.DeleteObjectsWhere which lists the objects with listObjects
+// and also adds the deleted objects to the plan. folderPath is the path of the folder relative to the storage root.
+func (h *DeleteHandler) deleteObjectsWhere(folder storage.Folder, folderPath string, confirmed bool,
+	filter func(object storage.Object) bool) error {
+	objects, err := h.listObjects(folder)
+	if err != nil {
+		return err
+	}
+	objectPaths := make([]string, 0)
+	tracelog.InfoLogger.Println("Objects in folder:")
+	for _, object := range objects {
+		if !filter(object) {
+			tracelog.DebugLogger.Println("\tskipped: " + object.GetName())
+			continue
+		}
+		tracelog.InfoLogger.Println("\twill be deleted: " + object.GetName())
+		objectPaths = append(objectPaths, object.GetName())
+		if h.plan != nil {
+			h.addObjectToPlan(folderPath+object.GetName(), object.GetSize())
+		}
+	}
+	if len(objectPaths) == 0 {
+		return nil
+	}
+	if !confirmed {
+		tracelog.InfoLogger.Println("Dry run, nothing were deleted")
+		return nil
+	}
+	return folder.DeleteObjects(objectPaths)
+}
+
+func (h *DeleteHandler) addObjectToPlan(objectPath string, size int64) {
+	if strings.HasPrefix(objectPath, utility.BaseBackupPath) {
+		backupName := utility.StripLeftmostBackupName(strings.TrimPrefix(objectPath, utility.BaseBackupPath))
+		h.deletedBackupBytes[backupName] += size
+		return
+	}
+	logFolder, logName := "", objectPath
+	if idx := strings.Index(objectPath, "/"); idx >= 0 {
+		logFolder, logName = objectPath[:idx], objectPath[idx+1:]
+	}
+	h.plan.AddLogToDelete(logFolder, logName, size)
+}
+
+func (h *DeleteHandler) setRetainReason(backupName string, reasons ...string) {
+	if _, ok := h.retainReasons[backupName]; !ok {
+		h.retainReasons[backupName] = reasons
+	}
+}
+
+func (h *DeleteHandler) writePlan(confirmed bool) error {
+	if h.plan == nil {
+		return nil
+	}
+	h.plan.Confirmed = confirmed
+	deletedBackupBytes := make(map[string]int64, len(h.deletedBackupBytes))
+	for name, size := range h.deletedBackupBytes {
+		deletedBackupBytes[name] = size
+	}
+	for _, backup := range h.backups {
+		name := backup.GetBackupName()
+		if size, ok := deletedBackupBytes[name]; ok {
+			h.plan.AddBackupToDelete(name, backup.GetBackupTime(), size)
+			delete(deletedBackupBytes, name)
+			continue
+		}
+		reasons := h.retainReasons[name]
+		if h.isPermanentBackup(backup) && !containsString(reasons, "permanent") {
+			reasons = append([]string{"permanent"}, reasons...)
+		}
+		h.plan.AddRetainedBackup(name, backup.GetBackupTime(), reasons)
+	}
+	// objects of the incomplete backups without sentinels
+	for _, size := range deletedBackupBytes {
+		h.plan.BytesFreed += size
+	}
+	return h.plan.Write(h.planOutput)
+}
+
+func (h *DeleteHandler) exitNothingToDelete() {
+	tracelog.InfoLogger.Printf("No backup found for deletion")
+	err := h.writePlan(false)
+	tracelog.ErrorLogger.FatalOnError(err)
+	os.Exit(0)
+}
+
+// isPermanentBackup checks the backup sentinel which is listed relative to the backups folder,
+// while isPermanent expects the path relative to the storage root
+func (h *DeleteHandler) isPermanentBackup(backup BackupObject) bool {
+	return h.isPermanent(storage.NewLocalObject(
+		utility.BaseBackupPath+backup.GetName(), backup.GetLastModified(), backup.GetSize()))
+}
+
 // Find all backups related to the target.
 // All delta backups with the same base backup are considered as related.
 func (h *DeleteHandler) findRelatedBackups(target BackupObject) []BackupObject {
@@ -411,6 +739,33 @@ func (h *DeleteHandler) findDependantBackups(target BackupObject) []BackupObject
 	return relatedBackups
 }
 
+// timedBackupObject adapts BackupObject to the TimedBackup
+type timedBackupObject struct {
+	BackupObject
+	isPermanent bool
+}
+
+func (o timedBackupObject) Name() string {
+	return o.GetBackupName()
+}
+
+func (o timedBackupObject) StartTime() time.Time {
+	return o.GetBackupTime()
+}
+
+func (o timedBackupObject) IsPermanent() bool {
+	return o.isPermanent
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func findTarget(objects []BackupObject,
 	compare func(object1, object2 storage.Object) bool,
 	isTarget func(object BackupObject) bool) (BackupObject, error) {
@@ -558,6 +913,27 @@ func DeleteRetainArgsValidator(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func DeleteRetainPolicyArgsValidator(cmd *cobra.Command, args []string) error {
+	err := cobra.NoArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	for _, flag := range []string{DeleteRetainDailyFlag, DeleteRetainWeeklyFlag, DeleteRetainMonthlyFlag} {
+		if cmd.Flags().Changed(flag) {
+			return nil
+		}
+	}
+	return fmt.Errorf("at least one of --%s, --%s, --%s flags is required",
+		DeleteRetainDailyFlag, DeleteRetainWeeklyFlag, DeleteRetainMonthlyFlag)
+}
+
+// AddRetainPolicyFlags adds the flags of the retain-policy subcommand
+func AddRetainPolicyFlags(cmd *cobra.Command, policy *RetainPolicy) {
+	cmd.Flags().IntVar(&policy.Daily, DeleteRetainDailyFlag, 0, DeleteRetainDailyDescription)
+	cmd.Flags().IntVar(&policy.Weekly, DeleteRetainWeeklyFlag, 0, DeleteRetainWeeklyDescription)
+	cmd.Flags().IntVar(&policy.Monthly, DeleteRetainMonthlyFlag, 0, DeleteRetainMonthlyDescription)
+}
+
 func DeleteRetainAfterArgsValidator(cmd *cobra.Command, args []string) error {
 	err := deleteArgsValidator(args, StringModifiers, 2, 3)
 	if err != nil {
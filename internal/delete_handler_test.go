@@ -0,0 +1,119 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+type testTimedBackup struct {
+	name        string
+	startTime   time.Time
+	isPermanent bool
+}
+
+func (b testTimedBackup) Name() string {
+	return b.name
+}
+
+func (b testTimedBackup) StartTime() time.Time {
+	return b.startTime
+}
+
+func (b testTimedBackup) IsPermanent() bool {
+	return b.isPermanent
+}
+
+type testDeltaBackupObject struct {
+	storage.Object
+	name          string
+	incrementFrom string
+}
+
+func (o testDeltaBackupObject) GetBackupTime() time.Time {
+	return o.GetLastModified()
+}
+
+func (o testDeltaBackupObject) GetBackupName() string {
+	return o.name
+}
+
+func (o testDeltaBackupObject) IsFullBackup() bool {
+	return o.incrementFrom == ""
+}
+
+func (o testDeltaBackupObject) GetBaseBackupName() string {
+	return o.incrementFrom
+}
+
+func (o testDeltaBackupObject) GetIncrementFromName() string {
+	return o.incrementFrom
+}
+
+func TestFindBackupsRetainedByPolicy(t *testing.T) {
+	// one backup every 12 hours, starting from Sunday
+	baseTime := time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)
+	var backups []internal.TimedBackup
+	for i := 0; i < 20; i++ {
+		backups = append(backups, testTimedBackup{
+			name:        baseTime.Add(time.Duration(i) * 12 * time.Hour).Format("2006-01-02T15"),
+			startTime:   baseTime.Add(time.Duration(i) * 12 * time.Hour),
+			isPermanent: i == 0,
+		})
+	}
+
+	retained := internal.FindBackupsRetainedByPolicy(backups, internal.RetainPolicy{Daily: 3, Weekly: 2, Monthly: 2})
+
+	assert.Equal(t, map[string][]string{
+		"2021-03-09T12": {"daily 2021-03-09", "weekly 2021-W10", "monthly 2021-03"},
+		"2021-03-08T12": {"daily 2021-03-08"},
+		"2021-03-07T12": {"daily 2021-03-07", "weekly 2021-W09"},
+		"2021-02-28T12": {"monthly 2021-02"},
+		"2021-02-28T00": {"permanent"},
+	}, retained)
+}
+
+func TestDeleteHandler_FindRetainedByPolicy_KeepsIncrementChains(t *testing.T) {
+	baseTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	newBackup := func(name, incrementFrom string, days int) internal.BackupObject {
+		return testDeltaBackupObject{
+			Object:        storage.NewLocalObject(name, baseTime.AddDate(0, 0, days), 0),
+			name:          name,
+			incrementFrom: incrementFrom,
+		}
+	}
+	backups := []internal.BackupObject{
+		newBackup("full_1", "", 0),
+		newBackup("delta_1_1", "full_1", 1),
+		newBackup("delta_1_2", "delta_1_1", 2),
+		newBackup("full_2", "", 3),
+		newBackup("delta_2_1", "full_2", 4),
+	}
+	deleteHandler := internal.NewDeleteHandler(testtools.MakeDefaultInMemoryStorageFolder(), backups,
+		func(object1, object2 storage.Object) bool {
+			return object1.GetLastModified().Before(object2.GetLastModified())
+		})
+
+	retained, err := deleteHandler.FindRetainedByPolicy(internal.RetainPolicy{Daily: 1, Weekly: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"delta_2_1": {"daily 2021-03-05", "weekly 2021-W09"},
+		"full_2":    {"increment base of delta_2_1"},
+	}, retained)
+
+	retained, err = deleteHandler.FindRetainedByPolicy(internal.RetainPolicy{Monthly: 1, Daily: 3})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"delta_2_1", "full_2", "delta_1_2", "delta_1_1", "full_1"}, backupNames(retained))
+}
+
+func backupNames(m map[string][]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	DeletePlanJSONFlag        = "json"
+	DeletePlanJSONDescription = "Print the deletion plan in JSON format to stdout"
+)
+
+// DeletePlan describes the backups and logs (WALs, binlogs or oplog archives)
+// selected by delete. It is printed with the --json flag,
+// so the plan of the dry run can be reviewed before the confirmed run.
+type DeletePlan struct {
+	Confirmed       bool                 `json:"confirmed"`
+	BackupsToDelete []DeletePlanBackup   `json:"backups_to_delete"`
+	RetainedBackups []DeletePlanBackup   `json:"retained_backups"`
+	LogsToDelete    []DeletePlanLogRange `json:"logs_to_delete"`
+	BytesFreed      int64                `json:"bytes_freed"`
+}
+
+type DeletePlanBackup struct {
+	Name    string    `json:"name"`
+	Time    time.Time `json:"time"`
+	Bytes   int64     `json:"bytes,omitempty"`
+	Reasons []string  `json:"reasons,omitempty"`
+}
+
+// DeletePlanLogRange describes the logs to delete from a single folder
+type DeletePlanLogRange struct {
+	Folder string `json:"folder"`
+	First  string `json:"first"`
+	Last   string `json:"last"`
+	Count  int    `json:"count"`
+	Bytes  int64  `json:"bytes"`
+}
+
+func NewDeletePlan(confirmed bool) *DeletePlan {
+	return &DeletePlan{
+		Confirmed:       confirmed,
+		BackupsToDelete: make([]DeletePlanBackup, 0),
+		RetainedBackups: make([]DeletePlanBackup, 0),
+		LogsToDelete:    make([]DeletePlanLogRange, 0),
+	}
+}
+
+func (p *DeletePlan) AddBackupToDelete(name string, backupTime time.Time, size int64) {
+	p.BackupsToDelete = append(p.BackupsToDelete, DeletePlanBackup{Name: name, Time: backupTime, Bytes: size})
+	p.BytesFreed += size
+}
+
+func (p *DeletePlan) AddRetainedBackup(name string, backupTime time.Time, reasons []string) {
+	p.RetainedBackups = append(p.RetainedBackups, DeletePlanBackup{Name: name, Time: backupTime, Reasons: reasons})
+}
+
+// AddLogToDelete extends the range of logs deleted from the folder. Logs are ordered by name.
+func (p *DeletePlan) AddLogToDelete(folder, name string, size int64) {
+	p.BytesFreed += size
+	for i := range p.LogsToDelete {
+		logRange := &p.LogsToDelete[i]
+		if logRange.Folder != folder {
+			continue
+		}
+		if name < logRange.First {
+			logRange.First = name
+		}
+		if name > logRange.Last {
+			logRange.Last = name
+		}
+		logRange.Count++
+		logRange.Bytes += size
+		return
+	}
+	p.LogsToDelete = append(p.LogsToDelete,
+		DeletePlanLogRange{Folder: folder, First: name, Last: name, Count: 1, Bytes: size})
+}
+
+// Write prints the plan as JSON, backups are sorted by time
+func (p *DeletePlan) Write(output io.Writer) error {
+	for _, backups := range [][]DeletePlanBackup{p.BackupsToDelete, p.RetainedBackups} {
+		backups := backups
+		sort.SliceStable(backups, func(i, j int) bool {
+			return backups[i].Time.Before(backups[j].Time)
+		})
+	}
+	sort.Slice(p.LogsToDelete, func(i, j int) bool {
+		return p.LogsToDelete[i].Folder < p.LogsToDelete[j].Folder
+	})
+	return WriteAsJSON(p, output, true)
+}
@@ -0,0 +1,40 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestDeletePlan_Write(t *testing.T) {
+	baseTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	plan := internal.NewDeletePlan(false)
+	plan.AddBackupToDelete("base_000000010000000000000004", baseTime.Add(time.Hour), 100)
+	plan.AddBackupToDelete("base_000000010000000000000002", baseTime, 200)
+	plan.AddRetainedBackup("base_000000010000000000000006", baseTime.Add(2*time.Hour), []string{"permanent"})
+	plan.AddLogToDelete("wal_005", "000000010000000000000003.lz4", 10)
+	plan.AddLogToDelete("wal_005", "000000010000000000000001.lz4", 10)
+	plan.AddLogToDelete("wal_005", "000000010000000000000002.lz4", 10)
+
+	var buf bytes.Buffer
+	assert.NoError(t, plan.Write(&buf))
+
+	var decoded internal.DeletePlan
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.False(t, decoded.Confirmed)
+	assert.Equal(t, int64(330), decoded.BytesFreed)
+	assert.Equal(t, "base_000000010000000000000002", decoded.BackupsToDelete[0].Name)
+	assert.Equal(t, "base_000000010000000000000004", decoded.BackupsToDelete[1].Name)
+	assert.Equal(t, []string{"permanent"}, decoded.RetainedBackups[0].Reasons)
+	assert.Equal(t, []internal.DeletePlanLogRange{{
+		Folder: "wal_005",
+		First:  "000000010000000000000001.lz4",
+		Last:   "000000010000000000000003.lz4",
+		Count:  3,
+		Bytes:  30,
+	}}, decoded.LogsToDelete)
+}
@@ -1,18 +1,29 @@
 package internal
 
-import "time"
+import (
+	"math/rand"
+	"time"
+)
 
 type ExponentialSleeper struct {
 	sleepDuration      time.Duration
 	sleepDurationBound time.Duration
+	jitter             float64
 }
 
 func NewExponentialSleeper(startSleepDuration, sleepDurationBound time.Duration) *ExponentialSleeper {
-	return &ExponentialSleeper{startSleepDuration, sleepDurationBound}
+	return &ExponentialSleeper{startSleepDuration, sleepDurationBound, 0}
+}
+
+// NewJitteredExponentialSleeper returns the sleeper which shortens every sleep by up to the jitter fraction at random,
+// so the clients failed together don't retry together
+func NewJitteredExponentialSleeper(startSleepDuration, sleepDurationBound time.Duration,
+	jitter float64) *ExponentialSleeper {
+	return &ExponentialSleeper{startSleepDuration, sleepDurationBound, jitter}
 }
 
 func (sleeper *ExponentialSleeper) Sleep() {
-	time.Sleep(sleeper.sleepDuration)
+	time.Sleep(sleeper.sleepDuration - time.Duration(sleeper.jitter*rand.Float64()*float64(sleeper.sleepDuration)))
 	sleeper.sleepDuration *= 2
 	if sleeper.sleepDuration > sleeper.sleepDurationBound {
 		sleeper.sleepDuration = sleeper.sleepDurationBound
@@ -14,6 +14,7 @@ import (
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 	"golang.org/x/sync/semaphore"
 )
@@ -106,6 +107,7 @@ func DecryptAndDecompressTar(writer io.Writer, readerMaker ReaderMaker, crypter
 		return errors.Wrap(err, "DecryptAndDecompressTar: failed to create new reader")
 	}
 	defer utility.LoggedClose(readCloser, "")
+	storageReader := readCloser
 
 	if crypter != nil {
 		var reader io.Reader
@@ -122,7 +124,10 @@ func DecryptAndDecompressTar(writer io.Writer, readerMaker ReaderMaker, crypter
 	fileExtension := utility.GetFileExtension(readerMaker.Path())
 	if fileExtension == "tar" {
 		_, err = io.Copy(writer, readCloser)
-		return errors.Wrap(err, "DecryptAndDecompressTar: tar extract failed")
+		if err != nil {
+			return errors.Wrap(err, "DecryptAndDecompressTar: tar extract failed")
+		}
+		return VerifyChecksum(storageReader)
 	}
 
 	for _, decompressor := range compression.Decompressors {
@@ -131,7 +136,7 @@ func DecryptAndDecompressTar(writer io.Writer, readerMaker ReaderMaker, crypter
 		}
 		err = decompressor.Decompress(writer, readCloser)
 		if err == nil {
-			return nil
+			return VerifyChecksum(storageReader)
 		}
 		decompressionError := newDecompressionError(err)
 		return errors.Wrapf(decompressionError,
@@ -150,7 +155,31 @@ func ExtractAll(tarInterpreter TarInterpreter, files []ReaderMaker) error {
 	return ExtractAllWithSleeper(tarInterpreter, files, NewExponentialSleeper(MinExtractRetryWait, MaxExtractRetryWait))
 }
 
+// ExtractAllWithJournal skips the tars recorded in the journal and records the tars extracted in full
+func ExtractAllWithJournal(tarInterpreter TarInterpreter, files []ReaderMaker, journal *ExtractJournal) error {
+	if len(files) == 0 {
+		return newNoFilesToExtractError()
+	}
+	filesToExtract := make([]ReaderMaker, 0, len(files))
+	for _, file := range files {
+		if journal.isExtracted(file) {
+			tracelog.InfoLogger.Printf("Skipping %s, it is already extracted", file.Path())
+			continue
+		}
+		filesToExtract = append(filesToExtract, file)
+	}
+	if len(filesToExtract) == 0 {
+		return nil
+	}
+	return extractAll(tarInterpreter, filesToExtract,
+		NewExponentialSleeper(MinExtractRetryWait, MaxExtractRetryWait), journal)
+}
+
 func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, sleeper Sleeper) error {
+	return extractAll(tarInterpreter, files, sleeper, nil)
+}
+
+func extractAll(tarInterpreter TarInterpreter, files []ReaderMaker, sleeper Sleeper, journal *ExtractJournal) error {
 	if len(files) == 0 {
 		return newNoFilesToExtractError()
 	}
@@ -161,7 +190,7 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 		return err
 	}
 	for currentRun := files; len(currentRun) > 0; {
-		failed := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency)
+		failed := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency, journal)
 		if downloadingConcurrency > 1 {
 			downloadingConcurrency /= 2
 		} else if len(failed) == len(currentRun) {
@@ -170,6 +199,7 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 		}
 		currentRun = failed
 		if len(failed) > 0 {
+			metrics.Retries.Add(int64(len(failed)))
 			sleeper.Sleep()
 		}
 	}
@@ -180,7 +210,8 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 // TODO : unit tests
 func tryExtractFiles(files []ReaderMaker,
 	tarInterpreter TarInterpreter,
-	downloadingConcurrency int) (failed []ReaderMaker) {
+	downloadingConcurrency int,
+	journal *ExtractJournal) (failed []ReaderMaker) {
 	downloadingContext := context.TODO()
 	downloadingSemaphore := semaphore.NewWeighted(int64(downloadingConcurrency))
 	crypter := ConfigureCrypter()
@@ -192,6 +223,7 @@ func tryExtractFiles(files []ReaderMaker,
 
 		extractingReader, pipeWriter := io.Pipe()
 		decompressingWriter := &EmptyWriteIgnorer{pipeWriter}
+		decompressionDone := make(chan bool, 1)
 		go func() {
 			err := DecryptAndDecompressTar(decompressingWriter, fileClosure, crypter)
 			utility.LoggedClose(decompressingWriter, "")
@@ -200,6 +232,7 @@ func tryExtractFiles(files []ReaderMaker,
 				isFailed.Store(fileClosure, true)
 				tracelog.ErrorLogger.Println(fileClosure.Path(), err)
 			}
+			decompressionDone <- err == nil
 		}()
 		go func() {
 			defer downloadingSemaphore.Release(1)
@@ -211,6 +244,10 @@ func tryExtractFiles(files []ReaderMaker,
 				isFailed.Store(fileClosure, true)
 				tracelog.ErrorLogger.Println(err)
 			}
+			// the tar is recorded once its checksum is verified by the decompression
+			if isDecompressed := <-decompressionDone; isDecompressed && err == nil {
+				journal.markExtracted(fileClosure)
+			}
 		}()
 	}
 
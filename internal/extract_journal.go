@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ExtractJournalFileName is the journal in the destination directory of backup-fetch
+const ExtractJournalFileName = ".walg_fetch_journal.json"
+
+// ExtractJournal records the tars extracted in full to the destination directory,
+// so the interrupted extraction skips them when it is resumed
+type ExtractJournal struct {
+	path      string
+	isResumed bool
+	mutex     sync.Mutex
+
+	// Tars maps the storage paths of the extracted tars to their checksums
+	Tars map[string]string `json:"tars"`
+}
+
+// OpenExtractJournal creates the journal in the directory. The journal left by the interrupted
+// extraction is loaded if the extraction is resumed and replaced otherwise.
+func OpenExtractJournal(directory string, resume bool) (*ExtractJournal, error) {
+	journal := &ExtractJournal{
+		path: filepath.Join(directory, ExtractJournalFileName),
+		Tars: make(map[string]string),
+	}
+	if resume {
+		data, err := ioutil.ReadFile(journal.path)
+		switch {
+		case os.IsNotExist(err):
+			tracelog.InfoLogger.Printf("No journal is found in %s, nothing to resume\n", directory)
+		case err != nil:
+			return nil, errors.Wrap(err, "failed to read the fetch journal")
+		default:
+			if err = json.Unmarshal(data, journal); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse the fetch journal %s", journal.path)
+			}
+			journal.isResumed = true
+			tracelog.InfoLogger.Printf("Resuming the fetch, %d tars are already extracted\n", len(journal.Tars))
+		}
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create the directory %s", directory)
+	}
+	return journal, journal.save()
+}
+
+// IsResumed tells whether the journal of the interrupted extraction is loaded
+func (journal *ExtractJournal) IsResumed() bool {
+	return journal != nil && journal.isResumed
+}
+
+func (journal *ExtractJournal) isExtracted(file ReaderMaker) bool {
+	if journal == nil {
+		return false
+	}
+	key, checksum := getExtractJournalKey(file)
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	recordedChecksum, ok := journal.Tars[key]
+	return ok && recordedChecksum == checksum
+}
+
+// markExtracted records the tar, the journal errors do not fail the extraction
+func (journal *ExtractJournal) markExtracted(file ReaderMaker) {
+	if journal == nil {
+		return
+	}
+	key, checksum := getExtractJournalKey(file)
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	journal.Tars[key] = checksum
+	if err := journal.save(); err != nil {
+		tracelog.WarningLogger.Printf("Failed to record %s in the fetch journal: %v\n", key, err)
+	}
+}
+
+// save replaces the journal file, so the interrupted write leaves the previous version
+func (journal *ExtractJournal) save() error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return utility.ReplaceFile(journal.path, data)
+}
+
+// Remove deletes the journal once the extraction is complete
+func (journal *ExtractJournal) Remove() error {
+	if journal == nil {
+		return nil
+	}
+	err := os.Remove(journal.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// getExtractJournalKey returns the storage path of the tar and its checksum from the backup manifest
+func getExtractJournalKey(file ReaderMaker) (string, string) {
+	if storageReaderMaker, ok := file.(*StorageReaderMaker); ok {
+		return storageReaderMaker.Folder.GetPath() + storageReaderMaker.RelativePath, storageReaderMaker.ExpectedChecksum
+	}
+	return file.Path(), ""
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -144,6 +145,41 @@ func TestExtractAll_multipleConcurrentTars(t *testing.T) {
 	}
 }
 
+func TestExtractAllWithJournal_SkipsExtractedTars(t *testing.T) {
+	os.Setenv(internal.DownloadConcurrencySetting, "1")
+	defer os.Unsetenv(internal.DownloadConcurrencySetting)
+	dir, err := ioutil.TempDir("", "fetch_journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	journal, err := internal.OpenExtractJournal(dir, false)
+	assert.NoError(t, err)
+	assert.False(t, journal.IsResumed())
+	extractedTar, _ := makeTar("extracted")
+	extractedTar.Key = "part_1.tar"
+	buf := testtools.NewConcurrentConcatBufferTarInterpreter()
+	err = internal.ExtractAllWithJournal(buf, []internal.ReaderMaker{&extractedTar}, journal)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.Out, "extracted")
+
+	resumedJournal, err := internal.OpenExtractJournal(dir, true)
+	assert.NoError(t, err)
+	assert.True(t, resumedJournal.IsResumed())
+	extractedTar, _ = makeTar("extracted")
+	extractedTar.Key = "part_1.tar"
+	pendingTar, b := makeTar("pending")
+	pendingTar.Key = "part_2.tar"
+	buf = testtools.NewConcurrentConcatBufferTarInterpreter()
+	err = internal.ExtractAllWithJournal(buf, []internal.ReaderMaker{&extractedTar, &pendingTar}, resumedJournal)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"pending": b}, buf.Out)
+	assert.Equal(t, map[string]string{"part_1.tar": "", "part_2.tar": ""}, resumedJournal.Tars)
+
+	assert.NoError(t, resumedJournal.Remove())
+	_, err = os.Stat(filepath.Join(dir, internal.ExtractJournalFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
 func noPassphrase() (string, bool) {
 	return "", false
 }
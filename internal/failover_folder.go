@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+// FailoverFolder reads from the replicas in order if the primary storage fails the read or misses the object.
+// Everything is uploaded to and deleted from the primary storage only.
+type FailoverFolder struct {
+	storage.Folder
+	replicas []storage.Folder
+}
+
+func NewFailoverFolder(primary storage.Folder, replicas []storage.Folder) *FailoverFolder {
+	return &FailoverFolder{primary, replicas}
+}
+
+// GetFailoverStorageConfigs returns the config files of the replica storages, in the order they are read from
+func GetFailoverStorageConfigs() []string {
+	configs := make([]string, 0)
+	for _, config := range strings.Split(viper.GetString(FailoverStorageConfigsSetting), ",") {
+		if config = strings.TrimSpace(config); config != "" {
+			configs = append(configs, config)
+		}
+	}
+	return configs
+}
+
+func (folder *FailoverFolder) sources() []storage.Folder {
+	return append([]storage.Folder{folder.Folder}, folder.replicas...)
+}
+
+func (folder *FailoverFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	replicas := make([]storage.Folder, len(folder.replicas))
+	for i, replica := range folder.replicas {
+		replicas[i] = replica.GetSubFolder(subFolderRelativePath)
+	}
+	return &FailoverFolder{folder.Folder.GetSubFolder(subFolderRelativePath), replicas}
+}
+
+func (folder *FailoverFolder) RetryPolicy() (RetryPolicy, bool) {
+	return retryPolicyOf(folder.Folder)
+}
+
+func (folder *FailoverFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	var firstErr error
+	for _, source := range folder.sources() {
+		sourceObjects, sourceSubFolders, err := source.ListFolder()
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to list '%s': %v\n", source.GetPath(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, subFolder := range sourceSubFolders {
+			subFolders = append(subFolders, folder.GetSubFolder(strings.TrimPrefix(subFolder.GetPath(), source.GetPath())))
+		}
+		return sourceObjects, subFolders, nil
+	}
+	return nil, nil, firstErr
+}
+
+func (folder *FailoverFolder) Exists(objectRelativePath string) (bool, error) {
+	var failure error
+	for _, source := range folder.sources() {
+		exists, err := source.Exists(objectRelativePath)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to check existence of '%s': %v\n", source.GetPath()+objectRelativePath, err)
+			failure = err
+			continue
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	return false, failure
+}
+
+// ReadObject returns the object from the first storage which has it.
+// The object is reported missing only if every storage is known to miss it.
+func (folder *FailoverFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	var notFoundErr, failure error
+	for i, source := range folder.sources() {
+		reader, err := source.ReadObject(objectRelativePath)
+		if err == nil {
+			if i > 0 {
+				tracelog.WarningLogger.Printf("Reading '%s' from the failover storage\n", source.GetPath()+objectRelativePath)
+			}
+			return reader, nil
+		}
+		if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
+			if notFoundErr == nil {
+				notFoundErr = err
+			}
+			continue
+		}
+		tracelog.WarningLogger.Printf("Failed to read '%s': %v\n", source.GetPath()+objectRelativePath, err)
+		failure = err
+	}
+	if failure != nil {
+		return nil, failure
+	}
+	return nil, notFoundErr
+}
@@ -0,0 +1,73 @@
+package internal_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+// unavailableFolder fails every read
+type unavailableFolder struct {
+	storage.Folder
+}
+
+func (folder *unavailableFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return nil, errors.New("503 Service Unavailable")
+}
+
+func (folder *unavailableFolder) Exists(objectRelativePath string) (bool, error) {
+	return false, errors.New("503 Service Unavailable")
+}
+
+func TestFailoverFolder_ReadsMissingObjectFromReplica(t *testing.T) {
+	primary := testtools.MakeDefaultInMemoryStorageFolder()
+	replica := testtools.MakeDefaultInMemoryStorageFolder()
+	err := replica.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("wal"))
+	assert.NoError(t, err)
+	folder := internal.NewFailoverFolder(primary, []storage.Folder{replica}).GetSubFolder("wal_005/")
+
+	assertObjectContent(t, folder, "000000010000000000000001.lz4", "wal")
+	exists, err := folder.Exists("000000010000000000000001.lz4")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = folder.ReadObject("000000010000000000000002.lz4")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestFailoverFolder_ReadsFromReplicaIfPrimaryFails(t *testing.T) {
+	primary := &unavailableFolder{testtools.MakeDefaultInMemoryStorageFolder()}
+	replica := testtools.MakeDefaultInMemoryStorageFolder()
+	err := replica.PutObject("object", strings.NewReader("content"))
+	assert.NoError(t, err)
+	folder := internal.NewFailoverFolder(primary, []storage.Folder{replica})
+
+	assertObjectContent(t, folder, "object", "content")
+
+	// the missing object isn't reported missing, as the primary may have it
+	_, err = folder.ReadObject("missing")
+	assert.Error(t, err)
+	_, isNotFound := err.(storage.ObjectNotFoundError)
+	assert.False(t, isNotFound)
+	_, err = folder.Exists("missing")
+	assert.Error(t, err)
+}
+
+func TestFailoverFolder_UploadsToPrimary(t *testing.T) {
+	primary := testtools.MakeDefaultInMemoryStorageFolder()
+	replica := testtools.MakeDefaultInMemoryStorageFolder()
+	folder := internal.NewFailoverFolder(primary, []storage.Folder{replica})
+
+	err := folder.PutObject("object", strings.NewReader("content"))
+	assert.NoError(t, err)
+	assertObjectContent(t, primary, "object", "content")
+	exists, err := replica.Exists("object")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,273 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+type FanOutPolicy string
+
+const (
+	// FanOutPolicyAll fails the upload if any storage fails it
+	FanOutPolicyAll FanOutPolicy = "all"
+	// FanOutPolicyAtLeastOne fails the upload only if every storage fails it
+	FanOutPolicyAtLeastOne FanOutPolicy = "at_least_one"
+)
+
+type InvalidFanOutPolicyError struct {
+	error
+}
+
+func NewInvalidFanOutPolicyError(policy string) InvalidFanOutPolicyError {
+	return InvalidFanOutPolicyError{errors.Errorf("invalid %s setting '%s', expected '%s' or '%s'",
+		UploadFanOutPolicySetting, policy, FanOutPolicyAll, FanOutPolicyAtLeastOne)}
+}
+
+func (err InvalidFanOutPolicyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type FanOutUploadError struct {
+	error
+}
+
+func NewFanOutUploadError(operation, objectPath string, failures []string) FanOutUploadError {
+	return FanOutUploadError{errors.Errorf("failed to %s '%s' in the storages:\n%s",
+		operation, objectPath, strings.Join(failures, "\n"))}
+}
+
+func (err FanOutUploadError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetFanOutPolicy returns the policy of the uploads to all the storages configured, if it is set
+func GetFanOutPolicy() (policy FanOutPolicy, isSet bool, err error) {
+	policyStr := viper.GetString(UploadFanOutPolicySetting)
+	switch FanOutPolicy(policyStr) {
+	case "":
+		return "", false, nil
+	case FanOutPolicyAll, FanOutPolicyAtLeastOne:
+		return FanOutPolicy(policyStr), true, nil
+	default:
+		return "", false, NewInvalidFanOutPolicyError(policyStr)
+	}
+}
+
+// fanOutState is shared by the folder and its subfolders
+type fanOutState struct {
+	policy FanOutPolicy
+
+	mutex sync.Mutex
+	// failed storages get no more uploads, so they get no sentinel of the backup missing some objects
+	failed []bool
+}
+
+// FanOutFolder uploads every object to all the storages and deletes the objects from all of them.
+// Everything else is done with the first storage.
+type FanOutFolder struct {
+	storage.Folder
+	destinations []storage.Folder
+	state        *fanOutState
+}
+
+func NewFanOutFolder(destinations []storage.Folder, policy FanOutPolicy) *FanOutFolder {
+	return &FanOutFolder{
+		Folder:       destinations[0],
+		destinations: destinations,
+		state:        &fanOutState{policy: policy, failed: make([]bool, len(destinations))},
+	}
+}
+
+func (folder *FanOutFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	subFolders := make([]storage.Folder, len(folder.destinations))
+	for i, destination := range folder.destinations {
+		subFolders[i] = destination.GetSubFolder(subFolderRelativePath)
+	}
+	return &FanOutFolder{subFolders[0], subFolders, folder.state}
+}
+
+// RetryPolicy returns the retry policy of the storages: the streams can be retried only by uploading them to all of them again
+func (folder *FanOutFolder) RetryPolicy() (RetryPolicy, bool) {
+	return retryPolicyOf(folder.destinations[0])
+}
+
+func (folder *FanOutFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	objects, primarySubFolders, err := folder.Folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, subFolder := range primarySubFolders {
+		subFolderPath := strings.TrimPrefix(subFolder.GetPath(), folder.GetPath())
+		subFolders = append(subFolders, folder.GetSubFolder(subFolderPath))
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *FanOutFolder) activeDestinations() []int {
+	folder.state.mutex.Lock()
+	defer folder.state.mutex.Unlock()
+	active := make([]int, 0, len(folder.destinations))
+	for i, failed := range folder.state.failed {
+		if !failed {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+func (folder *FanOutFolder) PutObject(name string, content io.Reader) error {
+	destinations := folder.activeDestinations()
+	if len(destinations) == 0 {
+		return NewFanOutUploadError("upload", folder.GetPath()+name, []string{"every storage failed before"})
+	}
+
+	var errs []error
+	if seekable, ok := content.(seekableContent); ok {
+		errs = folder.putSeekable(destinations, name, seekable)
+	} else {
+		errs = folder.putStream(destinations, name, content)
+	}
+	return folder.checkErrors("upload", name, destinations, errs, true)
+}
+
+// putSeekable uploads the content to each storage with its own section reader, so the storages can seek it
+func (folder *FanOutFolder) putSeekable(destinations []int, name string, content seekableContent) []error {
+	start, err := content.Seek(0, io.SeekCurrent)
+	if err == nil {
+		var end int64
+		end, err = content.Seek(0, io.SeekEnd)
+		if err == nil {
+			return folder.forEachDestination(destinations, func(_ int, destination storage.Folder) error {
+				return destination.PutObject(name, io.NewSectionReader(content, start, end-start))
+			})
+		}
+	}
+	errs := make([]error, len(destinations))
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// putStream uploads the content to all the storages at once. The content is read once,
+// the storage which fails the upload stops receiving it, the others keep on.
+func (folder *FanOutFolder) putStream(destinations []int, name string, content io.Reader) []error {
+	pipeWriters := make([]*io.PipeWriter, len(destinations))
+	pipeReaders := make([]*io.PipeReader, len(destinations))
+	for i := range destinations {
+		pipeReaders[i], pipeWriters[i] = io.Pipe()
+	}
+	errsChannel := make(chan []error, 1)
+	go func() {
+		errsChannel <- folder.forEachDestination(destinations, func(i int, destination storage.Folder) error {
+			err := destination.PutObject(name, pipeReaders[i])
+			if err != nil {
+				_ = pipeReaders[i].CloseWithError(err)
+			} else {
+				_ = pipeReaders[i].Close()
+			}
+			return err
+		})
+	}()
+
+	writer := &fanOutWriter{writers: pipeWriters, failed: make([]bool, len(pipeWriters))}
+	_, copyErr := io.Copy(writer, content)
+	for _, pipeWriter := range pipeWriters {
+		_ = pipeWriter.CloseWithError(copyErr)
+	}
+	errs := <-errsChannel
+	if copyErr != nil {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = copyErr
+			}
+		}
+	}
+	return errs
+}
+
+// forEachDestination runs the action for the storages concurrently, i is the index in the destinations slice
+func (folder *FanOutFolder) forEachDestination(destinations []int,
+	action func(i int, destination storage.Folder) error) []error {
+	errs := make([]error, len(destinations))
+	var wg sync.WaitGroup
+	for i, destination := range destinations {
+		wg.Add(1)
+		go func(i int, destination storage.Folder) {
+			defer wg.Done()
+			errs[i] = action(i, destination)
+		}(i, folder.destinations[destination])
+	}
+	wg.Wait()
+	return errs
+}
+
+// checkErrors applies the policy to the errors of the storages
+func (folder *FanOutFolder) checkErrors(operation, name string,
+	destinations []int, errs []error, excludeFailed bool) error {
+	failures := make([]string, 0)
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		destination := folder.destinations[destinations[i]]
+		failures = append(failures, fmt.Sprintf("%s: %v", destination.GetPath(), err))
+		if folder.state.policy == FanOutPolicyAtLeastOne && excludeFailed {
+			tracelog.WarningLogger.Printf("Failed to %s '%s' in storage %s, it gets no more uploads: %v\n",
+				operation, name, destination.GetPath(), err)
+			folder.state.mutex.Lock()
+			folder.state.failed[destinations[i]] = true
+			folder.state.mutex.Unlock()
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	if folder.state.policy == FanOutPolicyAtLeastOne && len(failures) < len(errs) {
+		return nil
+	}
+	return NewFanOutUploadError(operation, folder.GetPath()+name, failures)
+}
+
+func (folder *FanOutFolder) DeleteObjects(objectRelativePaths []string) error {
+	destinations := make([]int, len(folder.destinations))
+	for i := range destinations {
+		destinations[i] = i
+	}
+	errs := folder.forEachDestination(destinations, func(_ int, destination storage.Folder) error {
+		return destination.DeleteObjects(objectRelativePaths)
+	})
+	return folder.checkErrors("delete objects from", "", destinations, errs, false)
+}
+
+// fanOutWriter writes to all the writers, dropping the failed ones. It fails only if every writer fails.
+type fanOutWriter struct {
+	writers []*io.PipeWriter
+	failed  []bool
+}
+
+func (writer *fanOutWriter) Write(p []byte) (int, error) {
+	var lastErr error
+	for i, pipeWriter := range writer.writers {
+		if writer.failed[i] {
+			continue
+		}
+		if _, err := pipeWriter.Write(p); err != nil {
+			writer.failed[i] = true
+			lastErr = err
+		}
+	}
+	for _, failed := range writer.failed {
+		if !failed {
+			return len(p), nil
+		}
+	}
+	return 0, lastErr
+}
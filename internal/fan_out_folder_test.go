@@ -0,0 +1,89 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+// failingFolder fails every upload after reading a part of the content
+type failingFolder struct {
+	storage.Folder
+	calls int
+}
+
+func (folder *failingFolder) PutObject(name string, content io.Reader) error {
+	folder.calls++
+	_, _ = content.Read(make([]byte, 4))
+	return errors.New("503 Service Unavailable")
+}
+
+func assertObjectContent(t *testing.T, folder storage.Folder, objectPath, expected string) {
+	reader, err := folder.ReadObject(objectPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
+
+func TestFanOutFolder_UploadsToAllStorages(t *testing.T) {
+	first := testtools.MakeDefaultInMemoryStorageFolder()
+	second := testtools.MakeDefaultInMemoryStorageFolder()
+	folder := internal.NewFanOutFolder([]storage.Folder{first, second}, internal.FanOutPolicyAll)
+
+	streamContent := strings.Repeat("stream content ", 10000)
+	err := folder.GetSubFolder("base_1/").PutObject("stream", io.MultiReader(strings.NewReader(streamContent)))
+	assert.NoError(t, err)
+	err = folder.PutObject("seekable", bytes.NewReader([]byte("seekable content")))
+	assert.NoError(t, err)
+
+	for _, destination := range []storage.Folder{first, second} {
+		assertObjectContent(t, destination, "base_1/stream", streamContent)
+		assertObjectContent(t, destination, "seekable", "seekable content")
+	}
+
+	err = folder.DeleteObjects([]string{"seekable"})
+	assert.NoError(t, err)
+	for _, destination := range []storage.Folder{first, second} {
+		exists, err := destination.Exists("seekable")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	}
+}
+
+func TestFanOutFolder_AllPolicyFailsOnAnyFailure(t *testing.T) {
+	first := testtools.MakeDefaultInMemoryStorageFolder()
+	second := &failingFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder()}
+	folder := internal.NewFanOutFolder([]storage.Folder{first, second}, internal.FanOutPolicyAll)
+
+	err := folder.PutObject("stream", io.MultiReader(strings.NewReader("stream content")))
+	assert.IsType(t, internal.FanOutUploadError{}, err)
+	err = folder.PutObject("seekable", bytes.NewReader([]byte("seekable content")))
+	assert.IsType(t, internal.FanOutUploadError{}, err)
+}
+
+func TestFanOutFolder_AtLeastOnePolicyExcludesFailedStorage(t *testing.T) {
+	first := &failingFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder()}
+	second := testtools.MakeDefaultInMemoryStorageFolder()
+	folder := internal.NewFanOutFolder([]storage.Folder{first, second}, internal.FanOutPolicyAtLeastOne)
+
+	err := folder.PutObject("base_1/part_1", io.MultiReader(strings.NewReader("part")))
+	assert.NoError(t, err)
+	assertObjectContent(t, second, "base_1/part_1", "part")
+
+	// the failed storage gets no sentinel of the backup
+	err = folder.PutObject("base_1_backup_stop_sentinel.json", bytes.NewReader([]byte("{}")))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.calls)
+	assertObjectContent(t, second, "base_1_backup_stop_sentinel.json", "{}")
+}
@@ -0,0 +1,250 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+// partialReadMaxBytes limits the number of bytes returned by the partial read before it fails
+const partialReadMaxBytes = 1 << 20
+
+type FaultInjectedError struct {
+	error
+}
+
+func NewFaultInjectedError(operation, objectPath string) FaultInjectedError {
+	return FaultInjectedError{errors.Errorf("injected fault: %s '%s' failed", operation, objectPath)}
+}
+
+func (err FaultInjectedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// FaultInjectionConfig describes the faults injected into the storage operations.
+// It is meant for resilience testing only.
+type FaultInjectionConfig struct {
+	// Latency is added to every storage operation
+	Latency time.Duration
+	// ErrorRate is the probability of the storage operation to fail
+	ErrorRate float64
+	// PartialReadRate is the probability of the object read to fail with io.ErrUnexpectedEOF in the middle
+	PartialReadRate float64
+	// ListingLag hides the objects uploaded by this process from the listing for the time given
+	ListingLag time.Duration
+	// Seed of the random faults, the faults are reproducible if set
+	Seed int64
+}
+
+func (config FaultInjectionConfig) IsEnabled() bool {
+	return config.Latency > 0 || config.ErrorRate > 0 || config.PartialReadRate > 0 || config.ListingLag > 0
+}
+
+// GetFaultInjectionConfig reads the fault injection settings, no faults are injected if none is set
+func GetFaultInjectionConfig() (FaultInjectionConfig, error) {
+	config := FaultInjectionConfig{Seed: time.Now().UnixNano()}
+	var err error
+	if _, ok := GetSetting(FaultLatencySetting); ok {
+		if config.Latency, err = GetDurationSetting(FaultLatencySetting); err != nil {
+			return FaultInjectionConfig{}, err
+		}
+	}
+	if _, ok := GetSetting(FaultListingLagSetting); ok {
+		if config.ListingLag, err = GetDurationSetting(FaultListingLagSetting); err != nil {
+			return FaultInjectionConfig{}, err
+		}
+	}
+	if config.ErrorRate, err = getFaultRateSetting(FaultErrorRateSetting); err != nil {
+		return FaultInjectionConfig{}, err
+	}
+	if config.PartialReadRate, err = getFaultRateSetting(FaultPartialReadRateSetting); err != nil {
+		return FaultInjectionConfig{}, err
+	}
+	if seedStr, ok := GetSetting(FaultSeedSetting); ok {
+		config.Seed, err = strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			return FaultInjectionConfig{},
+				fmt.Errorf("integer expected for %s setting but given '%s': %w", FaultSeedSetting, seedStr, err)
+		}
+	}
+	return config, nil
+}
+
+func getFaultRateSetting(setting string) (float64, error) {
+	rateStr, ok := GetSetting(setting)
+	if !ok {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("number from 0 to 1 expected for %s setting but given '%s'", setting, rateStr)
+	}
+	return rate, nil
+}
+
+// faultInjector is shared by the folder and its subfolders
+type faultInjector struct {
+	config FaultInjectionConfig
+
+	mutex    sync.Mutex
+	random   *rand.Rand
+	putTimes map[string]time.Time
+}
+
+// FaultInjectingFolder injects latency, errors, partial reads and listing lag into the storage operations
+type FaultInjectingFolder struct {
+	storage.Folder
+	injector *faultInjector
+}
+
+func NewFaultInjectingFolder(folder storage.Folder, config FaultInjectionConfig) *FaultInjectingFolder {
+	return &FaultInjectingFolder{
+		Folder: folder,
+		injector: &faultInjector{
+			config:   config,
+			random:   rand.New(rand.NewSource(config.Seed)),
+			putTimes: make(map[string]time.Time),
+		},
+	}
+}
+
+func (injector *faultInjector) happens(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	return injector.random.Float64() < rate
+}
+
+func (injector *faultInjector) randomInt63n(n int64) int64 {
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	return injector.random.Int63n(n)
+}
+
+// inject delays the operation and fails it at random
+func (injector *faultInjector) inject(operation, objectPath string) error {
+	time.Sleep(injector.config.Latency)
+	if injector.happens(injector.config.ErrorRate) {
+		err := NewFaultInjectedError(operation, objectPath)
+		tracelog.WarningLogger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (injector *faultInjector) markPut(objectPath string) {
+	if injector.config.ListingLag <= 0 {
+		return
+	}
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	injector.putTimes[objectPath] = time.Now()
+}
+
+func (injector *faultInjector) isListed(objectPath string) bool {
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	putTime, ok := injector.putTimes[objectPath]
+	if !ok {
+		return true
+	}
+	if time.Since(putTime) < injector.config.ListingLag {
+		return false
+	}
+	delete(injector.putTimes, objectPath)
+	return true
+}
+
+func (folder *FaultInjectingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return &FaultInjectingFolder{folder.Folder.GetSubFolder(subFolderRelativePath), folder.injector}
+}
+
+func (folder *FaultInjectingFolder) RetryPolicy() (RetryPolicy, bool) {
+	return retryPolicyOf(folder.Folder)
+}
+
+func (folder *FaultInjectingFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	if err = folder.injector.inject("list", folder.GetPath()); err != nil {
+		return nil, nil, err
+	}
+	allObjects, allSubFolders, err := folder.Folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, object := range allObjects {
+		if folder.injector.isListed(folder.GetPath() + object.GetName()) {
+			objects = append(objects, object)
+		}
+	}
+	for _, subFolder := range allSubFolders {
+		subFolders = append(subFolders, &FaultInjectingFolder{subFolder, folder.injector})
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *FaultInjectingFolder) Exists(objectRelativePath string) (bool, error) {
+	if err := folder.injector.inject("check existence of", folder.GetPath()+objectRelativePath); err != nil {
+		return false, err
+	}
+	return folder.Folder.Exists(objectRelativePath)
+}
+
+func (folder *FaultInjectingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	objectPath := folder.GetPath() + objectRelativePath
+	if err := folder.injector.inject("read", objectPath); err != nil {
+		return nil, err
+	}
+	reader, err := folder.Folder.ReadObject(objectRelativePath)
+	if err != nil || !folder.injector.happens(folder.injector.config.PartialReadRate) {
+		return reader, err
+	}
+	limit := folder.injector.randomInt63n(partialReadMaxBytes)
+	tracelog.WarningLogger.Printf("Injected fault: read of '%s' will fail after %d bytes\n", objectPath, limit)
+	return &partialReader{ReadCloser: reader, left: limit}, nil
+}
+
+func (folder *FaultInjectingFolder) PutObject(name string, content io.Reader) error {
+	objectPath := folder.GetPath() + name
+	if err := folder.injector.inject("put", objectPath); err != nil {
+		return err
+	}
+	err := folder.Folder.PutObject(name, content)
+	if err == nil {
+		folder.injector.markPut(objectPath)
+	}
+	return err
+}
+
+func (folder *FaultInjectingFolder) DeleteObjects(objectRelativePaths []string) error {
+	if err := folder.injector.inject("delete from", folder.GetPath()); err != nil {
+		return err
+	}
+	return folder.Folder.DeleteObjects(objectRelativePaths)
+}
+
+// partialReader fails with io.ErrUnexpectedEOF once the limit is read, unless the object ends before
+type partialReader struct {
+	io.ReadCloser
+	left int64
+}
+
+func (reader *partialReader) Read(p []byte) (int, error) {
+	if reader.left <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > reader.left {
+		p = p[:reader.left]
+	}
+	n, err := reader.ReadCloser.Read(p)
+	reader.left -= int64(n)
+	return n, err
+}
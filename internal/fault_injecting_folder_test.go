@@ -0,0 +1,60 @@
+package internal_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestFaultInjectingFolder_Errors(t *testing.T) {
+	folder := internal.NewFaultInjectingFolder(testtools.MakeDefaultInMemoryStorageFolder(),
+		internal.FaultInjectionConfig{ErrorRate: 1})
+
+	err := folder.PutObject("object", strings.NewReader("content"))
+	assert.IsType(t, internal.FaultInjectedError{}, err)
+	_, err = folder.ReadObject("object")
+	assert.IsType(t, internal.FaultInjectedError{}, err)
+	_, _, err = folder.GetSubFolder("subfolder/").ListFolder()
+	assert.IsType(t, internal.FaultInjectedError{}, err)
+	err = folder.DeleteObjects([]string{"object"})
+	assert.IsType(t, internal.FaultInjectedError{}, err)
+}
+
+func TestFaultInjectingFolder_PartialReads(t *testing.T) {
+	memoryFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	content := bytes.Repeat([]byte{1}, 2<<20)
+	err := memoryFolder.PutObject("object", bytes.NewReader(content))
+	assert.NoError(t, err)
+	folder := internal.NewFaultInjectingFolder(memoryFolder, internal.FaultInjectionConfig{PartialReadRate: 1})
+
+	reader, err := folder.ReadObject("object")
+	assert.NoError(t, err)
+	readContent, err := ioutil.ReadAll(reader)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Less(t, len(readContent), len(content))
+}
+
+func TestFaultInjectingFolder_ListingLag(t *testing.T) {
+	memoryFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	err := memoryFolder.PutObject("subfolder/old", strings.NewReader("old"))
+	assert.NoError(t, err)
+	folder := internal.NewFaultInjectingFolder(memoryFolder, internal.FaultInjectionConfig{ListingLag: time.Hour})
+
+	err = folder.PutObject("subfolder/new", strings.NewReader("new"))
+	assert.NoError(t, err)
+
+	objects, _, err := folder.GetSubFolder("subfolder/").ListFolder()
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "old", objects[0].GetName())
+	exists, err := folder.GetSubFolder("subfolder/").Exists("new")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
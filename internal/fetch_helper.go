@@ -14,6 +14,7 @@ import (
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -56,6 +57,10 @@ func TryDownloadFile(folder storage.Folder, path string) (walFileReader io.ReadC
 	walFileReader, err = folder.ReadObject(path)
 	if err == nil {
 		exists = true
+		walFileReader = ioextensions.ReadCascadeCloser{
+			Reader: metrics.NewCountingReader(walFileReader, metrics.DownloadedBytes),
+			Closer: walFileReader,
+		}
 		return
 	}
 	if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
@@ -159,35 +164,77 @@ func putCachedDecompressorInFirstPlace(decompressors []compression.Decompressor)
 
 // TODO : unit tests
 func DownloadAndDecompressStorageFile(folder storage.Folder, fileName string) (io.ReadCloser, error) {
+	return DownloadAndDecompressStorageFileWithChecksum(folder, fileName, "")
+}
+
+// DownloadAndDecompressStorageFileWithChecksum downloads the file and fails the reading
+// if the SHA-256 of the stored object differs from the expected checksum. Empty checksum disables the check.
+func DownloadAndDecompressStorageFileWithChecksum(folder storage.Folder,
+	fileName, expectedChecksum string) (io.ReadCloser, error) {
 	for _, decompressor := range putCachedDecompressorInFirstPlace(compression.Decompressors) {
-		archiveReader, exists, err := TryDownloadFile(folder, fileName+"."+decompressor.FileExtension())
+		reader, exists, err := tryDownloadAndDecompress(folder, fileName+"."+decompressor.FileExtension(),
+			decompressor, expectedChecksum)
 		if err != nil {
 			return nil, err
 		}
-		if !exists {
-			continue
+		if exists {
+			_ = SetLastDecompressor(decompressor)
+			return reader, nil
 		}
-		_ = SetLastDecompressor(decompressor)
-		reader, writer := io.Pipe()
-		go func() {
-			err = DecompressDecryptBytes(&EmptyWriteIgnorer{writer}, archiveReader, decompressor)
-			_ = writer.CloseWithError(err)
-		}()
-		return reader, nil
 	}
 	return nil, newArchiveNonExistenceError(fileName)
 }
 
+// DownloadAndDecompressStorageObject downloads the object with the known name, e.g. taken from an index,
+// the decompressor is chosen by the object extension
+func DownloadAndDecompressStorageObject(folder storage.Folder, objectName string) (io.ReadCloser, error) {
+	decompressor := compression.FindDecompressor(utility.GetFileExtension(objectName))
+	if decompressor == nil {
+		return nil, fmt.Errorf("decompressor for the object '%s' was not found", objectName)
+	}
+	reader, exists, err := tryDownloadAndDecompress(folder, objectName, decompressor, "")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, newArchiveNonExistenceError(utility.TrimFileExtension(objectName))
+	}
+	return reader, nil
+}
+
+func tryDownloadAndDecompress(folder storage.Folder, objectName string,
+	decompressor compression.Decompressor, expectedChecksum string) (io.ReadCloser, bool, error) {
+	archiveReader, exists, err := TryDownloadFile(folder, objectName)
+	if err != nil || !exists {
+		return nil, exists, err
+	}
+	archiveReader = NewChecksumVerifyingReader(archiveReader, objectName, expectedChecksum)
+	reader, writer := io.Pipe()
+	go func() {
+		err := DecompressDecryptBytes(&EmptyWriteIgnorer{writer}, archiveReader, decompressor)
+		if err == nil {
+			err = VerifyChecksum(archiveReader)
+		}
+		_ = writer.CloseWithError(err)
+	}()
+	return reader, true, nil
+}
+
 // TODO : unit tests
 // DownloadFileTo downloads a file and writes it to local file
 func DownloadFileTo(folder storage.Folder, fileName string, dstPath string) error {
+	return DownloadFileToWithChecksum(folder, fileName, dstPath, "")
+}
+
+// DownloadFileToWithChecksum downloads a file, verifies it against the expected checksum and writes it to local file
+func DownloadFileToWithChecksum(folder storage.Folder, fileName, dstPath, expectedChecksum string) error {
 	// Create file as soon as possible. It may be important due to race condition in wal-prefetch for PG.
 	file, err := os.OpenFile(dstPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
 	if err != nil {
 		return err
 	}
 
-	reader, err := DownloadAndDecompressStorageFile(folder, fileName)
+	reader, err := DownloadAndDecompressStorageFileWithChecksum(folder, fileName, expectedChecksum)
 	if err != nil {
 		// We could not start upload - remove the file totally.
 		_ = os.Remove(dstPath)
@@ -0,0 +1,410 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/webserver"
+)
+
+const (
+	DefaultMetricsPattern = "/metrics"
+
+	namespace = "walg"
+
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultDurationBuckets are upper bounds (in seconds) used for the duration histograms.
+var DefaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600, 4 * 3600}
+
+var (
+	UploadedBytes = NewCounter("uploaded_bytes_total",
+		"Compressed and encrypted bytes written to the storage.")
+	UploadedRawBytes = NewCounter("uploaded_raw_bytes_total",
+		"Bytes read from the source before compression and encryption.")
+	DownloadedBytes = NewCounter("downloaded_bytes_total",
+		"Bytes read from the storage.")
+	FailedUploads = NewCounter("failed_uploads_total",
+		"Storage uploads which finished with an error.")
+	Retries = NewCounter("retries_total",
+		"Storage operations which were retried after a failure.")
+	WalPushDuration = NewHistogram("wal_push_duration_seconds",
+		"Time spent uploading a single WAL segment.", DefaultDurationBuckets)
+	BackupPushDuration = NewHistogram("backup_push_duration_seconds",
+		"Time spent pushing a backup.", DefaultDurationBuckets)
+
+	UploadedWalSegments = NewCounter("uploaded_wal_segments_total",
+		"WAL segments uploaded by wal-push, including the ones uploaded in background.")
+	WalPushSegment = NewInfo("wal_push_segment_info",
+		"WAL segment passed to wal-push.", "segment")
+	CompressionRatio = NewGauge("compression_ratio",
+		"Ratio of raw bytes to uploaded bytes.")
+	RunSuccess = NewGauge("run_success",
+		"1 if the command finished successfully, 0 otherwise.")
+	RunDuration = NewGauge("run_duration_seconds",
+		"Time passed since the command start.")
+	RunTimestamp = NewGauge("run_timestamp_seconds",
+		"Unix time when the run summary was written.")
+)
+
+var runStartTime = time.Now()
+
+// Metric is a single metric which can be rendered in Prometheus text exposition format.
+type Metric interface {
+	Name() string
+	write(w io.Writer, labels string) error
+}
+
+// Registry holds registered metrics and the labels attached to all of them.
+// It is thread-safe.
+type Registry struct {
+	sync.Mutex
+	metrics     []Metric
+	constLabels map[string]string
+}
+
+// DefaultRegistry contains all WAL-G metrics.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry builds empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{constLabels: make(map[string]string)}
+}
+
+// Register adds metric to the registry.
+func (r *Registry) Register(metric Metric) {
+	r.Lock()
+	defer r.Unlock()
+	r.metrics = append(r.metrics, metric)
+}
+
+// SetConstLabels sets labels which are attached to every metric of the registry.
+func (r *Registry) SetConstLabels(labels map[string]string) {
+	r.Lock()
+	defer r.Unlock()
+	r.constLabels = make(map[string]string, len(labels))
+	for k, v := range labels {
+		r.constLabels[k] = v
+	}
+}
+
+// WriteText writes all registered metrics in Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.Lock()
+	metrics := append(make([]Metric, 0, len(r.metrics)), r.metrics...)
+	labels := formatLabels(r.constLabels)
+	r.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name() < metrics[j].Name()
+	})
+	for _, metric := range metrics {
+		if err := metric.write(w, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements metrics http-handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	if err := r.WriteText(w); err != nil {
+		http.Error(w, fmt.Sprintf("failed to write metrics: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// SetConstLabels sets labels of the DefaultRegistry, see Registry.SetConstLabels
+func SetConstLabels(labels map[string]string) {
+	DefaultRegistry.SetConstLabels(labels)
+}
+
+// EnableMetricsEndpoint exposes DefaultRegistry metrics http endpoint.
+func EnableMetricsEndpoint(ws webserver.WebServer) {
+	ws.HandleFunc(DefaultMetricsPattern, DefaultRegistry.ServeHTTP)
+}
+
+// Counter is a monotonically increasing metric.
+type Counter struct {
+	name  string
+	help  string
+	value int64
+}
+
+// NewCounter builds Counter and registers it in the DefaultRegistry.
+func NewCounter(name, help string) *Counter {
+	return DefaultRegistry.NewCounter(name, help)
+}
+
+// NewCounter builds Counter and registers it in the registry.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	counter := &Counter{name: namespace + "_" + name, help: help}
+	r.Register(counter)
+	return counter
+}
+
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by delta.
+func (c *Counter) Add(delta int64) {
+	atomic.AddInt64(&c.value, delta)
+}
+
+// Get returns current value of the counter.
+func (c *Counter) Get() int64 {
+	return atomic.LoadInt64(&c.value)
+}
+
+func (c *Counter) write(w io.Writer, labels string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s%s %d\n",
+		c.name, c.help, c.name, c.name, wrapLabels(labels), c.Get())
+	return err
+}
+
+// Gauge is a metric which value can go up and down.
+type Gauge struct {
+	name  string
+	help  string
+	value uint64
+}
+
+// NewGauge builds Gauge and registers it in the DefaultRegistry.
+func NewGauge(name, help string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help)
+}
+
+// NewGauge builds Gauge and registers it in the registry.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	gauge := &Gauge{name: namespace + "_" + name, help: help}
+	r.Register(gauge)
+	return gauge
+}
+
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+// Set sets the gauge to value.
+func (g *Gauge) Set(value float64) {
+	atomic.StoreUint64(&g.value, math.Float64bits(value))
+}
+
+// Get returns current value of the gauge.
+func (g *Gauge) Get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.value))
+}
+
+func (g *Gauge) write(w io.Writer, labels string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s%s %s\n",
+		g.name, g.help, g.name, g.name, wrapLabels(labels), formatFloat(g.Get()))
+	return err
+}
+
+// Info exposes a string value as a label of a constant gauge.
+// Nothing is written until the value is set.
+type Info struct {
+	sync.Mutex
+	name  string
+	help  string
+	label string
+	value *string
+}
+
+// NewInfo builds Info and registers it in the DefaultRegistry.
+func NewInfo(name, help, label string) *Info {
+	return DefaultRegistry.NewInfo(name, help, label)
+}
+
+// NewInfo builds Info and registers it in the registry.
+func (r *Registry) NewInfo(name, help, label string) *Info {
+	info := &Info{name: namespace + "_" + name, help: help, label: label}
+	r.Register(info)
+	return info
+}
+
+func (i *Info) Name() string {
+	return i.name
+}
+
+// Set sets the label value.
+func (i *Info) Set(value string) {
+	i.Lock()
+	defer i.Unlock()
+	i.value = &value
+}
+
+func (i *Info) write(w io.Writer, labels string) error {
+	i.Lock()
+	defer i.Unlock()
+	if i.value == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s%s 1\n",
+		i.name, i.help, i.name, i.name, wrapLabels(labels, i.label, *i.value))
+	return err
+}
+
+// Histogram counts observed values in configurable buckets.
+type Histogram struct {
+	sync.Mutex
+	name    string
+	help    string
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram builds Histogram and registers it in the DefaultRegistry.
+// Buckets are upper bounds of the observed values and should be sorted.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets)
+}
+
+// NewHistogram builds Histogram and registers it in the registry.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	histogram := &Histogram{
+		name:    namespace + "_" + name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+	r.Register(histogram)
+	return histogram
+}
+
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// Observe adds a single value to the histogram.
+func (h *Histogram) Observe(value float64) {
+	h.Lock()
+	defer h.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// ObserveDuration adds the time passed since start to the histogram.
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Count returns the number of observed values.
+func (h *Histogram) Count() uint64 {
+	h.Lock()
+	defer h.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of observed values.
+func (h *Histogram) Sum() float64 {
+	h.Lock()
+	defer h.Unlock()
+	return h.sum
+}
+
+func (h *Histogram) write(w io.Writer, labels string) error {
+	h.Lock()
+	defer h.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, bound := range h.buckets {
+		fmt.Fprintf(&sb, "%s_bucket%s %d\n", h.name, wrapLabels(labels, "le", formatFloat(bound)), h.counts[i])
+	}
+	fmt.Fprintf(&sb, "%s_bucket%s %d\n", h.name, wrapLabels(labels, "le", "+Inf"), h.count)
+	fmt.Fprintf(&sb, "%s_sum%s %s\n", h.name, wrapLabels(labels), formatFloat(h.sum))
+	fmt.Fprintf(&sb, "%s_count%s %d\n", h.name, wrapLabels(labels), h.count)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FinishRun fills the run summary metrics.
+func FinishRun(success bool) {
+	if success {
+		RunSuccess.Set(1)
+	} else {
+		RunSuccess.Set(0)
+	}
+	RunDuration.Set(time.Since(runStartTime).Seconds())
+	RunTimestamp.Set(float64(time.Now().Unix()))
+}
+
+// CountingReader adds the number of read bytes to the counter.
+type CountingReader struct {
+	io.Reader
+	counter *Counter
+}
+
+// NewCountingReader builds CountingReader.
+func NewCountingReader(reader io.Reader, counter *Counter) *CountingReader {
+	return &CountingReader{reader, counter}
+}
+
+func (r *CountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.counter.Add(int64(n))
+	return
+}
+
+// formatLabels renders labels in a stable order without surrounding braces.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, formatLabel(k, labels[k]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func formatLabel(key, value string) string {
+	return key + "=" + strconv.Quote(value)
+}
+
+// wrapLabels joins labels with an optional extra key-value pair and wraps them in braces.
+func wrapLabels(labels string, extra ...string) string {
+	if len(extra) == 2 {
+		if labels != "" {
+			labels += ","
+		}
+		labels += formatLabel(extra[0], extra[1])
+	}
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
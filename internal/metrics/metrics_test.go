@@ -0,0 +1,101 @@
+package metrics_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/metrics"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := registry.NewCounter("test_total", "Test counter.")
+	histogram := registry.NewHistogram("test_seconds", "Test histogram.", []float64{1, 10})
+	registry.SetConstLabels(map[string]string{"db": "pg", "command": "wal-push"})
+
+	counter.Add(41)
+	counter.Inc()
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	histogram.Observe(50)
+
+	var buf bytes.Buffer
+	assert.NoError(t, registry.WriteText(&buf))
+	assert.Equal(t, `# HELP walg_test_seconds Test histogram.
+# TYPE walg_test_seconds histogram
+walg_test_seconds_bucket{command="wal-push",db="pg",le="1"} 1
+walg_test_seconds_bucket{command="wal-push",db="pg",le="10"} 2
+walg_test_seconds_bucket{command="wal-push",db="pg",le="+Inf"} 3
+walg_test_seconds_sum{command="wal-push",db="pg"} 55.5
+walg_test_seconds_count{command="wal-push",db="pg"} 3
+# HELP walg_test_total Test counter.
+# TYPE walg_test_total counter
+walg_test_total{command="wal-push",db="pg"} 42
+`, buf.String())
+}
+
+func TestRegistry_WriteTextWithoutLabels(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewCounter("unlabeled_total", "Unlabeled counter.")
+
+	var buf bytes.Buffer
+	assert.NoError(t, registry.WriteText(&buf))
+	assert.Contains(t, buf.String(), "\nwalg_unlabeled_total 0\n")
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewCounter("served_total", "Served counter.")
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", metrics.DefaultMetricsPattern, nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, recorder.Body.String(), "walg_served_total 0")
+}
+
+func TestCountingReader(t *testing.T) {
+	counter := metrics.NewRegistry().NewCounter("read_bytes_total", "Read bytes.")
+	reader := metrics.NewCountingReader(strings.NewReader("0123456789"), counter)
+
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+	assert.Equal(t, int64(10), counter.Get())
+}
+
+func TestRegistry_WriteTextFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	registry := metrics.NewRegistry()
+	gauge := registry.NewGauge("test_ratio", "Test gauge.")
+	info := registry.NewInfo("test_info", "Test info.", "segment")
+	gauge.Set(2.5)
+	info.Set("000000010000000000000001")
+
+	path := filepath.Join(dir, "walg.prom")
+	assert.NoError(t, registry.WriteTextFile(path))
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP walg_test_info Test info.
+# TYPE walg_test_info gauge
+walg_test_info{segment="000000010000000000000001"} 1
+# HELP walg_test_ratio Test gauge.
+# TYPE walg_test_ratio gauge
+walg_test_ratio 2.5
+`, string(data))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteTextFile atomically replaces the file at path with the registry metrics.
+// The output is suitable for the node_exporter textfile collector,
+// which expects files with the '.prom' extension.
+func (r *Registry) WriteTextFile(path string) error {
+	// the temporary file is created next to the target to make the rename atomic
+	// and is hidden from the collector by the leading dot and the missing extension
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	err = r.WriteText(tmpFile)
+	if err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Chmod(0644)
+	if err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/metrics"
+)
+
+// RetryPolicy describes how the failed storage operations are retried
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of the operation, including the first one
+	Attempts int
+	// MinBackoff is the wait before the first retry, it doubles with every retry up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the wait cut off at random
+	Jitter float64
+	// IsRetryable classifies the errors of the operations
+	IsRetryable func(err error) bool
+}
+
+// GetRetryPolicy reads the retry policy of the storage operations from the settings
+func GetRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:    viper.GetInt(StorageRetryAttemptsSetting),
+		MinBackoff:  viper.GetDuration(StorageRetryMinBackoffSetting),
+		MaxBackoff:  viper.GetDuration(StorageRetryMaxBackoffSetting),
+		Jitter:      viper.GetFloat64(StorageRetryJitterSetting),
+		IsRetryable: IsRetryableStorageError,
+	}
+}
+
+func (policy RetryPolicy) IsEnabled() bool {
+	return policy.Attempts > 1
+}
+
+// Do runs the operation until it succeeds, fails with the non-retryable error or runs out of attempts
+func (policy RetryPolicy) Do(operation, objectPath string, action func() error) error {
+	sleeper := NewJitteredExponentialSleeper(policy.MinBackoff, policy.MaxBackoff, policy.Jitter)
+	for attempt := 1; ; attempt++ {
+		err := action()
+		if err == nil || attempt >= policy.Attempts || !policy.IsRetryable(err) {
+			return err
+		}
+		tracelog.WarningLogger.Printf("Failed to %s '%s', attempt %d of %d: %v\n",
+			operation, objectPath, attempt, policy.Attempts, err)
+		metrics.Retries.Inc()
+		sleeper.Sleep()
+	}
+}
+
+// IsRetryableStorageError tells the transient storage errors from the ones which won't go away on retry:
+// missing objects, cancelled operations and the client errors (4xx) of the HTTP-based storages
+func IsRetryableStorageError(err error) bool {
+	cause := errors.Cause(err)
+	if _, ok := cause.(storage.ObjectNotFoundError); ok {
+		return false
+	}
+	if cause == context.Canceled {
+		return false
+	}
+	if statusError, ok := cause.(interface{ StatusCode() int }); ok {
+		statusCode := statusError.StatusCode()
+		return statusCode >= http.StatusInternalServerError ||
+			statusCode == http.StatusTooManyRequests ||
+			statusCode == http.StatusRequestTimeout
+	}
+	return true
+}
+
+// RetryingFolder retries the failed operations of the folder according to the retry policy.
+// Only the opening of the object is retried by ReadObject, the interrupted reads are retried by the callers.
+// PutObject retries the seekable content only, the streams are retried by the Uploader, which can open them again.
+type RetryingFolder struct {
+	storage.Folder
+	policy RetryPolicy
+}
+
+func NewRetryingFolder(folder storage.Folder, policy RetryPolicy) *RetryingFolder {
+	return &RetryingFolder{folder, policy}
+}
+
+func (folder *RetryingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewRetryingFolder(folder.Folder.GetSubFolder(subFolderRelativePath), folder.policy)
+}
+
+func (folder *RetryingFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	err = folder.policy.Do("list", folder.GetPath(), func() error {
+		objects, subFolders, err = folder.Folder.ListFolder()
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range subFolders {
+		subFolders[i] = NewRetryingFolder(subFolders[i], folder.policy)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *RetryingFolder) Exists(objectRelativePath string) (exists bool, err error) {
+	err = folder.policy.Do("check existence of", folder.GetPath()+objectRelativePath, func() error {
+		exists, err = folder.Folder.Exists(objectRelativePath)
+		return err
+	})
+	return exists, err
+}
+
+func (folder *RetryingFolder) ReadObject(objectRelativePath string) (reader io.ReadCloser, err error) {
+	err = folder.policy.Do("read", folder.GetPath()+objectRelativePath, func() error {
+		reader, err = folder.Folder.ReadObject(objectRelativePath)
+		return err
+	})
+	return reader, err
+}
+
+func (folder *RetryingFolder) PutObject(name string, content io.Reader) error {
+	seeker, ok := content.(io.Seeker)
+	if !ok {
+		return folder.Folder.PutObject(name, content)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return folder.Folder.PutObject(name, content)
+	}
+	return folder.policy.Do("put", folder.GetPath()+name, func() error {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		return folder.Folder.PutObject(name, content)
+	})
+}
+
+func (folder *RetryingFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.policy.Do("delete from", folder.GetPath(), func() error {
+		return folder.Folder.DeleteObjects(objectRelativePaths)
+	})
+}
+
+// RetryPolicy returns the retry policy of the folder
+func (folder *RetryingFolder) RetryPolicy() (RetryPolicy, bool) {
+	return folder.policy, true
+}
+
+// RetryPolicyProvider is implemented by the retrying folders and the folders wrapping them,
+// so the uploader knows whether to retry the streams the folders can't retry themselves
+type RetryPolicyProvider interface {
+	RetryPolicy() (RetryPolicy, bool)
+}
+
+// retryPolicyOf returns the retry policy of the folder configured by ConfigureFolder
+func retryPolicyOf(folder storage.Folder) (RetryPolicy, bool) {
+	if provider, ok := folder.(RetryPolicyProvider); ok {
+		return provider.RetryPolicy()
+	}
+	return RetryPolicy{}, false
+}
@@ -0,0 +1,163 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+// flakyFolder fails the given number of the first operations
+type flakyFolder struct {
+	storage.Folder
+	failures int
+	calls    int
+}
+
+func (folder *flakyFolder) fail() error {
+	folder.calls++
+	if folder.calls <= folder.failures {
+		return errors.New("503 Service Unavailable")
+	}
+	return nil
+}
+
+func (folder *flakyFolder) PutObject(name string, content io.Reader) error {
+	if err := folder.fail(); err != nil {
+		// the failed upload reads some content before failing
+		_, _ = content.Read(make([]byte, 4))
+		return err
+	}
+	return folder.Folder.PutObject(name, content)
+}
+
+func (folder *flakyFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	if err := folder.fail(); err != nil {
+		return nil, err
+	}
+	return folder.Folder.ReadObject(objectRelativePath)
+}
+
+type namedReadSeeker struct {
+	io.ReadSeeker
+	name string
+}
+
+func (file *namedReadSeeker) Name() string {
+	return file.name
+}
+
+func newTestRetryPolicy() internal.RetryPolicy {
+	return internal.RetryPolicy{Attempts: 3, IsRetryable: internal.IsRetryableStorageError}
+}
+
+func TestRetryingFolder_RetriesSeekableContent(t *testing.T) {
+	flaky := &flakyFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder(), failures: 2}
+	folder := internal.NewRetryingFolder(flaky, newTestRetryPolicy())
+
+	err := folder.PutObject("object", bytes.NewReader([]byte("content")))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, flaky.calls)
+
+	reader, err := folder.ReadObject("object")
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestRetryingFolder_GivesUpAfterAttempts(t *testing.T) {
+	flaky := &flakyFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder(), failures: 3}
+	folder := internal.NewRetryingFolder(flaky, newTestRetryPolicy())
+
+	err := folder.PutObject("object", bytes.NewReader([]byte("content")))
+	assert.Error(t, err)
+	assert.Equal(t, 3, flaky.calls)
+}
+
+func TestRetryingFolder_DoesNotRetryStreams(t *testing.T) {
+	flaky := &flakyFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder(), failures: 1}
+	folder := internal.NewRetryingFolder(flaky, newTestRetryPolicy())
+
+	err := folder.PutObject("object", io.MultiReader(strings.NewReader("content")))
+	assert.Error(t, err)
+	assert.Equal(t, 1, flaky.calls)
+}
+
+func TestRetryingFolder_DoesNotRetryMissingObjects(t *testing.T) {
+	flaky := &flakyFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder()}
+	folder := internal.NewRetryingFolder(flaky, newTestRetryPolicy())
+
+	_, err := folder.ReadObject("missing")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+	assert.Equal(t, 1, flaky.calls)
+}
+
+func TestUploader_UploadFile_RetriesSeekableFile(t *testing.T) {
+	memoryFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	flaky := &flakyFolder{Folder: memoryFolder, failures: 1}
+	uploader := internal.NewUploader(&testtools.MockCompressor{},
+		internal.NewRetryingFolder(flaky, newTestRetryPolicy()))
+
+	file := &namedReadSeeker{strings.NewReader("wal content"), "000000010000000000000001"}
+	err := uploader.UploadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, flaky.calls)
+	assert.False(t, uploader.Failed.Load().(bool))
+
+	reader, err := memoryFolder.ReadObject("000000010000000000000001.mock")
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "wal content", string(content))
+}
+
+func TestUploader_UploadReopenableFile_RetriesStream(t *testing.T) {
+	memoryFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	flaky := &flakyFolder{Folder: memoryFolder, failures: 1}
+	uploader := internal.NewUploader(&testtools.MockCompressor{},
+		internal.NewRetryingFolder(flaky, newTestRetryPolicy()))
+
+	opened := 0
+	err := uploader.UploadReopenableFile("000000010000000000000001", func() (io.Reader, error) {
+		opened++
+		return io.MultiReader(strings.NewReader("wal content")), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, opened)
+	assert.Equal(t, 2, flaky.calls)
+
+	// the failed attempt is not counted
+	rawSize, err := uploader.RawDataSize()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("wal content")), rawSize)
+	uploadedSize, err := uploader.UploadedDataSize()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("wal content")), uploadedSize)
+
+	reader, err := memoryFolder.ReadObject("000000010000000000000001.mock")
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "wal content", string(content))
+}
+
+func TestUploader_UploadFile_RetriesThroughWrappedFolders(t *testing.T) {
+	flaky := &flakyFolder{Folder: testtools.MakeDefaultInMemoryStorageFolder(), failures: 1}
+	fanOut := internal.NewFanOutFolder([]storage.Folder{internal.NewRetryingFolder(flaky, newTestRetryPolicy())},
+		internal.FanOutPolicyAll)
+	folder := internal.NewFailoverFolder(fanOut, nil)
+	uploader := internal.NewUploader(&testtools.MockCompressor{}, folder)
+
+	file := &namedReadSeeker{strings.NewReader("wal content"), "000000010000000000000001"}
+	err := uploader.UploadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, flaky.calls)
+}
@@ -1,12 +1,14 @@
 package internal
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/spf13/viper"
 	"github.com/wal-g/storages/azure"
 	"github.com/wal-g/storages/fs"
 	"github.com/wal-g/storages/gcs"
+	"github.com/wal-g/storages/memory"
 	"github.com/wal-g/storages/s3"
 	"github.com/wal-g/storages/sh"
 	"github.com/wal-g/storages/storage"
@@ -42,6 +44,27 @@ func (adapter *StorageAdapter) loadSettings(config *viper.Viper) map[string]stri
 	return settings
 }
 
+// configure returns the folder of the storage if its prefix is set
+func (adapter *StorageAdapter) configure(config *viper.Viper) (folder storage.Folder, ok bool, err error) {
+	prefix, ok := getWaleCompatibleSettingFrom(adapter.prefixName, config)
+	if !ok {
+		return nil, false, nil
+	}
+	if adapter.prefixPreprocessor != nil {
+		prefix = adapter.prefixPreprocessor(prefix)
+	}
+
+	settings := adapter.loadSettings(config)
+	folder, err = adapter.configureFolder(prefix, settings)
+	return folder, true, err
+}
+
+func NewStorageAdapter(prefixName string, settingNames []string,
+	configureFolder func(string, map[string]string) (storage.Folder, error),
+	prefixPreprocessor func(string) string) StorageAdapter {
+	return StorageAdapter{prefixName, settingNames, configureFolder, prefixPreprocessor}
+}
+
 func preprocessFilePrefix(prefix string) string {
 	return strings.TrimPrefix(prefix, WaleFileHost) // WAL-E backward compatibility
 }
@@ -53,4 +76,35 @@ var StorageAdapters = []StorageAdapter{
 	{"AZ_PREFIX", azure.SettingList, azure.ConfigureFolder, nil},
 	{"SWIFT_PREFIX", swift.SettingList, swift.ConfigureFolder, nil},
 	{"SSH_PREFIX", sh.SettingsList, sh.ConfigureFolder, nil},
+	{"MEMORY_PREFIX", nil, configureMemoryFolder, preprocessMemoryPrefix},
+}
+
+// RegisterStorageAdapter adds the storage backend, e.g. from the init function of a file built with a build tag.
+// Adapters must be registered before ConfigureSettings, so their settings are allowed.
+func RegisterStorageAdapter(adapter StorageAdapter) {
+	for _, registered := range StorageAdapters {
+		if registered.prefixName == adapter.prefixName {
+			panic(fmt.Sprintf("storage adapter %s is already registered", adapter.prefixName))
+		}
+	}
+	StorageAdapters = append(StorageAdapters, adapter)
+}
+
+const MemoryPrefixHost = "memory://"
+
+// memoryStorage is shared by all the folders configured with MEMORY_PREFIX,
+// so the backups pushed by one command are seen by the next commands run in the same process
+var memoryStorage = memory.NewStorage()
+
+func preprocessMemoryPrefix(prefix string) string {
+	return strings.TrimPrefix(prefix, MemoryPrefixHost)
+}
+
+func configureMemoryFolder(prefix string, settings map[string]string) (storage.Folder, error) {
+	return memory.NewFolder(prefix, memoryStorage), nil
+}
+
+// ResetMemoryStorage drops everything stored with MEMORY_PREFIX
+func ResetMemoryStorage() {
+	memoryStorage = memory.NewStorage()
 }
@@ -0,0 +1,67 @@
+package internal_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestConfigureFolder_MemoryStorageIsShared(t *testing.T) {
+	defer internal.ResetMemoryStorage()
+	config := viper.New()
+	config.Set("WALG_MEMORY_PREFIX", "memory://test-bucket/wal-g")
+
+	pushFolder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	err = pushFolder.PutObject("basebackups_005/base_1_backup_stop_sentinel.json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+
+	fetchFolder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	reader, err := fetchFolder.ReadObject("basebackups_005/base_1_backup_stop_sentinel.json")
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(content))
+
+	internal.ResetMemoryStorage()
+	exists, err := fetchFolder.Exists("basebackups_005/base_1_backup_stop_sentinel.json")
+	assert.NoError(t, err)
+	assert.True(t, exists, "configured folders keep the storage they were created with")
+
+	resetFolder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	exists, err = resetFolder.Exists("basebackups_005/base_1_backup_stop_sentinel.json")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRegisterStorageAdapter(t *testing.T) {
+	defer func(adapters []internal.StorageAdapter) {
+		internal.StorageAdapters = adapters
+	}(internal.StorageAdapters)
+	registeredFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	var configuredPrefix string
+	internal.RegisterStorageAdapter(internal.NewStorageAdapter("REGISTERED_TEST_PREFIX", nil,
+		func(prefix string, settings map[string]string) (storage.Folder, error) {
+			configuredPrefix = prefix
+			return registeredFolder, nil
+		}, nil))
+	config := viper.New()
+	config.Set("WALG_REGISTERED_TEST_PREFIX", "test://folder")
+
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, registeredFolder, folder)
+	assert.Equal(t, "test://folder", configuredPrefix)
+
+	assert.Panics(t, func() {
+		internal.RegisterStorageAdapter(internal.NewStorageAdapter("MEMORY_PREFIX", nil, nil, nil))
+	})
+}
@@ -4,20 +4,36 @@ import (
 	"io"
 
 	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/metrics"
 )
 
 // StorageReaderMaker creates readers for downloading from storage
 type StorageReaderMaker struct {
 	Folder       storage.Folder
 	RelativePath string
+	// ExpectedChecksum is the SHA-256 of the object, the readers check the object against it if it is set
+	ExpectedChecksum string
 }
 
 func NewStorageReaderMaker(folder storage.Folder, relativePath string) *StorageReaderMaker {
-	return &StorageReaderMaker{folder, relativePath}
+	return &StorageReaderMaker{Folder: folder, RelativePath: relativePath}
+}
+
+func NewVerifyingStorageReaderMaker(folder storage.Folder, relativePath, expectedChecksum string) *StorageReaderMaker {
+	return &StorageReaderMaker{Folder: folder, RelativePath: relativePath, ExpectedChecksum: expectedChecksum}
 }
 
 func (readerMaker *StorageReaderMaker) Path() string { return readerMaker.RelativePath }
 
 func (readerMaker *StorageReaderMaker) Reader() (io.ReadCloser, error) {
-	return readerMaker.Folder.ReadObject(readerMaker.RelativePath)
+	readCloser, err := readerMaker.Folder.ReadObject(readerMaker.RelativePath)
+	if err != nil {
+		return nil, err
+	}
+	readCloser = ioextensions.ReadCascadeCloser{
+		Reader: metrics.NewCountingReader(readCloser, metrics.DownloadedBytes),
+		Closer: readCloser,
+	}
+	return NewChecksumVerifyingReader(readCloser, readerMaker.RelativePath, readerMaker.ExpectedChecksum), nil
 }
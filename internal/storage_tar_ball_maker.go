@@ -11,6 +11,11 @@ func NewStorageTarBallMaker(backupName string, uploader *Uploader) *StorageTarBa
 	return &StorageTarBallMaker{0, backupName, uploader}
 }
 
+// NewResumedStorageTarBallMaker creates the maker numbering the tarballs after the parts already uploaded
+func NewResumedStorageTarBallMaker(backupName string, uploader *Uploader, uploadedPartCount int) *StorageTarBallMaker {
+	return &StorageTarBallMaker{uploadedPartCount, backupName, uploader}
+}
+
 // Make returns a tarball with required storage fields.
 func (tarBallMaker *StorageTarBallMaker) Make(dedicatedUploader bool) TarBall {
 	tarBallMaker.partCount++
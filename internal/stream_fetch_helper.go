@@ -38,22 +38,28 @@ func GetLogsDstSettings(operationLogsDstEnvVariable string) (dstFolder string, e
 func downloadAndDecompressStream(backup Backup, writeCloser io.WriteCloser) error {
 	defer utility.LoggedClose(writeCloser, "")
 
+	manifest, err := FetchChecksumManifest(backup.Folder, backup.Name)
+	if err != nil {
+		return err
+	}
+
 	for _, decompressor := range compression.Decompressors {
-		archiveReader, exists, err := TryDownloadFile(
-			backup.Folder, GetStreamName(backup.Name, decompressor.FileExtension()))
+		streamName := GetStreamName(backup.Name, decompressor.FileExtension())
+		archiveReader, exists, err := TryDownloadFile(backup.Folder, streamName)
 		if err != nil {
 			return errors.Wrapf(err, "failed to dowload file")
 		}
 		if !exists {
 			continue
 		}
+		archiveReader = NewChecksumVerifyingReader(archiveReader, streamName, manifest.Checksum(streamName))
 
 		tracelog.DebugLogger.Printf("Found file: %s.%s", backup.Name, decompressor.FileExtension())
 		err = DecompressDecryptBytes(&EmptyWriteIgnorer{WriteCloser: writeCloser}, archiveReader, decompressor)
 		if err != nil {
 			return errors.Wrapf(err, "failed to decompress and decrypt file")
 		}
-		return nil
+		return VerifyChecksum(archiveReader)
 	}
 	return newArchiveNonExistenceError(fmt.Sprintf("Archive '%s' does not exist.\n", backup.Name))
 }
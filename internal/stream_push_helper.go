@@ -6,6 +6,7 @@ import (
 	"path"
 
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -26,6 +27,7 @@ func (uploader *Uploader) PushStream(stream io.Reader) (string, error) {
 // TODO : unit tests
 // PushStreamToDestination compresses a stream and push it to specifyed destination
 func (uploader *Uploader) PushStreamToDestination(stream io.Reader, dstPath string) error {
+	stream = metrics.NewCountingReader(stream, metrics.UploadedRawBytes)
 	if uploader.dataSize != nil {
 		stream = NewWithSizeReader(stream, uploader.dataSize)
 	}
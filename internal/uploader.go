@@ -1,8 +1,11 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
@@ -12,6 +15,7 @@ import (
 	"github.com/wal-g/wal-g/internal/asm"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/metrics"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -26,6 +30,7 @@ type UploaderProvider interface {
 	DisableSizeTracking()
 	UploadedDataSize() (int64, error)
 	RawDataSize() (int64, error)
+	TakeObjectChecksums(prefix string) map[string]string
 }
 
 // Uploader contains fields associated with uploading tarballs.
@@ -39,6 +44,7 @@ type Uploader struct {
 	Failed                 atomic.Value
 	tarSize                *int64
 	dataSize               *int64
+	checksums              *ObjectChecksums
 }
 
 // UploadObject
@@ -57,6 +63,7 @@ func NewUploader(
 		waitGroup:       &sync.WaitGroup{},
 		tarSize:         new(int64),
 		dataSize:        new(int64),
+		checksums:       NewObjectChecksums(),
 	}
 	uploader.Failed.Store(false)
 	return uploader
@@ -85,6 +92,19 @@ func (uploader *Uploader) Finish() {
 	if uploader.Failed.Load().(bool) {
 		tracelog.ErrorLogger.Printf("WAL-G could not complete upload.\n")
 	}
+	uploader.updateCompressionRatio()
+}
+
+func (uploader *Uploader) updateCompressionRatio() {
+	uploadedSize, err := uploader.UploadedDataSize()
+	if err != nil || uploadedSize == 0 {
+		return
+	}
+	rawSize, err := uploader.RawDataSize()
+	if err != nil {
+		return
+	}
+	metrics.CompressionRatio.Set(float64(rawSize) / float64(uploadedSize))
 }
 
 // Clone creates similar Uploader with new WaitGroup
@@ -97,22 +117,67 @@ func (uploader *Uploader) Clone() *Uploader {
 		Failed:               uploader.Failed,
 		tarSize:              uploader.tarSize,
 		dataSize:             uploader.dataSize,
+		checksums:            uploader.checksums,
 	}
 }
 
 // TODO : unit tests
 // UploadFile compresses a file and uploads it.
+// The compressed stream can't be uploaded again, so the seekable file is compressed again on retry.
 func (uploader *Uploader) UploadFile(file ioextensions.NamedReader) error {
-	filename := file.Name()
-
-	fileReader := file.(io.Reader)
-	if uploader.dataSize != nil {
-		fileReader = NewWithSizeReader(fileReader, uploader.dataSize)
+	open := func() (io.Reader, error) {
+		return file, nil
+	}
+	seeker, isSeekable := file.(io.Seeker)
+	if isSeekable {
+		open = func() (io.Reader, error) {
+			_, err := seeker.Seek(0, io.SeekStart)
+			return file, err
+		}
 	}
-	compressedFile := CompressAndEncrypt(fileReader, uploader.Compressor, ConfigureCrypter())
+	return uploader.uploadFile(file.Name(), open, isSeekable)
+}
+
+// UploadReopenableFile compresses a file and uploads it, open provides the file content from the start
+// for every upload attempt, so the file can be uploaded again even if it is read as a stream
+func (uploader *Uploader) UploadReopenableFile(filename string, open func() (io.Reader, error)) error {
+	return uploader.uploadFile(filename, open, true)
+}
+
+func (uploader *Uploader) uploadFile(filename string, open func() (io.Reader, error), canReopen bool) error {
 	dstPath := utility.SanitizePath(filepath.Base(filename) + "." + uploader.Compressor.FileExtension())
 
-	err := uploader.Upload(dstPath, compressedFile)
+	upload := func() error {
+		file, err := open()
+		if err != nil {
+			return err
+		}
+		var rawSize int64
+		compressedFile := CompressAndEncrypt(NewWithSizeReader(file, &rawSize), uploader.Compressor, ConfigureCrypter())
+		err = uploader.uploadStream(dstPath, compressedFile)
+		if err != nil {
+			// wait for the compression to stop reading the file before it is read again
+			_, _ = io.Copy(ioutil.Discard, compressedFile)
+			return err
+		}
+		// the failed attempts read the file too, only the uploaded one is counted
+		rawSize = atomic.LoadInt64(&rawSize)
+		metrics.UploadedRawBytes.Add(rawSize)
+		if uploader.dataSize != nil {
+			atomic.AddInt64(uploader.dataSize, rawSize)
+		}
+		return nil
+	}
+	var err error
+	retryPolicy, canRetry := retryPolicyOf(uploader.UploadingFolder)
+	if canRetry && canReopen {
+		err = retryPolicy.Do("upload", dstPath, upload)
+	} else {
+		err = upload()
+	}
+	if err != nil {
+		uploader.markFailed(err)
+	}
 	tracelog.InfoLogger.Println("FILE PATH:", dstPath)
 	return err
 }
@@ -128,18 +193,81 @@ func (uploader *Uploader) Compression() compression.Compressor {
 	return uploader.Compressor
 }
 
-// TODO : unit tests
+// ObjectChecksum returns the SHA-256 of the object uploaded to the path
+func (uploader *Uploader) ObjectChecksum(path string) (string, bool) {
+	return uploader.checksums.Get(path)
+}
+
+// SetObjectChecksum remembers the checksum of the object uploaded earlier, e.g. by the interrupted backup-push
+func (uploader *Uploader) SetObjectChecksum(path, checksum string) {
+	uploader.checksums.Set(path, checksum)
+}
+
+// TakeObjectSize returns the size of the object uploaded to the path and stops tracking it
+func (uploader *Uploader) TakeObjectSize(path string) (int64, bool) {
+	return uploader.checksums.TakeSize(path)
+}
+
+// TakeObjectChecksums returns the checksums of the uploaded objects with the path prefix
+// and stops tracking them
+func (uploader *Uploader) TakeObjectChecksums(prefix string) map[string]string {
+	return uploader.checksums.Take(prefix)
+}
+
+// Upload uploads the content and remembers its SHA-256 computed along the way
 func (uploader *Uploader) Upload(path string, content io.Reader) error {
-	if uploader.tarSize != nil {
-		content = NewWithSizeReader(content, uploader.tarSize)
+	var err error
+	if seekable, ok := content.(seekableContent); ok {
+		err = uploader.uploadSeekable(path, seekable)
+	} else {
+		err = uploader.uploadStream(path, content)
 	}
-	err := uploader.UploadingFolder.PutObject(path, content)
-	if err == nil {
-		return nil
+	if err != nil {
+		uploader.markFailed(err)
 	}
+	return err
+}
+
+func (uploader *Uploader) markFailed(err error) {
 	uploader.Failed.Store(true)
+	metrics.FailedUploads.Inc()
 	tracelog.ErrorLogger.Printf(tracelog.GetErrorFormatter()+"\n", err)
-	return err
+}
+
+func (uploader *Uploader) uploadStream(path string, content io.Reader) error {
+	var size int64
+	hash := sha256.New()
+	err := uploader.UploadingFolder.PutObject(path, io.TeeReader(NewWithSizeReader(content, &size), hash))
+	if err != nil {
+		return err
+	}
+	uploader.addUploadedObject(path, hex.EncodeToString(hash.Sum(nil)), atomic.LoadInt64(&size))
+	return nil
+}
+
+// addUploadedObject counts the size of the uploaded object and remembers its checksum,
+// the failed uploads are not counted
+func (uploader *Uploader) addUploadedObject(path, checksum string, size int64) {
+	metrics.UploadedBytes.Add(size)
+	if uploader.tarSize != nil {
+		atomic.AddInt64(uploader.tarSize, size)
+	}
+	uploader.checksums.Set(path, checksum)
+	uploader.checksums.SetSize(path, size)
+}
+
+// uploadSeekable passes the content to the storage as is, so it can be uploaded without buffering
+func (uploader *Uploader) uploadSeekable(path string, content seekableContent) error {
+	checksum, size, err := computeSeekableChecksum(content)
+	if err != nil {
+		return err
+	}
+	err = uploader.UploadingFolder.PutObject(path, content)
+	if err != nil {
+		return err
+	}
+	uploader.addUploadedObject(path, checksum, size)
+	return nil
 }
 
 // UploadMultiple uploads multiple objects from the start of the slice,
@@ -149,7 +277,7 @@ func (uploader *Uploader) UploadMultiple(objects []UploadObject) error {
 	for _, object := range objects {
 		err := uploader.Upload(object.Path, object.Content)
 		if err != nil {
-			// possibly do a retry here
+			// the failed uploads are retried by the RetryingFolder configured by ConfigureFolder
 			return err
 		}
 	}
@@ -1,7 +1,9 @@
 package walparser_test
 
 import (
+	"encoding/binary"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/wal-g/internal/walparser"
@@ -14,3 +16,22 @@ func TestExtractBlockLocations(t *testing.T) {
 	actualLocations := walparser.ExtractBlockLocations([]walparser.XLogRecord{record})
 	assert.Equal(t, expectedLocations, actualLocations)
 }
+
+func TestXLogRecordTransactionTime(t *testing.T) {
+	commitTime := time.Date(2021, time.March, 4, 5, 6, 7, 8000, time.UTC)
+	mainData := make([]byte, 8)
+	microseconds := commitTime.Sub(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)).Microseconds()
+	binary.LittleEndian.PutUint64(mainData, uint64(microseconds))
+	record := walparser.XLogRecord{
+		Header:   walparser.XLogRecordHeader{ResourceManagerID: walparser.RmXactID, Info: walparser.XLogXactCommit},
+		MainData: mainData,
+	}
+
+	transactionTime, ok := record.TransactionTime()
+	assert.True(t, ok)
+	assert.True(t, commitTime.Equal(transactionTime))
+
+	record.Header.ResourceManagerID = walparser.RmHeapID
+	_, ok = record.TransactionTime()
+	assert.False(t, ok)
+}
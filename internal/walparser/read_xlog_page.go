@@ -79,8 +79,9 @@ func readXLogLongPageHeaderData(reader io.Reader) error {
 	}, reader)
 }
 
+// ReadXLogPageHeader reads and validates the page header.
 // If header is long, then long header data is read from reader and thrown away
-func readXLogPageHeader(reader io.Reader) (*XLogPageHeader, error) {
+func ReadXLogPageHeader(reader io.Reader) (*XLogPageHeader, error) {
 	pageHeader := XLogPageHeader{}
 	err := parsingutil.ParseMultipleFieldsFromReader([]parsingutil.FieldToParse{
 		{Field: &pageHeader.Magic, Name: "magic"},
@@ -14,7 +14,7 @@ func TestReadXLogPageHeader_Long(t *testing.T) {
 		0x00, 0x00, 0x00, 0x01,
 	}
 	reader := bytes.NewReader(headerData)
-	header, err := readXLogPageHeader(reader)
+	header, err := ReadXLogPageHeader(reader)
 	assert.NoError(t, err)
 	assert.Equal(t, header.Magic, uint16(0xd098))
 	assert.Equal(t, header.Info, uint16(0x0007))
@@ -30,7 +30,7 @@ func TestReadXLogPageHeader_Short(t *testing.T) {
 		0xcd, 0x0a, 0x00, 0x00,
 	}
 	reader := bytes.NewReader(headerData)
-	header, err := readXLogPageHeader(reader)
+	header, err := ReadXLogPageHeader(reader)
 	assert.NoError(t, err)
 	assert.Equal(t, header.Magic, uint16(0xd098))
 	assert.Equal(t, header.Info, uint16(0x0005))
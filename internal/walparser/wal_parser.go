@@ -57,10 +57,16 @@ func (err PartialPageError) Error() string {
 type WalParser struct {
 	currentRecordData         []byte
 	hasCurrentRecordBeginning bool
+	checkRecordCrc            bool
 }
 
 func NewWalParser() *WalParser {
-	return &WalParser{make([]byte, 0), false}
+	return &WalParser{currentRecordData: make([]byte, 0)}
+}
+
+// NewWalParserWithCrcCheck creates the parser which also verifies the CRC of each parsed record
+func NewWalParserWithCrcCheck() *WalParser {
+	return &WalParser{currentRecordData: make([]byte, 0), checkRecordCrc: true}
 }
 
 func (parser *WalParser) setCurrentRecordData(data []byte) {
@@ -99,7 +105,7 @@ func (parser *WalParser) ParseRecordsFromPage(reader io.Reader) (prevRecordTail
 	if header.TotalRecordLength != uint32(len(currentRecordData)) {
 		return nil, nil, NewContinuationNotFoundError()
 	}
-	currentRecord, err := ParseXLogRecordFromBytes(currentRecordData)
+	currentRecord, err := parser.parseRecord(currentRecordData)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -112,7 +118,7 @@ func (parser *WalParser) ParseRecordsFromPage(reader io.Reader) (prevRecordTail
 
 func (parser *WalParser) parsePage(reader io.Reader) (*XLogPage, error) {
 	alignedReader := NewAlignedReader(reader, XLogRecordAlignment)
-	pageHeader, err := readXLogPageHeader(alignedReader)
+	pageHeader, err := ReadXLogPageHeader(alignedReader)
 	if err != nil {
 		if _, ok := err.(ZeroPageHeaderError); ok {
 			pageData, err1 := ioutil.ReadAll(alignedReader)
@@ -147,10 +153,11 @@ func (parser *WalParser) parsePage(reader io.Reader) (*XLogPage, error) {
 			return &XLogPage{Header: *pageHeader, PrevRecordTrailingData: remainingData}, nil
 		}
 	}
-	return readXLogPage(alignedReader, pageHeader, remainingData)
+	return parser.readXLogPage(alignedReader, pageHeader, remainingData)
 }
 
-func readXLogPage(alignedReader *AlignedReader, pageHeader *XLogPageHeader, remainingData []byte) (*XLogPage, error) {
+func (parser *WalParser) readXLogPage(alignedReader *AlignedReader,
+	pageHeader *XLogPageHeader, remainingData []byte) (*XLogPage, error) {
 	pageRecords := make([]XLogRecord, 0)
 	for {
 		recordData, wholeRecord, err := tryReadXLogRecordData(alignedReader)
@@ -160,7 +167,7 @@ func readXLogPage(alignedReader *AlignedReader, pageHeader *XLogPageHeader, rema
 		}
 		if wholeRecord {
 			// The header was previously validated being zero, so now it doesn't need to. However we do this for code robustness.
-			record, err := ParseXLogRecordFromBytes(recordData)
+			record, err := parser.parseRecord(recordData)
 			if err != nil {
 				return checkPartialPage(alignedReader,
 					&XLogPage{Header: *pageHeader, PrevRecordTrailingData: remainingData, Records: pageRecords}, err)
@@ -175,6 +182,16 @@ func readXLogPage(alignedReader *AlignedReader, pageHeader *XLogPageHeader, rema
 	}
 }
 
+func (parser *WalParser) parseRecord(recordData []byte) (*XLogRecord, error) {
+	if parser.checkRecordCrc {
+		err := checkXLogRecordCrc(recordData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ParseXLogRecordFromBytes(recordData)
+}
+
 func checkPartialPage(pageReader io.Reader, page *XLogPage, recordReadingErr error) (*XLogPage, error) {
 	if _, ok := recordReadingErr.(ZeroRecordHeaderError); ok {
 		pageData, err1 := ioutil.ReadAll(pageReader)
@@ -217,9 +234,9 @@ func LoadWalParser(reader io.Reader) (*WalParser, error) {
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return &WalParser{data, len(data) > 0}, nil
+	return &WalParser{currentRecordData: data, hasCurrentRecordBeginning: len(data) > 0}, nil
 }
 
 func LoadWalParserFromCurrentRecordHead(currentRecordHead []byte) *WalParser {
-	return &WalParser{currentRecordHead, true}
+	return &WalParser{currentRecordData: currentRecordHead, hasCurrentRecordBeginning: true}
 }
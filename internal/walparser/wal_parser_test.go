@@ -2,9 +2,11 @@ package walparser
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -99,6 +101,23 @@ func TestParsing_LongRecord(t *testing.T) {
 	parsingTestCase(t, LongRecordTestPath, doLongRecordParsingTesting)
 }
 
+func TestParsing_RecordCrc(t *testing.T) {
+	walData, err := ioutil.ReadFile(LongRecordTestPath)
+	assert.NoError(t, err)
+	parser := NewWalParserWithCrcCheck()
+	for offset := 0; offset < len(walData); offset += int(WalPageSize) {
+		_, _, err = parser.ParseRecordsFromPage(bytes.NewReader(walData[offset : offset+int(WalPageSize)]))
+		assert.NoError(t, err)
+	}
+
+	// the last byte of the first record which starts on the first page
+	walData[1976+59] ^= 0xFF
+	_, _, err = NewWalParser().ParseRecordsFromPage(bytes.NewReader(walData[:WalPageSize]))
+	assert.NoError(t, err)
+	_, _, err = NewWalParserWithCrcCheck().ParseRecordsFromPage(bytes.NewReader(walData[:WalPageSize]))
+	assert.IsType(t, XLogRecordCrcMismatchError{}, errors.Cause(err))
+}
+
 func TestSaveLoadWalParser(t *testing.T) {
 	walParser := LoadWalParserFromCurrentRecordHead([]byte{1, 2, 3, 4, 5, 6})
 
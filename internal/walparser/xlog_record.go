@@ -1,10 +1,24 @@
 package walparser
 
+import (
+	"encoding/binary"
+	"time"
+)
+
 const (
 	XLogSwitch          = 0x40
 	WalSwitchRecordSize = XLogRecordHeaderSize
+
+	// transaction record types, for clarification you can look at postgres code:
+	// src/include/access/xact.h
+	XLogXactOpMask = 0x70
+	XLogXactCommit = 0x00
+	XLogXactAbort  = 0x20
 )
 
+// the timestamps in the WAL are microseconds since the postgres epoch
+var postgresEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type XLogRecord struct {
 	Header      XLogRecordHeader
 	MainDataLen uint32
@@ -27,3 +41,18 @@ func (record *XLogRecord) isWALSwitch() bool {
 	return record.Header.ResourceManagerID == RmXlogID &&
 		(record.Header.Info&^XlrInfoMask) == XLogSwitch
 }
+
+// TransactionTime returns the commit or abort time of the transaction record. Postgres takes this time
+// before inserting the record, so all the records following it in the WAL are written later.
+func (record *XLogRecord) TransactionTime() (time.Time, bool) {
+	if record.Header.ResourceManagerID != RmXactID || len(record.MainData) < 8 {
+		return time.Time{}, false
+	}
+	switch record.Header.Info & XLogXactOpMask {
+	case XLogXactCommit, XLogXactAbort:
+		microseconds := int64(binary.LittleEndian.Uint64(record.MainData))
+		return postgresEpoch.Add(time.Duration(microseconds) * time.Microsecond), true
+	default:
+		return time.Time{}, false
+	}
+}
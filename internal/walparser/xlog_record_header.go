@@ -1,7 +1,9 @@
 package walparser
 
 import (
+	"bytes"
 	"fmt"
+	"hash/crc32"
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
@@ -16,8 +18,12 @@ const (
 	XlrSpecialRelUpdate  = 0x01
 	XlrCheckConsistency  = 0x02
 	XLogRecordHeaderSize = 24
+	// the header bytes preceding the crc field are included in the record crc
+	xLogRecordCrcOffset = 20
 )
 
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
 type InconsistentXLogRecordTotalLengthError struct {
 	error
 }
@@ -48,6 +54,20 @@ func (err InvalidXLogRecordResourceManagerIDError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type XLogRecordCrcMismatchError struct {
+	error
+}
+
+func NewXLogRecordCrcMismatchError(expectedCrc uint32, actualCrc uint32) XLogRecordCrcMismatchError {
+	return XLogRecordCrcMismatchError{
+		errors.Errorf("record crc mismatch: %08X, while the record header has: %08X", actualCrc, expectedCrc),
+	}
+}
+
+func (err XLogRecordCrcMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type ZeroRecordHeaderError struct {
 	error
 }
@@ -111,3 +131,18 @@ func (header *XLogRecordHeader) isZero() bool {
 		header.ResourceManagerID == 0 &&
 		header.Crc32Hash == 0
 }
+
+// checkXLogRecordCrc computes the CRC-32C of the whole record data the same way postgres does:
+// over the data following the header first and then over the header up to the crc field
+func checkXLogRecordCrc(recordData []byte) error {
+	header, err := readXLogRecordHeader(bytes.NewReader(recordData))
+	if err != nil {
+		return err
+	}
+	crc := crc32.Update(0, crc32cTable, recordData[XLogRecordHeaderSize:])
+	crc = crc32.Update(crc, crc32cTable, recordData[:xLogRecordCrcOffset])
+	if crc != header.Crc32Hash {
+		return NewXLogRecordCrcMismatchError(header.Crc32Hash, crc)
+	}
+	return nil
+}
@@ -50,6 +50,7 @@ const (
 
 	// utility.SentinelSuffix is a suffix of backup finish sentinel file
 	SentinelSuffix         = "_backup_stop_sentinel.json"
+	ChecksumManifestSuffix = "_backup_checksums.json"
 	CompressedBlockMaxSize = 20 << 20
 	CopiedBlockMaxSize     = CompressedBlockMaxSize
 	MetadataFileName       = "metadata.json"
@@ -150,6 +151,27 @@ func GetSubdirectoryRelativePath(subdirectoryPath string, directoryPath string)
 	return NormalizePath(SanitizePath(strings.TrimPrefix(subdirectoryPath, directoryPath)))
 }
 
+// ReplaceFile writes the data to the temporary file and renames it to the path,
+// so the interrupted write leaves the previous version of the file
+func ReplaceFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
 // BytesPool holds []byte.
 type BytesPool struct {
 	pool chan []byte